@@ -65,3 +65,10 @@ type AtomicWorkflow interface {
 	// End performs cleanup after the AtomicWorkflow engine finish its execution
 	End(ctx context.Context)
 }
+
+// Undoer is an optional interface for an AtomicWorkflow that can reverse its last successful run
+// Workflow implements it, see WithUndoWindow.
+type Undoer interface {
+	// Undo reverses a successful run of the AtomicWorkflow by executing rollback of all steps in reverse order
+	Undo(ctx context.Context) (WorkflowReport, error)
+}
@@ -0,0 +1,270 @@
+package automa
+
+import (
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"sort"
+	"strings"
+)
+
+// BuilderInfo describes a StepBuilder registered with a StepRegistry for tooling, e.g. to generate docs
+// Name and Version are set by the registry, Version being empty for a builder registered without version.
+type BuilderInfo struct {
+	Name        string `yaml:"name" json:"name"`
+	Version     string `yaml:"version,omitempty" json:"version,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// RequiredKeys are the keys of the state required by the steps, they must be provided either as parameters of the
+	// step or as outputs of a previous step
+	RequiredKeys []string `yaml:"required_keys,omitempty" json:"requiredKeys,omitempty"`
+
+	// ProducedKeys are the keys of the state published by the steps in StepReport.Outputs
+	ProducedKeys []string `yaml:"produced_keys,omitempty" json:"producedKeys,omitempty"`
+}
+
+// registeredBuilder is a StepBuilder registered with a StepRegistry
+type registeredBuilder struct {
+	name      string
+	version   semver
+	versioned bool
+	builder   StepBuilder
+	info      BuilderInfo
+}
+
+// describe returns the BuilderInfo of the builder
+func (b *registeredBuilder) describe() BuilderInfo {
+	info := b.info
+	info.Name = b.name
+	info.Version = ""
+	if b.versioned {
+		info.Version = b.version.String()
+	}
+
+	info.RequiredKeys = append([]string(nil), b.info.RequiredKeys...)
+	info.ProducedKeys = append([]string(nil), b.info.ProducedKeys...)
+
+	return info
+}
+
+// RegisterStepBuilder registers a StepBuilder by name to build the steps of a WorkflowSpec
+// It replaces any builder registered with the same name without a version. If a nil builder is provided, it skips
+// adding it to the registry.
+// It returns itself so that chaining is possible when registering multiple builders with the registry
+func (r *StepRegistry) RegisterStepBuilder(name string, builder StepBuilder) *StepRegistry {
+	if builder == nil {
+		return r
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// the builder without version is kept first so that it has a lower precedence than any version
+	list := []*registeredBuilder{{name: name, builder: builder}}
+	for _, b := range r.builders[name] {
+		if b.versioned {
+			list = append(list, b)
+		}
+	}
+
+	r.builders[name] = list
+
+	return r
+}
+
+// AddVersioned registers a StepBuilder by name and semantic version, e.g. AddVersioned("fs/mkdir", "v1.2.0", b)
+// Names may be namespaced using "/" so that catalogs of steps shared across teams do not collide. Every version of
+// a name can only be registered once.
+func (r *StepRegistry) AddVersioned(name string, version string, builder StepBuilder) error {
+	if err := validateBuilderName(name); err != nil {
+		return err
+	}
+
+	if builder == nil {
+		return errors.Newf("step builder %q@%s is nil", name, version)
+	}
+
+	v, err := parseSemver(version)
+	if err != nil {
+		return errors.Wrapf(err, "invalid version of step builder %q", name)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, b := range r.builders[name] {
+		if b.versioned && b.version == v {
+			return errors.Newf("step builder %q@%s is already registered", name, v)
+		}
+	}
+
+	list := append(r.builders[name], &registeredBuilder{name: name, version: v, versioned: true, builder: builder})
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].versioned == list[j].versioned && list[i].version.compare(list[j].version) < 0 ||
+			!list[i].versioned && list[j].versioned
+	})
+	r.builders[name] = list
+
+	return nil
+}
+
+// Of returns the StepBuilder referenced by name, optionally followed by version constraints, e.g. "fs/mkdir@>=1.0"
+// The highest version satisfying all the comma separated constraints is returned, see parseConstraints for the
+// supported operators. Without constraints, the highest version is returned, or else the builder registered without
+// version using RegisterStepBuilder.
+func (r *StepRegistry) Of(ref string) (StepBuilder, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	b, err := r.resolveBuilder(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.builder, nil
+}
+
+// resolveBuilder returns the registered builder referenced by ref as per Of
+// The caller must hold the lock of the registry.
+func (r *StepRegistry) resolveBuilder(ref string) (*registeredBuilder, error) {
+	name, constraint, hasConstraint := strings.Cut(ref, "@")
+	candidates := r.builders[name]
+	if len(candidates) == 0 {
+		return nil, errors.Newf("step builder %q is not registered", name)
+	}
+
+	if !hasConstraint {
+		// versions are sorted in ascending order after the builder without version, if any
+		return candidates[len(candidates)-1], nil
+	}
+
+	constraints, err := parseConstraints(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(candidates) - 1; i >= 0; i-- {
+		b := candidates[i]
+		if !b.versioned {
+			continue
+		}
+
+		matched := true
+		for _, c := range constraints {
+			matched = matched && c.matches(b.version)
+		}
+
+		if matched {
+			return b, nil
+		}
+	}
+
+	return nil, errors.Newf("no version of step builder %q satisfies %q", name, constraint)
+}
+
+// validateBuilderName returns an error if the name is empty, contains a version or an empty namespace
+func validateBuilderName(name string) error {
+	if name == "" || strings.Contains(name, "@") {
+		return errors.Newf("invalid step builder name %q", name)
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == "" {
+			return errors.Newf("invalid step builder name %q", name)
+		}
+	}
+
+	return nil
+}
+
+// SetBuilderInfo sets the description and the state keys of the builder registered with the exact reference
+// The reference is the name of the builder, followed by "@" and its version if it was registered using AddVersioned.
+func (r *StepRegistry) SetBuilderInfo(ref string, info BuilderInfo) error {
+	name, version, versioned := strings.Cut(ref, "@")
+	var v semver
+	if versioned {
+		var err error
+		if v, err = parseSemver(version); err != nil {
+			return errors.Wrapf(err, "invalid version of step builder %q", name)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, b := range r.builders[name] {
+		if b.versioned == versioned && b.version == v {
+			b.info = info
+			return nil
+		}
+	}
+
+	return errors.Newf("step builder %q is not registered", ref)
+}
+
+// List returns the BuilderInfo of every registered StepBuilder ordered by name and version
+func (r *StepRegistry) List() []BuilderInfo {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.builders))
+	for name := range r.builders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var list []BuilderInfo
+	for _, name := range names {
+		for _, b := range r.builders[name] {
+			list = append(list, b.describe())
+		}
+	}
+
+	return list
+}
+
+// Describe returns the BuilderInfo of the StepBuilder referenced by ref as per Of
+func (r *StepRegistry) Describe(ref string) (BuilderInfo, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	b, err := r.resolveBuilder(ref)
+	if err != nil {
+		return BuilderInfo{}, err
+	}
+
+	return b.describe(), nil
+}
+
+// ValidateSpec checks the WorkflowSpec against the registered builders and returns the list of violations
+// A violation is reported for a step whose builder cannot be resolved, or whose required keys are neither provided
+// as parameters nor produced by a previous step. An empty list means that the spec can be built.
+func (r *StepRegistry) ValidateSpec(spec *WorkflowSpec) []Violation {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var violations []Violation
+	produced := map[string]bool{}
+	for _, stepSpec := range spec.Steps {
+		b, err := r.resolveBuilder(stepSpec.Uses)
+		if err != nil {
+			violations = append(violations, Violation{StepID: stepSpec.ID, Reason: err.Error()})
+			continue
+		}
+
+		for _, key := range b.info.RequiredKeys {
+			if _, ok := stepSpec.Params[key]; !ok && !produced[key] {
+				violations = append(violations, Violation{
+					StepID: stepSpec.ID,
+					Reason: fmt.Sprintf("required key %q is neither a parameter nor produced by a previous step", key),
+				})
+			}
+		}
+
+		for _, key := range b.info.ProducedKeys {
+			produced[key] = true
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,147 @@
+package automa
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newVersionedBuilder returns a StepBuilder building steps whose version is the given version
+func newVersionedBuilder(version string) StepBuilder {
+	return func(id string, params map[string]string) (AtomicStep, error) {
+		return &mockVersionedStep{Step: Step{ID: id}, version: version}, nil
+	}
+}
+
+// builtVersion returns the version of the step built by the builder
+func builtVersion(t *testing.T, builder StepBuilder) string {
+	step, err := builder("step_1", nil)
+	assert.NoError(t, err)
+
+	return step.(*mockVersionedStep).version
+}
+
+func TestStepRegistry_AddVersioned(t *testing.T) {
+	registry := NewStepRegistry(nil)
+	assert.NoError(t, registry.AddVersioned("fs/mkdir", "v1.2.0", newVersionedBuilder("v1.2.0")))
+	assert.NoError(t, registry.AddVersioned("fs/mkdir", "v0.9.0", newVersionedBuilder("v0.9.0")))
+	assert.NoError(t, registry.AddVersioned("fs/mkdir", "2.0.1", newVersionedBuilder("v2.0.1")))
+	registry.RegisterStepBuilder("fs/mkdir", newVersionedBuilder("unversioned"))
+
+	assert.Error(t, registry.AddVersioned("fs/mkdir", "1.2", newVersionedBuilder("v1.2.0")))
+	assert.Error(t, registry.AddVersioned("fs/", "1.0.0", newVersionedBuilder("v1.0.0")))
+	assert.Error(t, registry.AddVersioned("fs/mkdir@1", "1.0.0", newVersionedBuilder("v1.0.0")))
+	assert.Error(t, registry.AddVersioned("fs/rm", "latest", newVersionedBuilder("v1.0.0")))
+	assert.Error(t, registry.AddVersioned("fs/rm", "1.0.0", nil))
+
+	tests := map[string]string{
+		"fs/mkdir":             "v2.0.1",
+		"fs/mkdir@>=1.0":       "v2.0.1",
+		"fs/mkdir@>=1.0, <2.0": "v1.2.0",
+		"fs/mkdir@^0.9":        "v0.9.0",
+		"fs/mkdir@1.2.0":       "v1.2.0",
+	}
+	for ref, version := range tests {
+		builder, err := registry.Of(ref)
+		if assert.NoError(t, err, ref) {
+			assert.Equal(t, version, builtVersion(t, builder), ref)
+		}
+	}
+
+	for _, ref := range []string{"fs/rm", "fs/mkdir@>=3.0", "fs/mkdir@>=x"} {
+		_, err := registry.Of(ref)
+		assert.Error(t, err, ref)
+	}
+
+	// the builder without version is used if no version is registered
+	registry.RegisterStepBuilder("fs/rm", newVersionedBuilder("unversioned"))
+	builder, err := registry.Of("fs/rm")
+	assert.NoError(t, err)
+	assert.Equal(t, "unversioned", builtVersion(t, builder))
+
+	_, err = registry.Of("fs/rm@1.0")
+	assert.Error(t, err)
+}
+
+func TestStepRegistry_BuildWorkflowFromSpec_Versioned(t *testing.T) {
+	registry := NewStepRegistry(nil)
+	assert.NoError(t, registry.AddVersioned("fs/mkdir", "v1.2.0", newVersionedBuilder("v1.2.0")))
+	assert.NoError(t, registry.AddVersioned("fs/mkdir", "v2.0.0", newVersionedBuilder("v2.0.0")))
+
+	spec := &WorkflowSpec{ID: "workflow_1", Steps: []StepSpec{{ID: "mkdir", Uses: "fs/mkdir@~1.2"}}}
+	workflow, err := registry.BuildWorkflowFromSpec(spec)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.2.0", workflow.Manifest().Steps[0].Version)
+
+	spec.Steps[0].Uses = "fs/mkdir@>=3"
+	_, err = registry.BuildWorkflowFromSpec(spec)
+	assert.Error(t, err)
+}
+
+func TestStepRegistry_List(t *testing.T) {
+	registry := NewStepRegistry(nil)
+	assert.NoError(t, registry.AddVersioned("k8s/kubeconfig", "v1.0.0", newVersionedBuilder("v1.0.0")))
+	assert.NoError(t, registry.AddVersioned("k8s/apply", "v1.1.0", newVersionedBuilder("v1.1.0")))
+	assert.NoError(t, registry.AddVersioned("k8s/apply", "v1.0.0", newVersionedBuilder("v1.0.0")))
+	registry.RegisterStepBuilder("k8s/apply", newVersionedBuilder("unversioned"))
+
+	assert.NoError(t, registry.SetBuilderInfo("k8s/apply@1.1", BuilderInfo{
+		Description:  "applies a manifest",
+		RequiredKeys: []string{"kubeconfig", "manifest"},
+	}))
+	assert.NoError(t, registry.SetBuilderInfo("k8s/apply", BuilderInfo{Description: "legacy"}))
+	assert.NoError(t, registry.SetBuilderInfo("k8s/kubeconfig@v1.0.0", BuilderInfo{ProducedKeys: []string{"kubeconfig"}}))
+	assert.Error(t, registry.SetBuilderInfo("k8s/apply@2.0.0", BuilderInfo{}))
+	assert.Error(t, registry.SetBuilderInfo("k8s/kubeconfig", BuilderInfo{}))
+	assert.Error(t, registry.SetBuilderInfo("k8s/apply@x", BuilderInfo{}))
+
+	list := registry.List()
+	assert.Equal(t, 4, len(list))
+	assert.Equal(t, BuilderInfo{Name: "k8s/apply", Description: "legacy"}, list[0])
+	assert.Equal(t, "v1.0.0", list[1].Version)
+	assert.Equal(t, "v1.1.0", list[2].Version)
+	assert.Equal(t, "k8s/kubeconfig", list[3].Name)
+
+	info, err := registry.Describe("k8s/apply")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.1.0", info.Version)
+	assert.Equal(t, "applies a manifest", info.Description)
+
+	// the returned info is a copy
+	info.RequiredKeys[0] = "changed"
+	info, err = registry.Describe("k8s/apply@1.1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kubeconfig", "manifest"}, info.RequiredKeys)
+
+	_, err = registry.Describe("k8s/delete")
+	assert.Error(t, err)
+}
+
+func TestStepRegistry_ValidateSpec(t *testing.T) {
+	registry := NewStepRegistry(nil)
+	assert.NoError(t, registry.AddVersioned("k8s/kubeconfig", "v1.0.0", newVersionedBuilder("v1.0.0")))
+	assert.NoError(t, registry.AddVersioned("k8s/apply", "v1.0.0", newVersionedBuilder("v1.0.0")))
+	assert.NoError(t, registry.SetBuilderInfo("k8s/kubeconfig@1.0.0", BuilderInfo{ProducedKeys: []string{"kubeconfig"}}))
+	assert.NoError(t, registry.SetBuilderInfo("k8s/apply@1.0.0", BuilderInfo{RequiredKeys: []string{"kubeconfig", "manifest"}}))
+
+	spec := &WorkflowSpec{ID: "workflow_1", Steps: []StepSpec{
+		{ID: "apply", Uses: "k8s/apply", Params: map[string]string{"manifest": "app.yaml"}},
+		{ID: "kubeconfig", Uses: "k8s/kubeconfig"},
+		{ID: "delete", Uses: "k8s/delete"},
+	}}
+
+	violations := registry.ValidateSpec(spec)
+	assert.Equal(t, 2, len(violations))
+	assert.Equal(t, "apply", violations[0].StepID)
+	assert.Contains(t, violations[0].Reason, `"kubeconfig"`)
+	assert.Equal(t, "delete", violations[1].StepID)
+
+	_, err := registry.BuildWorkflowFromSpec(spec)
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "workflow_1", validationErr.WorkflowID)
+
+	spec.Steps = []StepSpec{spec.Steps[1], spec.Steps[0]}
+	assert.Empty(t, registry.ValidateSpec(spec))
+	_, err = registry.BuildWorkflowFromSpec(spec)
+	assert.NoError(t, err)
+}
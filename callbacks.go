@@ -0,0 +1,226 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"sync"
+	"time"
+)
+
+// WorkflowCallback is a func definition to be invoked at the end of a workflow run with the final WorkflowReport
+type WorkflowCallback func(ctx context.Context, report WorkflowReport)
+
+// CallbackFailure defines the report data model for a callback that panicked
+type CallbackFailure struct {
+	Callback      string              `yaml:"callback" json:"callback"`
+	Time          time.Time           `yaml:"time" json:"time"`
+	FailureReason errors.EncodedError `yaml:"reason" json:"reason"`
+}
+
+// safeInvoke invokes the callback and recovers from any panic raised by it
+// It returns the CallbackFailure if the callback panicked, otherwise nil.
+func safeInvoke(ctx context.Context, name string, cb WorkflowCallback, report WorkflowReport) (failure *CallbackFailure) {
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				err = errors.Newf("%v", r)
+			}
+
+			err = errors.Wrapf(err, "%s callback panicked", name)
+			failure = &CallbackFailure{
+				Callback:      name,
+				Time:          time.Now(),
+				FailureReason: errors.EncodeError(ctx, err),
+			}
+		}
+	}()
+
+	cb(ctx, report)
+
+	return nil
+}
+
+// BackpressurePolicy defines the behaviour of the async callback dispatcher when its queue is full
+type BackpressurePolicy string
+
+const (
+	// BlockOnFull blocks the caller until there is space in the queue
+	BlockOnFull BackpressurePolicy = "block"
+
+	// DropOnFull drops the callback, the dropped callbacks of a Workflow are counted by Workflow.DroppedCallbacks
+	DropOnFull BackpressurePolicy = "drop"
+)
+
+// callbackDispatcher executes callbacks asynchronously using a bounded set of workers and a bounded queue
+// With a single worker, callbacks are executed in the order they are dispatched.
+type callbackDispatcher struct {
+	queue  chan func()
+	policy BackpressurePolicy
+	wg     sync.WaitGroup
+
+	// closeMutex is held by dispatch while enqueueing so that drain never closes the queue under a blocked dispatch
+	closeMutex sync.RWMutex
+	closed     bool
+
+	// pending is the number of dispatched callbacks not executed yet and idle is closed whenever it is zero
+	// waiting is the number of pending callbacks waiting for the others and settled is closed whenever all the
+	// pending callbacks are waiting, see waitFromCallback
+	mutex   sync.Mutex
+	pending int
+	idle    chan struct{}
+	waiting int
+	settled chan struct{}
+}
+
+// newCallbackDispatcher returns a callbackDispatcher with its workers started
+// workers and queueSize are set as 1 if a non-positive value is provided
+func newCallbackDispatcher(workers int, queueSize int, policy BackpressurePolicy) *callbackDispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	d := &callbackDispatcher{
+		queue:   make(chan func(), queueSize),
+		policy:  policy,
+		idle:    make(chan struct{}),
+		settled: make(chan struct{}),
+	}
+	close(d.idle)
+	close(d.settled)
+
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for fn := range d.queue {
+				fn()
+				d.done()
+			}
+		}()
+	}
+
+	return d
+}
+
+// dispatch enqueues the callback as per the BackpressurePolicy of the dispatcher
+// It returns false if the callback was dropped, i.e. the queue is full with DropOnFull or the dispatcher is drained.
+func (d *callbackDispatcher) dispatch(fn func()) bool {
+	d.closeMutex.RLock()
+	defer d.closeMutex.RUnlock()
+
+	if d.closed {
+		return false
+	}
+
+	d.add()
+
+	if d.policy == DropOnFull {
+		select {
+		case d.queue <- fn:
+			return true
+		default:
+			d.done()
+			return false
+		}
+	}
+
+	d.queue <- fn
+	return true
+}
+
+// wait blocks until all the dispatched callbacks are executed or the context is done
+// Unlike drain, the dispatcher can still be used after wait returns.
+func (d *callbackDispatcher) wait(ctx context.Context) error {
+	d.mutex.Lock()
+	idle := d.idle
+	d.mutex.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitFromCallback is like wait for a callback executed by the dispatcher
+// The calling callback is excluded from the callbacks to wait for, otherwise it would wait for itself. Likewise,
+// callbacks waiting at the same time don't wait for each other.
+func (d *callbackDispatcher) waitFromCallback(ctx context.Context) error {
+	d.mutex.Lock()
+	d.waiting++
+	d.update()
+	settled := d.settled
+	d.mutex.Unlock()
+
+	defer func() {
+		d.mutex.Lock()
+		d.waiting--
+		d.update()
+		d.mutex.Unlock()
+	}()
+
+	select {
+	case <-settled:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// add increments the number of pending callbacks
+func (d *callbackDispatcher) add() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.pending++
+	d.update()
+}
+
+// done decrements the number of pending callbacks
+func (d *callbackDispatcher) done() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.pending--
+	d.update()
+}
+
+// update closes idle and settled once their condition is met, otherwise it replaces them by new ones if closed
+// It must be invoked with the mutex locked.
+func (d *callbackDispatcher) update() {
+	d.idle = signal(d.idle, d.pending == 0)
+	d.settled = signal(d.settled, d.pending <= d.waiting)
+}
+
+// signal returns the channel closed if the condition is met, otherwise an open channel
+func signal(ch chan struct{}, condition bool) chan struct{} {
+	select {
+	case <-ch:
+		if !condition {
+			return make(chan struct{})
+		}
+	default:
+		if condition {
+			close(ch)
+		}
+	}
+
+	return ch
+}
+
+// drain stops accepting callbacks and waits until all the queued callbacks are executed
+// Callbacks dispatched after drain are dropped.
+func (d *callbackDispatcher) drain() {
+	d.closeMutex.Lock()
+	d.closed = true
+	close(d.queue)
+	d.closeMutex.Unlock()
+
+	d.wg.Wait()
+}
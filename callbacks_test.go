@@ -0,0 +1,310 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallbackDispatcher(t *testing.T) {
+	var count int32
+	d := newCallbackDispatcher(2, 10, BlockOnFull)
+	for i := 0; i < 50; i++ {
+		assert.True(t, d.dispatch(func() { atomic.AddInt32(&count, 1) }))
+	}
+	d.drain()
+	assert.Equal(t, int32(50), atomic.LoadInt32(&count))
+
+	// a blocked worker with a full queue should drop callbacks
+	block := make(chan struct{})
+	started := make(chan struct{})
+	d = newCallbackDispatcher(0, 0, DropOnFull)
+	assert.True(t, d.dispatch(func() { close(started); <-block }))
+	<-started
+	assert.True(t, d.dispatch(func() {}))
+	assert.False(t, d.dispatch(func() {}))
+	close(block)
+	d.drain()
+
+	// callbacks dispatched after drain are dropped
+	assert.False(t, d.dispatch(func() {}))
+}
+
+func TestWorkflow_Callbacks(t *testing.T) {
+	ctx := context.Background()
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	restart := &mockRestartContainersStep{
+		Step:  Step{ID: "restart_containers"},
+		cache: map[string][]byte{},
+	}
+	restart.RegisterSaga(restart.run, restart.rollback)
+
+	var completed, failed int32
+	onCompletion := func(ctx context.Context, report WorkflowReport) {
+		assert.Equal(t, StatusSuccess, report.Status)
+		atomic.AddInt32(&completed, 1)
+	}
+	onFailure := func(ctx context.Context, report WorkflowReport) {
+		assert.Equal(t, StatusFailed, report.Status)
+		atomic.AddInt32(&failed, 1)
+	}
+
+	workflow1 := NewWorkflow("workflow_1", WithSteps(fetch), WithOnCompletion(onCompletion), WithOnFailure(onFailure))
+	_, err := workflow1.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&completed))
+
+	workflow2 := NewWorkflow("workflow_2", WithSteps(fetch, restart),
+		WithOnCompletion(onCompletion), WithOnFailure(onFailure), WithAsyncCallbacks(1, 10, BlockOnFull))
+	for i := 0; i < 5; i++ {
+		_, err = workflow2.Start(ctx)
+		assert.Error(t, err)
+	}
+	workflow2.End(ctx)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&failed))
+	assert.Nil(t, workflow2.dispatcher)
+}
+
+func TestWorkflow_CallbackPanic(t *testing.T) {
+	ctx := context.Background()
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	onCompletion := func(ctx context.Context, report WorkflowReport) {
+		panic("mock panic")
+	}
+
+	workflow1 := NewWorkflow("workflow_1", WithSteps(fetch), WithOnCompletion(onCompletion))
+	report, err := workflow1.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.Equal(t, 1, len(report.CallbackFailures))
+	assert.Equal(t, "onCompletion", report.CallbackFailures[0].Callback)
+
+	workflow2 := NewWorkflow("workflow_2", WithSteps(fetch), WithOnCompletion(onCompletion),
+		WithAsyncCallbacks(1, 1, BlockOnFull))
+	report, err = workflow2.Start(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, report.CallbackFailures)
+	workflow2.End(ctx)
+	assert.Equal(t, 1, len(workflow2.CallbackFailures()))
+}
+
+func TestWorkflow_WaitCallbacks(t *testing.T) {
+	ctx := context.Background()
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	var order []int
+	var runs int
+	block := make(chan struct{})
+	onCompletion := func(ctx context.Context, report WorkflowReport) {
+		<-block
+		runs++
+		order = append(order, runs)
+	}
+
+	workflow := NewWorkflow("workflow_1", WithSteps(fetch), WithOnCompletion(onCompletion),
+		WithAsyncCallbacks(1, 10, BlockOnFull))
+	assert.NoError(t, workflow.WaitCallbacks(ctx))
+
+	for i := 0; i < 3; i++ {
+		_, err := workflow.Start(ctx)
+		assert.NoError(t, err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, workflow.WaitCallbacks(timeoutCtx))
+
+	close(block)
+	assert.NoError(t, workflow.WaitCallbacks(ctx))
+	assert.Equal(t, []int{1, 2, 3}, order)
+
+	// dispatcher can still be used after wait
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, workflow.WaitCallbacks(ctx))
+	assert.Equal(t, 4, len(order))
+	workflow.End(ctx)
+}
+
+func TestWorkflow_DroppedCallbacks(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	var once sync.Once
+	started := make(chan struct{})
+	block := make(chan struct{})
+	onCompletion := func(ctx context.Context, report WorkflowReport) {
+		once.Do(func() { close(started) })
+		<-block
+	}
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1), WithOnCompletion(onCompletion),
+		WithAsyncCallbacks(1, 1, DropOnFull))
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	<-started
+
+	for i := 0; i < 2; i++ {
+		_, err = workflow.Start(ctx)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(1), workflow.DroppedCallbacks())
+
+	close(block)
+	workflow.End(ctx)
+	assert.Equal(t, uint64(1), workflow.DroppedCallbacks())
+}
+
+func TestWorkflow_CallbacksBlockedOnFullQueue(t *testing.T) {
+	ctx := context.Background()
+
+	var runs int32
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		atomic.AddInt32(&runs, 1)
+		return false, nil
+	}, nil)
+
+	var callbacks int32
+	var once sync.Once
+	started := make(chan struct{})
+	block := make(chan struct{})
+	onCompletion := func(ctx context.Context, report WorkflowReport) {
+		once.Do(func() { close(started) })
+		<-block
+		atomic.AddInt32(&callbacks, 1)
+	}
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1), WithOnCompletion(onCompletion),
+		WithAsyncCallbacks(1, 1, BlockOnFull))
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	<-started
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	// the callbacks of the next runs are blocked by the full queue but the workflow is not locked
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := workflow.Start(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&runs) == 4
+	}, time.Second, time.Millisecond)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, workflow.WaitCallbacks(timeoutCtx))
+
+	close(block)
+	wg.Wait()
+	workflow.End(ctx)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&callbacks))
+	assert.Equal(t, uint64(0), workflow.DroppedCallbacks())
+}
+
+func TestWorkflow_CallbackReportIsolation(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1), WithOnCompletion(func(ctx context.Context, report WorkflowReport) {
+		report.StepReports[0].Status = StatusFailed
+	}))
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.StepReports[0].Status)
+}
+
+func TestWorkflow_CallbacksUsingWorkflow(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	// callbacks waiting for the callbacks don't wait for themselves nor for each other
+	var workflow *Workflow
+	var waited int32
+	onCompletion := func(ctx context.Context, report WorkflowReport) {
+		waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		assert.NoError(t, workflow.WaitCallbacks(waitCtx))
+		atomic.AddInt32(&waited, 1)
+	}
+
+	workflow = NewWorkflow("workflow_1", WithSteps(s1), WithOnCompletion(onCompletion),
+		WithAsyncCallbacks(2, 10, BlockOnFull))
+	for i := 0; i < 2; i++ {
+		_, err := workflow.Start(ctx)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, workflow.WaitCallbacks(ctx))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&waited))
+	workflow.End(ctx)
+
+	// a callback may start the workflow while it ends
+	var restarted int32
+	block := make(chan struct{})
+	onCompletion = func(ctx context.Context, report WorkflowReport) {
+		<-block
+		if atomic.AddInt32(&restarted, 1) == 1 {
+			_, err := workflow.Start(context.Background())
+			assert.NoError(t, err)
+		}
+	}
+
+	workflow = NewWorkflow("workflow_1", WithSteps(s1), WithOnCompletion(onCompletion),
+		WithAsyncCallbacks(1, 10, BlockOnFull))
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	ended := make(chan struct{})
+	go func() {
+		workflow.End(ctx)
+		close(ended)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(block)
+
+	select {
+	case <-ended:
+	case <-time.After(time.Second):
+		t.Fatal("End is deadlocked by a callback starting the workflow")
+	}
+	workflow.End(ctx)
+}
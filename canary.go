@@ -0,0 +1,89 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"time"
+)
+
+// CanaryCheck is a func definition to validate the canary during the bake time
+// Returning an error aborts the canary and triggers the rollback of the deployment.
+type CanaryCheck func(ctx context.Context) error
+
+// CanaryConfig defines the actions and parameters of a canary workflow
+type CanaryConfig struct {
+	// Deploy deploys the canary and Undeploy removes it on rollback
+	Deploy   SagaRun
+	Undeploy SagaUndo
+
+	// Check validates the canary. It is invoked every CheckInterval until BakeTime has elapsed.
+	// If it is nil, the canary is only baked for BakeTime without any validation.
+	Check         CanaryCheck
+	BakeTime      time.Duration
+	CheckInterval time.Duration
+
+	// Promote promotes the canary to the full fleet and Demote reverses the promotion on rollback
+	Promote SagaRun
+	Demote  SagaUndo
+}
+
+// NewCanaryWorkflow returns a Workflow composed of deploy, bake and promote steps
+// The step IDs are derived from the workflow id, i.e. "<id>_deploy", "<id>_bake" and "<id>_promote".
+// If a check fails during the bake time, the canary is undeployed and the workflow fails without promotion.
+// Any additional WorkflowOption (e.g. WithLogger) is applied after the steps are added.
+func NewCanaryWorkflow(id string, cfg CanaryConfig, opts ...WorkflowOption) (*Workflow, error) {
+	if cfg.Deploy == nil {
+		return nil, errors.Newf("deploy action is required for canary workflow %q", id)
+	}
+
+	if cfg.Promote == nil {
+		return nil, errors.Newf("promote action is required for canary workflow %q", id)
+	}
+
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = cfg.BakeTime
+	}
+
+	deploy := &Step{ID: id + "_deploy"}
+	deploy.RegisterSaga(cfg.Deploy, cfg.Undeploy)
+
+	bake := &Step{ID: id + "_bake"}
+	bake.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, bakeCanary(ctx, cfg)
+	}, nil)
+
+	promote := &Step{ID: id + "_promote"}
+	promote.RegisterSaga(cfg.Promote, cfg.Demote)
+
+	opts = append([]WorkflowOption{WithSteps(deploy, bake, promote)}, opts...)
+
+	return NewWorkflow(id, opts...), nil
+}
+
+// bakeCanary waits for the bake time while validating the canary periodically
+func bakeCanary(ctx context.Context, cfg CanaryConfig) error {
+	deadline := time.Now().Add(cfg.BakeTime)
+	for {
+		if cfg.Check != nil {
+			if err := cfg.Check(ctx); err != nil {
+				return errors.Wrap(err, "canary check failed")
+			}
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil
+		}
+
+		wait := cfg.CheckInterval
+		if wait <= 0 || wait > remaining {
+			wait = remaining
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "canary bake interrupted")
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewCanaryWorkflow(t *testing.T) {
+	ctx := context.Background()
+
+	var deployed, promoted bool
+	cfg := CanaryConfig{
+		Deploy: func(ctx context.Context) (skipped bool, err error) {
+			deployed = true
+			return false, nil
+		},
+		Undeploy: func(ctx context.Context) (skipped bool, err error) {
+			deployed = false
+			return false, nil
+		},
+		BakeTime:      5 * time.Millisecond,
+		CheckInterval: time.Millisecond,
+		Promote: func(ctx context.Context) (skipped bool, err error) {
+			promoted = true
+			return false, nil
+		},
+	}
+
+	_, err := NewCanaryWorkflow("canary", CanaryConfig{Promote: cfg.Promote})
+	assert.Error(t, err)
+	_, err = NewCanaryWorkflow("canary", CanaryConfig{Deploy: cfg.Deploy})
+	assert.Error(t, err)
+
+	checks := 0
+	cfg.Check = func(ctx context.Context) error {
+		checks++
+		return nil
+	}
+	workflow, err := NewCanaryWorkflow("canary", cfg)
+	assert.NoError(t, err)
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.True(t, deployed)
+	assert.True(t, promoted)
+	assert.Greater(t, checks, 1)
+	assert.Equal(t, StepIDs{"canary_deploy", "canary_bake", "canary_promote"}, report.StepSequence)
+
+	// failed check aborts the canary
+	deployed, promoted = false, false
+	cfg.Check = func(ctx context.Context) error {
+		return errors.New("error rate too high")
+	}
+	workflow, err = NewCanaryWorkflow("canary", cfg)
+	assert.NoError(t, err)
+	_, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.False(t, deployed)
+	assert.False(t, promoted)
+
+	// cancelled context interrupts the bake time
+	cfg.Check = nil
+	cfg.BakeTime = time.Hour
+	cfg.CheckInterval = 0
+	workflow, err = NewCanaryWorkflow("canary", cfg)
+	assert.NoError(t, err)
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = workflow.Start(cancelledCtx)
+	assert.Error(t, err)
+	assert.False(t, promoted)
+}
@@ -0,0 +1,126 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWorkflow_Cancel(t *testing.T) {
+	var rolledBack []string
+	newSteps := func(cancel context.CancelFunc) []AtomicStep {
+		s1 := &Step{ID: "step_1"}
+		s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			return false, nil
+		}, func(ctx context.Context) (skipped bool, err error) {
+			// rollback is not cancelled
+			assert.NoError(t, ctx.Err())
+			rolledBack = append(rolledBack, "step_1")
+			return false, nil
+		})
+
+		s2 := &Step{ID: "step_2"}
+		s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			cancel()
+			return false, nil
+		}, func(ctx context.Context) (skipped bool, err error) {
+			rolledBack = append(rolledBack, "step_2")
+			return false, nil
+		})
+
+		s3 := &Step{ID: "step_3"}
+		s3.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			assert.Fail(t, "step_3 must not run")
+			return false, nil
+		}, nil)
+
+		return []AtomicStep{s1, s2, s3}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	workflow := NewWorkflow("workflow_1", WithSteps(newSteps(cancel)...))
+	report, err := workflow.Start(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StatusCancelled, report.Status)
+	assert.Equal(t, []string{"step_2", "step_1"}, rolledBack)
+
+	assert.Equal(t, 5, len(report.StepReports))
+	assert.Equal(t, "step_3", report.StepReports[2].StepID)
+	assert.Equal(t, RunAction, report.StepReports[2].Action)
+	assert.Equal(t, StatusCancelled, report.StepReports[2].Status)
+	assert.Equal(t, StatusSuccess, report.StepReports[3].Status)
+	assert.Empty(t, report.HardFailures())
+	stepErr, ok := AsStepError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "step_3", stepErr.StepID)
+
+	// stop on cancel
+	rolledBack = nil
+	ctx, cancel = context.WithCancel(context.Background())
+	workflow = NewWorkflow("workflow_1", WithSteps(newSteps(cancel)...), WithCancelBehavior(StopOnCancel))
+	report, err = workflow.Start(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StatusCancelled, report.Status)
+	assert.Empty(t, rolledBack)
+	assert.Equal(t, 3, len(report.StepReports))
+}
+
+func TestWorkflow_CancelDuringStep(t *testing.T) {
+	var rolledBack []string
+	newSteps := func(cancel context.CancelFunc) []AtomicStep {
+		s1 := &Step{ID: "step_1"}
+		s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			return false, nil
+		}, func(ctx context.Context) (skipped bool, err error) {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			rolledBack = append(rolledBack, "step_1")
+			return false, nil
+		})
+
+		s2 := &Step{ID: "step_2"}
+		s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			cancel()
+			<-ctx.Done()
+			return false, ctx.Err()
+		}, func(ctx context.Context) (skipped bool, err error) {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			rolledBack = append(rolledBack, "step_2")
+			return false, nil
+		})
+
+		return []AtomicStep{s1, s2}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	workflow := NewWorkflow("workflow_1", WithSteps(newSteps(cancel)...))
+	report, err := workflow.Start(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StatusCancelled, report.Status)
+	assert.Equal(t, []string{"step_2", "step_1"}, rolledBack)
+
+	assert.Equal(t, 4, len(report.StepReports))
+	assert.Equal(t, "step_2", report.StepReports[1].StepID)
+	assert.Equal(t, RunAction, report.StepReports[1].Action)
+	assert.Equal(t, StatusCancelled, report.StepReports[1].Status)
+	assert.Equal(t, StatusSuccess, report.StepReports[2].Status)
+	assert.Equal(t, StatusSuccess, report.StepReports[3].Status)
+	assert.Empty(t, report.HardFailures())
+	stepErr, ok := AsStepError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "step_2", stepErr.StepID)
+
+	// stop on cancel
+	rolledBack = nil
+	ctx, cancel = context.WithCancel(context.Background())
+	workflow = NewWorkflow("workflow_1", WithSteps(newSteps(cancel)...), WithCancelBehavior(StopOnCancel))
+	report, err = workflow.Start(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StatusCancelled, report.Status)
+	assert.Empty(t, rolledBack)
+	assert.Equal(t, 2, len(report.StepReports))
+	assert.Equal(t, StatusCancelled, report.StepReports[1].Status)
+}
@@ -0,0 +1,10 @@
+//go:build !go1.20
+
+package automa
+
+import "context"
+
+// contextCause returns the error of the context since cancellation causes are only supported since go1.20
+func contextCause(ctx context.Context) error {
+	return ctx.Err()
+}
@@ -0,0 +1,10 @@
+//go:build go1.20
+
+package automa
+
+import "context"
+
+// contextCause returns the cause of the cancellation of the context, see context.Cause
+func contextCause(ctx context.Context) error {
+	return context.Cause(ctx)
+}
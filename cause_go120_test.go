@@ -0,0 +1,51 @@
+//go:build go1.20
+
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWithCancelCause(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	mockErr := errors.New("mock error")
+	assert.Equal(t, mockErr, withCancelCause(ctx, mockErr))
+
+	cause := errors.New("deployment superseded by a newer release")
+	cancel(cause)
+
+	err := withCancelCause(ctx, errors.Wrap(ctx.Err(), "step interrupted"))
+	assert.Equal(t, cause.Error(), err.Error())
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, mockErr, withCancelCause(ctx, mockErr))
+	assert.Nil(t, withCancelCause(ctx, nil))
+
+	// without cause the error is not changed
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	cancelCtx()
+	assert.Equal(t, context.Canceled, withCancelCause(ctx, context.Canceled))
+}
+
+func TestStep_CancelCause(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cause := errors.New("maintenance window closed")
+
+	s1 := &Step{ID: "drain_node"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		cancel(cause)
+		<-ctx.Done()
+		return false, ctx.Err()
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), cause.Error())
+
+	reason := errors.DecodeError(ctx, report.StepReports[0].FailureReason)
+	assert.Equal(t, cause.Error(), reason.Error())
+}
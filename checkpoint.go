@@ -0,0 +1,144 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWorkflowPaused is returned by Start and Resume when the run is paused using Workflow.Pause
+var ErrWorkflowPaused = errors.New("workflow paused")
+
+// Checkpoint defines the progress of a paused workflow run so that it can be resumed later, possibly in another process
+// It is serializable as JSON or YAML.
+type Checkpoint struct {
+	WorkflowID   string        `yaml:"workflow_id" json:"workflowID"`
+	RunID        string        `yaml:"run_id" json:"runID"`
+	ManifestHash string        `yaml:"manifest_hash" json:"manifestHash"`
+	Status       Status        `yaml:"status" json:"status"`
+	StartTime    time.Time     `yaml:"start_time" json:"startTime"`
+	NextStep     string        `yaml:"next_step" json:"nextStep"`
+	StepReports  []*StepReport `yaml:"step_reports" json:"stepReports"`
+}
+
+// pauseSignal is the pause request of a workflow run shared with the steps through the context
+type pauseSignal struct {
+	requested int32
+}
+
+// withPauseSignal returns a copy of the context with the given pauseSignal
+func withPauseSignal(ctx context.Context, p *pauseSignal) context.Context {
+	return context.WithValue(ctx, ctxKeyPauseSignal, p)
+}
+
+// pauseRequested returns true if the pause of the workflow run has been requested
+func pauseRequested(ctx context.Context) bool {
+	p, ok := ctx.Value(ctxKeyPauseSignal).(*pauseSignal)
+	return ok && atomic.LoadInt32(&p.requested) == 1
+}
+
+// Pause requests the current run of the Workflow to pause before its next step
+// The step being executed is completed first. Start or Resume then returns ErrWorkflowPaused and the report has
+// StatusPaused. The progress of the run can be retrieved using Checkpoint in order to Resume it later.
+func (wf *Workflow) Pause() {
+	atomic.StoreInt32(&wf.pause.requested, 1)
+}
+
+// Checkpoint returns the Checkpoint of the last run of the Workflow
+// It returns error if the last run was not paused.
+func (wf *Workflow) Checkpoint() (*Checkpoint, error) {
+	wf.mutex.Lock()
+	defer wf.mutex.Unlock()
+
+	if wf.report.Status != StatusPaused {
+		return nil, errors.Newf("workflow %q cannot be checkpointed since its status is %s", wf.id, wf.report.Status)
+	}
+
+	return newCheckpoint(wf.report, wf.stepIDs), nil
+}
+
+// newCheckpoint returns the Checkpoint of a run as per its report
+// The next step is the first step of the sequence without any RunAction in the report.
+func newCheckpoint(report WorkflowReport, stepIDs StepIDs) *Checkpoint {
+	cp := &Checkpoint{
+		WorkflowID:   report.WorkflowID,
+		RunID:        report.RunID,
+		ManifestHash: report.ManifestHash,
+		Status:       report.Status,
+		StartTime:    report.StartTime,
+	}
+
+	executed := map[string]bool{}
+	for _, stepReport := range report.StepReports {
+		cp.StepReports = append(cp.StepReports, stepReport.Clone())
+		if stepReport.Action == RunAction {
+			executed[stepReport.StepID] = true
+		}
+	}
+
+	for _, id := range stepIDs {
+		if !executed[id] {
+			cp.NextStep = id
+			break
+		}
+	}
+
+	return cp
+}
+
+// Resume resumes a paused run of the Workflow from the Checkpoint, skipping the steps that were already executed
+// The workflow definition must be the same as when the checkpoint was taken, i.e. it must have the same manifest hash.
+// If a step fails after resuming, the steps executed before the pause are rolled back as well.
+func (wf *Workflow) Resume(ctx context.Context, cp *Checkpoint) (WorkflowReport, error) {
+	defer wf.dispatchCallbacks()
+	wf.mutex.Lock()
+	defer wf.mutex.Unlock()
+
+	if cp.WorkflowID != wf.id {
+		return wf.report, errors.Newf("checkpoint of workflow %q cannot be resumed by workflow %q", cp.WorkflowID, wf.id)
+	}
+
+	manifestHash := wf.Manifest().Hash()
+	if cp.ManifestHash != manifestHash {
+		return wf.report, errors.Newf("workflow %q has changed since run %q was checkpointed", wf.id, cp.RunID)
+	}
+
+	var step AtomicStep
+	for _, s := range wf.steps {
+		if s.GetID() == cp.NextStep {
+			step = s
+			break
+		}
+	}
+
+	if step == nil {
+		return wf.report, errors.Newf("step %q of run %q is not found in workflow %q", cp.NextStep, cp.RunID, wf.id)
+	}
+
+	if err := wf.admit(ctx, AdmitResume, cp.RunID); err != nil {
+		return wf.report, err
+	}
+
+	release, err := wf.acquire(ctx)
+	if err != nil {
+		return wf.report, err
+	}
+	defer release()
+
+	wf.report.StepSequence = wf.stepIDs
+	wf.report.Status = StatusUndefined
+	wf.report.StartTime = cp.StartTime
+	wf.report.StepReports = []*StepReport{}
+	for _, stepReport := range cp.StepReports {
+		wf.report.StepReports = append(wf.report.StepReports, stepReport.Clone())
+	}
+	wf.report.Outputs = map[string][]byte{}
+	wf.report.RunID = cp.RunID
+	wf.report.ManifestHash = manifestHash
+	wf.report.ManifestDiff = nil
+
+	return wf.execute(ctx, func(ctx context.Context) (WorkflowReport, error) {
+		return runStep(wf.phaseContext(ctx, step.GetID()), step, NewStartTrigger(wf.report))
+	})
+}
@@ -0,0 +1,86 @@
+package automa
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWorkflow_PauseResume(t *testing.T) {
+	ctx := context.Background()
+
+	var executed []string
+	newWorkflow := func(pauseAfter string, failAt string) *Workflow {
+		var wf *Workflow
+		var steps []AtomicStep
+		for _, id := range []string{"step_1", "step_2", "step_3"} {
+			id := id
+			s := &Step{ID: id}
+			s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+				executed = append(executed, "run_"+id)
+				if id == pauseAfter {
+					wf.Pause()
+				}
+				if id == failAt {
+					return false, errors.New("mock error")
+				}
+				return false, nil
+			}, func(ctx context.Context) (skipped bool, err error) {
+				executed = append(executed, "rollback_"+id)
+				return false, nil
+			})
+			steps = append(steps, s)
+		}
+		wf = NewWorkflow("workflow_1", WithSteps(steps...))
+		return wf
+	}
+
+	workflow := newWorkflow("step_1", "")
+	_, err := workflow.Checkpoint()
+	assert.Error(t, err)
+
+	report, err := workflow.Start(ctx)
+	assert.ErrorIs(t, err, ErrWorkflowPaused)
+	assert.Equal(t, StatusPaused, report.Status)
+	assert.Equal(t, []string{"run_step_1"}, executed)
+
+	cp, err := workflow.Checkpoint()
+	assert.NoError(t, err)
+	assert.Equal(t, "step_2", cp.NextStep)
+	assert.Equal(t, report.RunID, cp.RunID)
+
+	// the checkpoint can be persisted and resumed by another process
+	b, err := json.Marshal(cp)
+	assert.NoError(t, err)
+	restored := &Checkpoint{}
+	assert.NoError(t, json.Unmarshal(b, restored))
+
+	executed = nil
+	resumed := newWorkflow("", "")
+	report, err = resumed.Resume(ctx, restored)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.Equal(t, cp.RunID, report.RunID)
+	assert.Equal(t, []string{"run_step_2", "run_step_3"}, executed)
+	assert.Equal(t, 3, len(report.StepReports))
+	assert.Equal(t, "step_1", report.StepReports[0].StepID)
+
+	// a failure after resuming rolls back the steps executed before the pause
+	executed = nil
+	report, err = newWorkflow("", "step_3").Resume(ctx, restored)
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, []string{"run_step_2", "run_step_3", "rollback_step_3", "rollback_step_2", "rollback_step_1"}, executed)
+
+	// the workflow definition must not change
+	changed := newWorkflow("", "")
+	changed.steps[0].(*Step).WithMemoize(true)
+	_, err = changed.Resume(ctx, restored)
+	assert.Error(t, err)
+
+	restored.WorkflowID = "workflow_2"
+	_, err = newWorkflow("", "").Resume(ctx, restored)
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package automa
+
+import (
+	"context"
+	"time"
+)
+
+// WithClockSkewTolerance normalizes the timestamps of the step reports against the clock of the workflow
+// Steps executed on remote machines may report timestamps from a clock that is not in sync with the clock of the
+// workflow. At the end of every run, a StepReport starting before the start of the run or ending after its end by
+// more than the tolerance is shifted, preserving its duration, so that it fits in the run. The shift is recorded in
+// StepReport.ClockSkew and reported as a warning.
+func WithClockSkewTolerance(tolerance time.Duration) WorkflowOption {
+	return func(wf *Workflow) {
+		if tolerance < 0 {
+			tolerance = 0
+		}
+
+		wf.clockSkewTolerance = &tolerance
+	}
+}
+
+// EstimateClockSkew returns the offset of a remote clock given the local times a remote call was sent and its
+// response was received, as well as the remote times the call started and ended
+// It assumes that the network delays of the request and the response are the same, as in NTP. A positive skew
+// means that the remote clock is ahead of the local clock.
+func EstimateClockSkew(localSent, localReceived, remoteStart, remoteEnd time.Time) time.Duration {
+	localMid := localSent.Add(localReceived.Sub(localSent) / 2)
+	remoteMid := remoteStart.Add(remoteEnd.Sub(remoteStart) / 2)
+
+	return remoteMid.Sub(localMid)
+}
+
+// NormalizeClock converts StartTime and EndTime from a remote clock having the given skew to the local clock
+// The skew is added to ClockSkew, e.g. as estimated using EstimateClockSkew.
+func (sr *StepReport) NormalizeClock(skew time.Duration) {
+	if skew == 0 {
+		return
+	}
+
+	sr.StartTime = sr.StartTime.Add(-skew)
+	sr.EndTime = sr.EndTime.Add(-skew)
+	sr.ClockSkew += skew
+}
+
+// normalizeClocks shifts the step reports that do not fit in the given time range by more than the tolerance
+func (wfr *WorkflowReport) normalizeClocks(ctx context.Context, start time.Time, end time.Time, tolerance time.Duration) {
+	for _, stepReport := range wfr.StepReports {
+		if stepReport.StartTime.IsZero() || stepReport.EndTime.IsZero() {
+			continue
+		}
+
+		var skew time.Duration
+		if stepReport.StartTime.Before(start.Add(-tolerance)) {
+			skew = stepReport.StartTime.Sub(start)
+		} else if stepReport.EndTime.After(end.Add(tolerance)) {
+			skew = stepReport.EndTime.Sub(end)
+		}
+
+		if skew != 0 {
+			stepReport.NormalizeClock(skew)
+			AddWarning(ctx, "timestamps of %s action of step %q were shifted by %s to the clock of the workflow",
+				stepReport.Action, stepReport.StepID, -skew)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// mockRemoteStep is an example of a step reporting the timestamps of a remote machine
+type mockRemoteStep struct {
+	Step
+	skew time.Duration
+}
+
+func (s *mockRemoteStep) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	report := NewStepReport(s.GetID(), RunAction)
+	report.StartTime = report.StartTime.Add(s.skew)
+
+	next, err := s.RunNext(ctx, prevSuccess, report)
+	// RunNext sets EndTime using the local clock
+	report.EndTime = report.StartTime
+
+	return next, err
+}
+
+func TestEstimateClockSkew(t *testing.T) {
+	now := time.Now()
+	skew := EstimateClockSkew(now, now.Add(100*time.Millisecond),
+		now.Add(time.Minute+40*time.Millisecond), now.Add(time.Minute+60*time.Millisecond))
+	assert.Equal(t, time.Minute, skew)
+}
+
+func TestStepReport_NormalizeClock(t *testing.T) {
+	report := NewStepReport("step_1", RunAction)
+	start := report.StartTime
+	report.NormalizeClock(time.Minute)
+	report.NormalizeClock(time.Second)
+	assert.Equal(t, start.Add(-time.Minute-time.Second), report.StartTime)
+	assert.Equal(t, time.Minute+time.Second, report.ClockSkew)
+}
+
+func TestWithClockSkewTolerance(t *testing.T) {
+	ctx := context.Background()
+	ahead := &mockRemoteStep{Step: Step{ID: "ahead"}, skew: time.Hour}
+	behind := &mockRemoteStep{Step: Step{ID: "behind"}, skew: -time.Hour}
+	local := &mockRemoteStep{Step: Step{ID: "local"}}
+
+	workflow := NewWorkflow("workflow_1", WithSteps(ahead, behind, local), WithClockSkewTolerance(time.Minute))
+	defer workflow.End(ctx)
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(report.StepReports))
+	for _, stepReport := range report.StepReports {
+		assert.False(t, stepReport.StartTime.Before(report.StartTime), stepReport.StepID)
+		assert.False(t, stepReport.EndTime.After(report.EndTime), stepReport.StepID)
+		assert.Equal(t, stepReport.StartTime, stepReport.EndTime, stepReport.StepID)
+	}
+
+	assert.InDelta(t, time.Hour, report.StepReports[0].ClockSkew, float64(time.Second))
+	assert.InDelta(t, -time.Hour, report.StepReports[1].ClockSkew, float64(time.Second))
+	assert.Equal(t, time.Duration(0), report.StepReports[2].ClockSkew)
+	assert.Equal(t, 2, len(report.Warnings))
+
+	// without tolerance, timestamps are reported as is
+	workflow = NewWorkflow("workflow_2", WithSteps(&mockRemoteStep{Step: Step{ID: "ahead"}, skew: time.Hour}))
+	report, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.True(t, report.StepReports[0].StartTime.After(report.EndTime))
+}
@@ -0,0 +1,132 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"strconv"
+	"time"
+)
+
+// ErrNotConsistent is the error returned when a ConsistencyCheck does not succeed before the timeout
+var ErrNotConsistent = errors.New("read did not converge")
+
+// ConsistencyCheck reads from an eventually consistent system, e.g. DNS, IAM or S3, and returns true once the result
+// of the previous writes is visible
+// An error stops the wait, a not-found read is therefore expected to return false rather than an error.
+type ConsistencyCheck func(ctx context.Context) (bool, error)
+
+// ConsistencyOptions defines how long and how often a ConsistencyCheck is re-checked
+// Timeout and Backoff are set as DefaultConsistencyTimeout and DefaultConsistencyBackoff respectively if not provided.
+type ConsistencyOptions struct {
+	Timeout time.Duration
+	Backoff Backoff
+}
+
+const (
+	// DefaultConsistencyTimeout is the default maximum duration to wait for a ConsistencyCheck to succeed
+	DefaultConsistencyTimeout = time.Minute
+
+	// ConsistencyChecksMetadataKey is the key of StepReport.Metadata containing the number of consistency checks
+	ConsistencyChecksMetadataKey = "consistency_checks"
+
+	// ConvergenceTimeMetadataKey is the key of StepReport.Metadata containing the time it took for the reads to converge
+	ConvergenceTimeMetadataKey = "convergence_time"
+)
+
+// DefaultConsistencyBackoff is the default delay between the consistency checks
+var DefaultConsistencyBackoff = ExponentialBackoff(100*time.Millisecond, 5*time.Second)
+
+// ConsistencyResult defines the outcome of WaitForConsistency
+type ConsistencyResult struct {
+	// Checks is the number of times the ConsistencyCheck was invoked
+	Checks int
+
+	// ConvergenceTime is the duration from the first check until the check succeeded or the wait stopped
+	ConvergenceTime time.Duration
+}
+
+// WaitForConsistency invokes the check until it returns true, it fails or the timeout of the options elapses
+// Every check is invoked with a context that is cancelled once the timeout elapses, so that a hung read doesn't block
+// the wait. It returns an error matching ErrNotConsistent on timeout, or the error of the check or the context
+// otherwise.
+func WaitForConsistency(ctx context.Context, check ConsistencyCheck, opts ConsistencyOptions) (ConsistencyResult, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultConsistencyTimeout
+	}
+
+	if opts.Backoff == nil {
+		opts.Backoff = DefaultConsistencyBackoff
+	}
+
+	start := time.Now()
+	deadline := start.Add(opts.Timeout)
+	result := ConsistencyResult{}
+	for {
+		result.Checks++
+		checkCtx, cancel := context.WithDeadline(ctx, deadline)
+		ok, err := check(checkCtx)
+		cancel()
+		result.ConvergenceTime = time.Since(start)
+		if err != nil && ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		if err != nil && !time.Now().Before(deadline) {
+			return result, errors.Wrapf(ErrNotConsistent, "after %d checks in %s: %v", result.Checks, result.ConvergenceTime, err)
+		}
+
+		if err != nil {
+			return result, errors.Wrapf(err, "consistency check %d failed", result.Checks)
+		}
+
+		if ok {
+			return result, nil
+		}
+
+		delay := opts.Backoff(result.Checks)
+		if time.Now().Add(delay).After(deadline) {
+			return result, errors.Wrapf(ErrNotConsistent, "after %d checks in %s", result.Checks, result.ConvergenceTime)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			result.ConvergenceTime = time.Since(start)
+			return result, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// WithConsistencyCheck waits for the check to succeed once SagaRun succeeded, see WaitForConsistency
+// The number of checks and the convergence time are recorded in the Metadata of the report of the step. If the reads
+// do not converge, the run of the step fails as if SagaRun failed. The wait is subject to the timeout of the step and
+// of the workflow as well.
+func (s *Step) WithConsistencyCheck(check ConsistencyCheck, opts ConsistencyOptions) *Step {
+	s.consistencyCheck = check
+	s.consistencyOptions = opts
+
+	return s
+}
+
+// waitForConsistency waits for the consistency check of the step, if any, and annotates the report
+func (s *Step) waitForConsistency(ctx context.Context, report *StepReport) error {
+	if s.consistencyCheck == nil {
+		return nil
+	}
+
+	// the check is user code like SagaRun, a panic fails the run of the step and is reported in WorkflowReport.Panics
+	check := func(ctx context.Context) (bool, error) {
+		return callSaga(ctx, s.GetID(), RunAction, s.consistencyCheck)
+	}
+
+	waitCtx, cancel := s.runContext(ctx)
+	defer cancel()
+
+	result, err := WaitForConsistency(s.stepContext(waitCtx), check, s.consistencyOptions)
+	report.Metadata[ConsistencyChecksMetadataKey] = []byte(strconv.Itoa(result.Checks))
+	report.Metadata[ConvergenceTimeMetadataKey] = []byte(result.ConvergenceTime.String())
+
+	return withCancelCause(ctx, err)
+}
@@ -0,0 +1,153 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// eventuallyVisible returns a ConsistencyCheck that succeeds from the given check
+func eventuallyVisible(visibleAt int) ConsistencyCheck {
+	checks := 0
+	return func(ctx context.Context) (bool, error) {
+		checks++
+		return checks >= visibleAt, nil
+	}
+}
+
+func TestWaitForConsistency(t *testing.T) {
+	ctx := context.Background()
+	opts := ConsistencyOptions{Timeout: time.Second, Backoff: ConstantBackoff(time.Millisecond)}
+
+	result, err := WaitForConsistency(ctx, eventuallyVisible(3), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Checks)
+	assert.True(t, result.ConvergenceTime >= 2*time.Millisecond)
+
+	result, err = WaitForConsistency(ctx, eventuallyVisible(100), ConsistencyOptions{
+		Timeout: 10 * time.Millisecond,
+		Backoff: ConstantBackoff(4 * time.Millisecond),
+	})
+	assert.True(t, errors.Is(err, ErrNotConsistent))
+	assert.True(t, result.Checks < 100)
+
+	_, err = WaitForConsistency(ctx, func(ctx context.Context) (bool, error) {
+		return false, errors.New("access denied")
+	}, opts)
+	assert.EqualError(t, err, "consistency check 1 failed: access denied")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = WaitForConsistency(cancelled, eventuallyVisible(100), opts)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// a hung read is cancelled once the timeout elapses
+	result, err = WaitForConsistency(ctx, func(ctx context.Context) (bool, error) {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}, ConsistencyOptions{Timeout: 10 * time.Millisecond})
+	assert.True(t, errors.Is(err, ErrNotConsistent))
+	assert.Equal(t, 1, result.Checks)
+}
+
+func TestStep_WithConsistencyCheck(t *testing.T) {
+	ctx := context.Background()
+	opts := ConsistencyOptions{Timeout: time.Second, Backoff: ConstantBackoff(time.Millisecond)}
+
+	var checkedStep string
+	createRecord := &Step{ID: "create_dns_record"}
+	createRecord.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil).WithConsistencyCheck(func(ctx context.Context) (bool, error) {
+		checkedStep, _ = StepFromContext(ctx)
+		return eventuallyVisible(1)(ctx)
+	}, opts)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(createRecord))
+	defer workflow.End(ctx)
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "create_dns_record", checkedStep)
+	assert.Equal(t, []byte("1"), report.StepReports[0].Metadata[ConsistencyChecksMetadataKey])
+	assert.NotEmpty(t, report.StepReports[0].Metadata[ConvergenceTimeMetadataKey])
+
+	// a step whose reads do not converge fails and is rolled back
+	rolledBack := false
+	createRole := &Step{ID: "create_iam_role"}
+	createRole.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		rolledBack = true
+		return false, nil
+	}).WithConsistencyCheck(eventuallyVisible(100), ConsistencyOptions{
+		Timeout: 5 * time.Millisecond,
+		Backoff: ConstantBackoff(2 * time.Millisecond),
+	})
+
+	workflow = NewWorkflow("workflow_2", WithSteps(createRole))
+	defer workflow.End(ctx)
+	report, err = workflow.Start(ctx)
+	assert.True(t, errors.Is(err, ErrNotConsistent))
+	assert.True(t, rolledBack)
+	assert.Equal(t, StatusFailed, report.StepReports[0].Status)
+}
+
+func TestStep_WithConsistencyCheck_Panic(t *testing.T) {
+	ctx := context.Background()
+
+	rolledBack := false
+	createRecord := &Step{ID: "create_dns_record"}
+	createRecord.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		rolledBack = true
+		return false, nil
+	}).WithConsistencyCheck(func(ctx context.Context) (bool, error) {
+		panic("mock panic")
+	}, ConsistencyOptions{Timeout: time.Second})
+
+	workflow := NewWorkflow("workflow_1", WithSteps(createRecord))
+	defer workflow.End(ctx)
+	report, err := workflow.Start(ctx)
+	var panicErr *PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.True(t, rolledBack)
+	assert.Equal(t, StatusFailed, report.StepReports[0].Status)
+	assert.Equal(t, 1, len(report.Panics))
+	assert.Equal(t, "create_dns_record", report.Panics[0].StepID)
+	assert.Equal(t, RunAction, report.Panics[0].Action)
+}
+
+func TestStep_WithConsistencyCheck_Timeout(t *testing.T) {
+	ctx := context.Background()
+
+	hungRead := func(ctx context.Context) (bool, error) {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+
+	createRecord := &Step{ID: "create_dns_record"}
+	createRecord.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil).WithConsistencyCheck(hungRead, ConsistencyOptions{Timeout: time.Minute}).WithTimeout(10 * time.Millisecond)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(createRecord))
+	defer workflow.End(ctx)
+	report, err := workflow.Start(ctx)
+	assert.True(t, IsTimeout(err))
+	assert.Equal(t, StatusTimedOut, report.StepReports[0].Status)
+
+	// the timeout of the workflow applies as well
+	createRole := &Step{ID: "create_iam_role"}
+	createRole.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil).WithConsistencyCheck(hungRead, ConsistencyOptions{Timeout: time.Minute})
+
+	workflow = NewWorkflow("workflow_2", WithSteps(createRole), WithTimeout(10*time.Millisecond))
+	defer workflow.End(ctx)
+	report, err = workflow.Start(ctx)
+	assert.True(t, IsTimeout(err))
+	assert.Equal(t, StatusTimedOut, report.Status)
+}
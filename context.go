@@ -0,0 +1,159 @@
+package automa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/cockroachdb/errors"
+	"time"
+)
+
+// contextKey is the type of the keys of the values injected by automa in the context
+// It is unexported to avoid collisions with context keys defined in other packages
+type contextKey string
+
+const (
+	ctxKeyStepID     contextKey = "automa.step_id"
+	ctxKeyRunID      contextKey = "automa.run_id"
+	ctxKeyWorkflowID contextKey = "automa.workflow_id"
+
+	ctxKeyExecutionMode  contextKey = "automa.execution_mode"
+	ctxKeyRollbackMode   contextKey = "automa.rollback_mode"
+	ctxKeyQuarantine     contextKey = "automa.quarantine"
+	ctxKeyWarnings       contextKey = "automa.warnings"
+	ctxKeyPrefetcher     contextKey = "automa.prefetcher"
+	ctxKeyRunMemo        contextKey = "automa.run_memo"
+	ctxKeyRunTracker     contextKey = "automa.run_tracker"
+	ctxKeyResources      contextKey = "automa.resources"
+	ctxKeySinkErrors     contextKey = "automa.sink_errors"
+	ctxKeySeedState      contextKey = "automa.seed_state"
+	ctxKeyInputs         contextKey = "automa.inputs"
+	ctxKeyPauseSignal    contextKey = "automa.pause_signal"
+	ctxKeyStateRecorder  contextKey = "automa.state_recorder"
+	ctxKeyGoroutines     contextKey = "automa.goroutines"
+	ctxKeyDryRun         contextKey = "automa.dry_run"
+	ctxKeyCancelBehavior contextKey = "automa.cancel_behavior"
+
+	ctxKeyStateSizeLimits contextKey = "automa.state_size_limits"
+	ctxKeyGoroutineDump   contextKey = "automa.goroutine_dump"
+	ctxKeyEventEmitter    contextKey = "automa.event_emitter"
+	ctxKeyPanics          contextKey = "automa.panics"
+	ctxKeyPhase           contextKey = "automa.phase"
+	ctxKeyProgress        contextKey = "automa.progress"
+
+	ctxKeyGoroutineBudget contextKey = "automa.goroutine_budget"
+	ctxKeyFeatures        contextKey = "automa.features"
+	ctxKeyEngine          contextKey = "automa.engine"
+	ctxKeyStateMerge      contextKey = "automa.state_merge"
+	ctxKeyCostMeter       contextKey = "automa.cost_meter"
+	ctxKeyStepIO          contextKey = "automa.step_io"
+
+	ctxKeyNilReportPolicy contextKey = "automa.nil_report_policy"
+	ctxKeyRetryPolicy     contextKey = "automa.retry_policy"
+	ctxKeyStepExtras      contextKey = "automa.step_extras"
+	ctxKeyNilReportRetry  contextKey = "automa.nil_report_retry"
+	ctxKeyAsyncCallback   contextKey = "automa.async_callback"
+)
+
+// withStepID returns a copy of the context with the given step ID
+func withStepID(ctx context.Context, stepID string) context.Context {
+	return context.WithValue(ctx, ctxKeyStepID, stepID)
+}
+
+// withRun returns a copy of the context with the given workflow ID and run ID
+func withRun(ctx context.Context, workflowID string, runID string) context.Context {
+	ctx = context.WithValue(ctx, ctxKeyWorkflowID, workflowID)
+	return context.WithValue(ctx, ctxKeyRunID, runID)
+}
+
+// StepFromContext returns the ID of the step being executed
+// It is injected into every step context, i.e. the context passed to the Run and Rollback methods of every AtomicStep,
+// including custom implementations and ParallelGroup members, as well as the one of the saga functions of Step.
+func StepFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKeyStepID).(string)
+	return id, ok
+}
+
+// RunIdFromContext returns the ID of the current run of the workflow
+// It is injected by the Workflow in the context passed to every step.
+func RunIdFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKeyRunID).(string)
+	return id, ok
+}
+
+// WorkflowIdFromContext returns the ID of the workflow being executed
+// It is injected by the Workflow in the context passed to every step.
+func WorkflowIdFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKeyWorkflowID).(string)
+	return id, ok
+}
+
+// withCancelCause replaces an error caused by the cancellation of the context with the cause of the cancellation
+// The returned error matches the context error so that errors.Is(err, context.Canceled) remains true, while the cause
+// becomes the root error in the reports. It returns err as is if the context has no specific cause.
+func withCancelCause(ctx context.Context, err error) error {
+	ctxErr := ctx.Err()
+	if err == nil || ctxErr == nil || !errors.Is(err, ctxErr) {
+		return err
+	}
+
+	cause := contextCause(ctx)
+	if cause == nil || cause == ctxErr {
+		return err
+	}
+
+	return &cancelCauseError{cause: cause, ctxErr: ctxErr}
+}
+
+// cancelCauseError is the cause of the cancellation of a context that also matches the context error
+type cancelCauseError struct {
+	cause  error
+	ctxErr error
+}
+
+// Error implements error interface for cancelCauseError
+func (e *cancelCauseError) Error() string {
+	return e.cause.Error()
+}
+
+// Unwrap returns the cause
+func (e *cancelCauseError) Unwrap() error {
+	return e.cause
+}
+
+// Is returns true if the target is the context error
+func (e *cancelCauseError) Is(target error) bool {
+	return target == e.ctxErr
+}
+
+// detachedContext is a context that keeps the values of its parent but is never cancelled
+// It is used for the work that must be completed even if the context of the run is cancelled, e.g. cleanup.
+type detachedContext struct {
+	context.Context
+}
+
+// Deadline implements context.Context interface
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done implements context.Context interface
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err implements context.Context interface
+func (detachedContext) Err() error {
+	return nil
+}
+
+// newRunID returns a random ID for a workflow run
+// It falls back to a time based ID if random bytes cannot be generated
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,81 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestContextHelpers(t *testing.T) {
+	ctx := context.Background()
+
+	_, ok := StepFromContext(ctx)
+	assert.False(t, ok)
+	_, ok = RunIdFromContext(ctx)
+	assert.False(t, ok)
+	_, ok = WorkflowIdFromContext(ctx)
+	assert.False(t, ok)
+
+	var stepID, runID, workflowID string
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		stepID, _ = StepFromContext(ctx)
+		runID, _ = RunIdFromContext(ctx)
+		workflowID, _ = WorkflowIdFromContext(ctx)
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "step_1", stepID)
+	assert.Equal(t, "workflow_1", workflowID)
+	assert.NotEmpty(t, runID)
+	assert.Equal(t, report.RunID, runID)
+
+	report2, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.NotEqual(t, report.RunID, report2.RunID)
+}
+
+// mockContextStep is a custom AtomicStep recording the context values passed to its Run and Rollback methods
+type mockContextStep struct {
+	Step
+	stepIDs  []string
+	hasRunID bool
+}
+
+func (s *mockContextStep) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	stepID, _ := StepFromContext(ctx)
+	s.stepIDs = append(s.stepIDs, stepID)
+	_, s.hasRunID = RunIdFromContext(ctx)
+
+	return s.RunNext(ctx, prevSuccess, NewStepReport(s.GetID(), RunAction))
+}
+
+func (s *mockContextStep) Rollback(ctx context.Context, prevFailure *Failure) (WorkflowReport, error) {
+	stepID, _ := StepFromContext(ctx)
+	s.stepIDs = append(s.stepIDs, stepID)
+
+	return s.RollbackPrev(ctx, prevFailure, NewStepReport(s.GetID(), RollbackAction))
+}
+
+func TestStepFromContext_CustomStep(t *testing.T) {
+	ctx := context.Background()
+
+	// the step ID is injected in the context of the Run and Rollback methods of every step
+	s1 := &mockContextStep{Step: Step{ID: "step_1"}}
+	member := &mockContextStep{Step: Step{ID: "member_1"}}
+	s3 := &Step{ID: "step_3"}
+	s3.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, NewParallelGroup("group_1", member), s3))
+	_, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"step_1", "step_1"}, s1.stepIDs)
+	assert.Equal(t, []string{"member_1", "member_1"}, member.stepIDs)
+	assert.True(t, s1.hasRunID)
+}
@@ -0,0 +1,165 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// SagaCoordinator composes a set of independent AtomicWorkflow into a single logical transaction
+// Workflows are started sequentially. If a workflow fails, it rolls back its own steps and then the coordinator
+// reverses every previously completed workflow in reverse order using their Undo method.
+//
+// Note that the workflows must implement Undoer, be configured with an undo window (see WithUndoWindow) and complete
+// successfully in order to be compensated. The workflows that cannot be compensated are reported as violations.
+type SagaCoordinator struct {
+	id        string
+	mutex     sync.Mutex
+	workflows []AtomicWorkflow
+	logger    *zap.Logger
+}
+
+// CoordinatorReport combines the WorkflowReport of every workflow executed by the SagaCoordinator
+type CoordinatorReport struct {
+	CoordinatorID    string            `yaml:"coordinator_id" json:"coordinatorID"`
+	StartTime        time.Time         `yaml:"start_time" json:"startTime"`
+	EndTime          time.Time         `yaml:"end_time" json:"endTime"`
+	Status           Status            `yaml:"status" json:"status"`
+	WorkflowSequence []string          `yaml:"workflow_sequence" json:"workflowSequence"`
+	WorkflowReports  []*WorkflowReport `yaml:"workflow_reports" json:"workflowReports"`
+
+	// Violations contains the completed workflows that could not be compensated after the failure of a workflow
+	Violations []CoordinatorViolation `yaml:"violations,omitempty" json:"violations,omitempty"`
+}
+
+// CoordinatorViolation defines a completed workflow that the SagaCoordinator could not compensate
+type CoordinatorViolation struct {
+	WorkflowID string `yaml:"workflow_id" json:"workflowID"`
+	Reason     string `yaml:"reason" json:"reason"`
+}
+
+// String returns a human-readable description of the CoordinatorViolation
+func (v CoordinatorViolation) String() string {
+	return fmt.Sprintf("workflow %q: %s", v.WorkflowID, v.Reason)
+}
+
+// CoordinatorOption exposes "constructor with option" pattern for SagaCoordinator
+type CoordinatorOption func(sc *SagaCoordinator)
+
+// WithWorkflows allows SagaCoordinator to be initialized with the list of ordered workflows
+func WithWorkflows(workflows ...AtomicWorkflow) CoordinatorOption {
+	return func(sc *SagaCoordinator) {
+		for _, wf := range workflows {
+			if wf != nil {
+				sc.workflows = append(sc.workflows, wf)
+			}
+		}
+	}
+}
+
+// WithCoordinatorLogger allows SagaCoordinator to be initialized with a logger
+// By default a SagaCoordinator is initialized with a NoOp logger
+func WithCoordinatorLogger(logger *zap.Logger) CoordinatorOption {
+	return func(sc *SagaCoordinator) {
+		if logger != nil {
+			sc.logger = logger
+		}
+	}
+}
+
+// NewSagaCoordinator returns an instance of SagaCoordinator
+func NewSagaCoordinator(id string, opts ...CoordinatorOption) *SagaCoordinator {
+	sc := &SagaCoordinator{
+		id:     id,
+		logger: zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(sc)
+	}
+
+	return sc
+}
+
+// GetID returns the id of the SagaCoordinator
+func (sc *SagaCoordinator) GetID() string {
+	return sc.id
+}
+
+// Start starts the workflows in order and returns the combined CoordinatorReport
+// If any workflow fails, previously completed workflows are undone in reverse order and the returned error contains
+// the failure of the workflow as well as any failure encountered while undoing the completed workflows.
+func (sc *SagaCoordinator) Start(ctx context.Context) (CoordinatorReport, error) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	report := CoordinatorReport{
+		CoordinatorID:   sc.id,
+		StartTime:       time.Now(),
+		Status:          StatusUndefined,
+		WorkflowReports: []*WorkflowReport{},
+	}
+
+	for _, wf := range sc.workflows {
+		report.WorkflowSequence = append(report.WorkflowSequence, wf.GetID())
+	}
+
+	for i, wf := range sc.workflows {
+		wfReport, err := wf.Start(ctx)
+		report.WorkflowReports = append(report.WorkflowReports, &wfReport)
+		if err != nil {
+			err = errors.Wrapf(err, "workflow %q failed", wf.GetID())
+			err = errors.CombineErrors(err, sc.undo(ctx, &report, i))
+			report.Status = StatusFailed
+			report.EndTime = time.Now()
+			return report, err
+		}
+	}
+
+	report.Status = StatusSuccess
+	report.EndTime = time.Now()
+
+	return report, nil
+}
+
+// undo reverses the workflows completed before the failed workflow at index failedIndex in reverse order
+// It replaces the report of every undone workflow in the CoordinatorReport, records the workflows that cannot be undone
+// as violations and returns any error encountered. The workflows are undone using a context that keeps the values of
+// ctx but is not cancelled, so that they are compensated even if the failure was caused by the cancellation of ctx.
+func (sc *SagaCoordinator) undo(ctx context.Context, report *CoordinatorReport, failedIndex int) error {
+	var undoErr error
+	for i := failedIndex - 1; i >= 0; i-- {
+		wf := sc.workflows[i]
+		undoer, ok := wf.(Undoer)
+		if !ok {
+			undoErr = errors.CombineErrors(undoErr, sc.violation(report, wf.GetID(), "cannot be undone"))
+			continue
+		}
+
+		if status := report.WorkflowReports[i].Status; status != StatusSuccess {
+			reason := fmt.Sprintf("cannot be undone since its status is %s", status)
+			undoErr = errors.CombineErrors(undoErr, sc.violation(report, wf.GetID(), reason))
+			continue
+		}
+
+		wfReport, err := undoer.Undo(detachedContext{ctx})
+		report.WorkflowReports[i] = &wfReport
+		if err != nil {
+			sc.logger.Error("failed to undo workflow", zap.String("workflow_id", wf.GetID()), zap.Error(err))
+			undoErr = errors.CombineErrors(undoErr, errors.Wrapf(err, "failed to undo workflow %q", wf.GetID()))
+		}
+	}
+
+	return undoErr
+}
+
+// violation records the workflow that cannot be compensated in the CoordinatorReport and returns it as an error
+func (sc *SagaCoordinator) violation(report *CoordinatorReport, workflowID string, reason string) error {
+	sc.logger.Error("workflow cannot be compensated", zap.String("workflow_id", workflowID), zap.String("reason", reason))
+	report.Violations = append(report.Violations, CoordinatorViolation{WorkflowID: workflowID, Reason: reason})
+
+	return errors.Newf("workflow %q %s", workflowID, reason)
+}
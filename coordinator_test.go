@@ -0,0 +1,147 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSagaCoordinator_Start(t *testing.T) {
+	ctx := context.Background()
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	notify := &mockNotifyStep{
+		Step:  Step{ID: "notify_on_slack"},
+		cache: map[string][]byte{},
+	}
+	notify.RegisterSaga(notify.run, notify.rollback)
+
+	restart := &mockRestartContainersStep{
+		Step:  Step{ID: "restart_containers"},
+		cache: map[string][]byte{},
+	}
+	restart.RegisterSaga(restart.run, restart.rollback)
+
+	workflow1 := NewWorkflow("workflow_1", WithSteps(fetch), WithUndoWindow(time.Minute))
+	workflow2 := NewWorkflow("workflow_2", WithSteps(notify), WithUndoWindow(time.Minute))
+	workflow3 := NewWorkflow("workflow_3", WithSteps(restart), WithUndoWindow(time.Minute))
+
+	coordinator := NewSagaCoordinator("saga_1", WithWorkflows(workflow1, workflow2))
+	assert.Equal(t, "saga_1", coordinator.GetID())
+	report, err := coordinator.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.Equal(t, []string{"workflow_1", "workflow_2"}, report.WorkflowSequence)
+	assert.Equal(t, 2, len(report.WorkflowReports))
+
+	coordinator = NewSagaCoordinator("saga_2", WithWorkflows(workflow1, workflow2, workflow3))
+	report, err = coordinator.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, 3, len(report.WorkflowReports))
+	assert.Equal(t, StatusUndone, report.WorkflowReports[0].Status)
+	assert.Equal(t, StatusUndone, report.WorkflowReports[1].Status)
+	assert.Equal(t, StatusFailed, report.WorkflowReports[2].Status)
+
+	// completed workflows without an undo window cannot be compensated
+	workflow4 := NewWorkflow("workflow_4", WithSteps(fetch))
+	coordinator = NewSagaCoordinator("saga_3", WithWorkflows(workflow4, workflow3))
+	report, err = coordinator.Start(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "workflow \"workflow_3\" failed")
+	assert.Equal(t, StatusSuccess, report.WorkflowReports[0].Status)
+}
+
+// mockLegacyWorkflow is an example of an AtomicWorkflow implementation that cannot be undone
+type mockLegacyWorkflow struct {
+	id string
+}
+
+func (wf *mockLegacyWorkflow) GetID() string {
+	return wf.id
+}
+
+func (wf *mockLegacyWorkflow) Start(ctx context.Context) (WorkflowReport, error) {
+	return WorkflowReport{WorkflowID: wf.id, Status: StatusSuccess}, nil
+}
+
+func (wf *mockLegacyWorkflow) End(ctx context.Context) {
+}
+
+func TestSagaCoordinator_NotUndoer(t *testing.T) {
+	failing := &Step{ID: "fail"}
+	failing.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil)
+
+	coordinator := NewSagaCoordinator("saga_1",
+		WithWorkflows(&mockLegacyWorkflow{id: "legacy_workflow"}, NewWorkflow("workflow_2", WithSteps(failing))))
+	report, err := coordinator.Start(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, fmt.Sprintf("%+v", err), `workflow "legacy_workflow" cannot be undone`)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, StatusSuccess, report.WorkflowReports[0].Status)
+}
+
+func TestSagaCoordinator_UndoAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	created := &Step{ID: "create_bucket"}
+	created.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return false, ctx.Err()
+	})
+
+	cancelling := &Step{ID: "upload_objects"}
+	cancelling.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		cancel()
+		return false, ctx.Err()
+	}, nil)
+
+	// the completed workflow is undone even though the context of the coordinator is cancelled
+	workflow1 := NewWorkflow("workflow_1", WithSteps(created), WithUndoWindow(time.Minute))
+	workflow2 := NewWorkflow("workflow_2", WithSteps(cancelling))
+	coordinator := NewSagaCoordinator("saga_1", WithWorkflows(workflow1, workflow2))
+	report, err := coordinator.Start(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, StatusUndone, report.WorkflowReports[0].Status)
+	assert.Empty(t, report.Violations)
+}
+
+func TestSagaCoordinator_Violations(t *testing.T) {
+	ctx := context.Background()
+
+	tolerated := &Step{ID: "warm_cache"}
+	tolerated.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil).WithSeverity(SeverityWarning)
+
+	failing := &Step{ID: "fail"}
+	failing.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil)
+
+	workflow1 := NewWorkflow("workflow_1", WithSteps(tolerated), WithUndoWindow(time.Minute))
+	coordinator := NewSagaCoordinator("saga_1", WithWorkflows(
+		&mockLegacyWorkflow{id: "legacy_workflow"}, workflow1, NewWorkflow("workflow_2", WithSteps(failing))))
+	report, err := coordinator.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, StatusPartial, report.WorkflowReports[1].Status)
+	assert.Equal(t, []CoordinatorViolation{
+		{WorkflowID: "workflow_1", Reason: "cannot be undone since its status is PARTIAL"},
+		{WorkflowID: "legacy_workflow", Reason: "cannot be undone"},
+	}, report.Violations)
+	assert.Equal(t, `workflow "workflow_1": cannot be undone since its status is PARTIAL`, report.Violations[0].String())
+}
@@ -0,0 +1,105 @@
+package automa
+
+import (
+	"context"
+	"sync"
+)
+
+// Costs defines the amount of every cost metric incurred by a step, e.g. number of API calls, estimated cloud spend in
+// USD or billed seconds
+// Metrics are free-form names, amounts of the same metric are summed up in the totals of groups, phases and runs.
+type Costs map[string]float64
+
+// Add adds the amounts of the other costs
+func (c Costs) Add(other Costs) {
+	for metric, amount := range other {
+		c[metric] += amount
+	}
+}
+
+// Clone returns a copy of the costs
+func (c Costs) Clone() Costs {
+	if c == nil {
+		return nil
+	}
+
+	clone := make(Costs, len(c))
+	clone.Add(c)
+
+	return clone
+}
+
+// AddCost adds the amount to the metric of the costs of the step
+func (sr *StepReport) AddCost(metric string, amount float64) {
+	if sr.Costs == nil {
+		sr.Costs = Costs{}
+	}
+
+	sr.Costs[metric] += amount
+}
+
+// AddCost adds the amount to the metric of the costs of the step being executed
+// It is meant to be called from SagaRun and SagaUndo, it is a NOOP if the context doesn't belong to a workflow step.
+// The costs of a nested workflow started from SagaRun or SagaUndo are also added to the costs of the step.
+func AddCost(ctx context.Context, metric string, amount float64) {
+	if m := costMeterFromContext(ctx); m != nil {
+		m.add(Costs{metric: amount})
+	}
+}
+
+// costMeter collects the costs of an action of a step that may be added concurrently
+type costMeter struct {
+	mutex sync.Mutex
+	costs Costs
+}
+
+// add adds the costs to the meter
+func (m *costMeter) add(costs Costs) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.costs == nil {
+		m.costs = Costs{}
+	}
+
+	m.costs.Add(costs)
+}
+
+// addTo adds the collected costs to the report of the step
+func (m *costMeter) addTo(report *StepReport) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for metric, amount := range m.costs {
+		report.AddCost(metric, amount)
+	}
+}
+
+// withCostMeter returns a copy of the context with the given costMeter, nil if costs are not collected
+func withCostMeter(ctx context.Context, m *costMeter) context.Context {
+	return context.WithValue(ctx, ctxKeyCostMeter, m)
+}
+
+// costMeterFromContext returns the costMeter of the step being executed, if any
+func costMeterFromContext(ctx context.Context) *costMeter {
+	m, _ := ctx.Value(ctxKeyCostMeter).(*costMeter)
+	return m
+}
+
+// totalCosts returns the sum of the costs of the step reports, nil if no step reported any cost
+func totalCosts(stepReports []*StepReport) Costs {
+	var total Costs
+	for _, stepReport := range stepReports {
+		if len(stepReport.Costs) == 0 {
+			continue
+		}
+
+		if total == nil {
+			total = Costs{}
+		}
+
+		total.Add(stepReport.Costs)
+	}
+
+	return total
+}
@@ -0,0 +1,78 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAddCost(t *testing.T) {
+	ctx := context.Background()
+
+	// costs reported from a nested workflow are added to the step running it
+	nestedStep := &Step{ID: "create_vm"}
+	nestedStep.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		AddCost(ctx, "usd", 1.5)
+		return false, nil
+	}, nil)
+	nested := NewWorkflow("nested", WithSteps(nestedStep))
+	defer nested.End(ctx)
+
+	provision := &Step{ID: "provision"}
+	provision.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		AddCost(ctx, "api_calls", 2)
+		_, err = nested.Start(ctx)
+		return false, err
+	}, func(ctx context.Context) (skipped bool, err error) {
+		AddCost(ctx, "api_calls", 1)
+		return false, nil
+	}).WithGroup("infra")
+
+	configure := &Step{ID: "configure"}
+	configure.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		AddCost(ctx, "api_calls", 3)
+		return false, nil
+	}, nil).WithGroup("infra")
+
+	failing := &Step{ID: "deploy"}
+	failing.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		AddCost(ctx, "api_calls", 1)
+		return false, errors.New("deploy failed")
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(provision, configure))
+	defer workflow.End(ctx)
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Costs{"api_calls": 2, "usd": 1.5}, report.StepReports[0].Costs)
+	assert.Equal(t, Costs{"api_calls": 3}, report.StepReports[1].Costs)
+	assert.Equal(t, Costs{"api_calls": 5, "usd": 1.5}, report.Groups[0].Costs)
+	assert.Equal(t, Costs{"api_calls": 5, "usd": 1.5}, report.Costs)
+	assert.Equal(t, Costs{"usd": 1.5}, nested.report.Costs)
+
+	// costs of the rollback are accounted separately
+	workflow = NewWorkflow("workflow_2", WithSteps(provision, failing))
+	defer workflow.End(ctx)
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, Costs{"api_calls": 1}, report.StepReports[1].Costs)
+	assert.Equal(t, RollbackAction, report.StepReports[3].Action)
+	assert.Equal(t, Costs{"api_calls": 1}, report.StepReports[3].Costs)
+	assert.Equal(t, Costs{"api_calls": 4, "usd": 1.5}, report.Costs)
+	assert.Equal(t, Costs{"api_calls": 8, "usd": 3}, AggregateReports(&report, &report).Costs)
+
+	// AddCost is a NOOP outside of a step
+	AddCost(ctx, "api_calls", 1)
+}
+
+func TestStepReport_AddCost(t *testing.T) {
+	report := NewStepReport("step_1", RunAction)
+	report.AddCost("usd", 0.25)
+	report.AddCost("usd", 0.5)
+	assert.Equal(t, Costs{"usd": 0.75}, report.Costs)
+
+	clone := report.Clone()
+	clone.AddCost("usd", 1)
+	assert.Equal(t, Costs{"usd": 0.75}, report.Costs)
+}
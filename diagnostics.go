@@ -0,0 +1,67 @@
+package automa
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Diagnostics defines the diagnostics of a workflow run that did not affect its outcome
+type Diagnostics struct {
+	// SinkErrors contains the failures of the observability sinks during the run, e.g. heartbeat store or metrics push
+	SinkErrors []*SinkError `yaml:"sink_errors" json:"sinkErrors"`
+
+	// LeakedGoroutines contains the goroutines started by the steps using Go that were still running at the end
+	LeakedGoroutines []*GoroutineLeak `yaml:"leaked_goroutines,omitempty" json:"leakedGoroutines,omitempty"`
+}
+
+// SinkError defines the failure of an observability sink
+// Sink failures never fail the workflow, they are only reported in WorkflowReport.Diagnostics.
+type SinkError struct {
+	Sink    string    `yaml:"sink" json:"sink"`
+	Time    time.Time `yaml:"time" json:"time"`
+	Message string    `yaml:"message" json:"message"`
+}
+
+// sinkErrors collects the failures of the observability sinks during a workflow run
+type sinkErrors struct {
+	mutex  sync.Mutex
+	errors []*SinkError
+}
+
+// add records the failure of the sink
+func (s *sinkErrors) add(sink string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.errors = append(s.errors, &SinkError{Sink: sink, Time: time.Now(), Message: err.Error()})
+}
+
+// list returns the recorded failures in the order they occurred
+func (s *sinkErrors) list() []*SinkError {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	errs := make([]*SinkError, len(s.errors))
+	copy(errs, s.errors)
+
+	return errs
+}
+
+// withSinkErrors returns a copy of the context with the given sink errors collector
+func withSinkErrors(ctx context.Context, s *sinkErrors) context.Context {
+	return context.WithValue(ctx, ctxKeySinkErrors, s)
+}
+
+// ReportSinkError records the failure of an observability sink in the diagnostics of the current workflow run
+// Steps and callbacks pushing metrics or notifications should use it instead of failing the workflow.
+// It is a NOOP if the context doesn't belong to a workflow run or err is nil.
+func ReportSinkError(ctx context.Context, sink string, err error) {
+	if err == nil {
+		return
+	}
+
+	if s, ok := ctx.Value(ctxKeySinkErrors).(*sinkErrors); ok {
+		s.add(sink, err)
+	}
+}
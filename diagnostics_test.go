@@ -0,0 +1,49 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockFailingHeartbeatStore struct {
+	InMemHeartbeatStore
+}
+
+func (s *mockFailingHeartbeatStore) SaveHeartbeat(ctx context.Context, hb Heartbeat) error {
+	return errors.New("store is down")
+}
+
+func TestWorkflow_Diagnostics(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "push_metrics"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		ReportSinkError(ctx, "metrics", errors.New("push gateway unavailable"))
+		ReportSinkError(ctx, "metrics", nil)
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(s1),
+		WithHeartbeat(&mockFailingHeartbeatStore{}, time.Hour),
+		WithOnCompletion(func(ctx context.Context, report WorkflowReport) {
+			ReportSinkError(ctx, "webhook", errors.New("webhook is down"))
+		}))
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+
+	var sinks []string
+	for _, sinkErr := range report.Diagnostics.SinkErrors {
+		sinks = append(sinks, sinkErr.Sink)
+	}
+	assert.Equal(t, []string{"heartbeat", "metrics", "heartbeat", "webhook"}, sinks)
+	assert.Equal(t, "push gateway unavailable", report.Diagnostics.SinkErrors[1].Message)
+
+	// NOOP outside of a workflow run
+	ReportSinkError(ctx, "metrics", errors.New("mock error"))
+}
@@ -0,0 +1,112 @@
+package automa
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// GoroutineDumpMetadataKey is the key of the StepReport.Metadata containing the goroutine dump of a step
+const GoroutineDumpMetadataKey = "goroutine_dump"
+
+// defaultGoroutineDumpMaxBytes is the size limit of a goroutine dump if GoroutineDumpOptions.MaxBytes is not set
+const defaultGoroutineDumpMaxBytes = 64 * 1024
+
+// GoroutineDumpOptions defines when the stacks of all goroutines are captured in the report of a step
+// A dump is captured when the run action of a step fails, or when it is still running after SlowStep if set. The
+// latter helps to diagnose a step that hangs since the dump is taken while it is blocked.
+type GoroutineDumpOptions struct {
+	// SlowStep is the duration of the run action after which a dump is captured, 0 disables it
+	SlowStep time.Duration
+
+	// MaxBytes is the size limit of a dump, it is truncated beyond it
+	MaxBytes int
+}
+
+// WithGoroutineDump allows goroutine dumps to be attached to the reports of failing or slow steps
+// The dump is set in StepReport.Metadata with GoroutineDumpMetadataKey.
+func WithGoroutineDump(opts GoroutineDumpOptions) WorkflowOption {
+	return func(wf *Workflow) {
+		if opts.MaxBytes < 1 {
+			opts.MaxBytes = defaultGoroutineDumpMaxBytes
+		}
+
+		wf.goroutineDump = &opts
+	}
+}
+
+// withGoroutineDump returns a copy of the context with the given GoroutineDumpOptions
+func withGoroutineDump(ctx context.Context, opts *GoroutineDumpOptions) context.Context {
+	return context.WithValue(ctx, ctxKeyGoroutineDump, opts)
+}
+
+// captureGoroutines returns the stacks of all goroutines truncated to maxBytes
+func captureGoroutines(maxBytes int) []byte {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		return []byte(err.Error())
+	}
+
+	dump := buf.Bytes()
+	if len(dump) > maxBytes {
+		dump = dump[:maxBytes]
+	}
+
+	return dump
+}
+
+// dumpWatch captures a goroutine dump of a step run as per the GoroutineDumpOptions
+type dumpWatch struct {
+	opts  *GoroutineDumpOptions
+	timer *time.Timer
+
+	mutex sync.Mutex
+	dump  []byte
+}
+
+// startDumpWatch starts watching the run of a step
+// It returns nil if goroutine dumps are not enabled for the workflow run.
+func startDumpWatch(ctx context.Context) *dumpWatch {
+	opts, ok := ctx.Value(ctxKeyGoroutineDump).(*GoroutineDumpOptions)
+	if !ok || opts == nil {
+		return nil
+	}
+
+	w := &dumpWatch{opts: opts}
+	if opts.SlowStep > 0 {
+		w.timer = time.AfterFunc(opts.SlowStep, func() {
+			dump := captureGoroutines(opts.MaxBytes)
+
+			w.mutex.Lock()
+			defer w.mutex.Unlock()
+			w.dump = dump
+		})
+	}
+
+	return w
+}
+
+// stop stops watching the run of the step and attaches the dump to its report, if any
+// A dump is captured if the run failed and the step was not slow.
+func (w *dumpWatch) stop(report *StepReport, err error) {
+	if w == nil {
+		return
+	}
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.dump == nil && err != nil {
+		w.dump = captureGoroutines(w.opts.MaxBytes)
+	}
+
+	if w.dump != nil {
+		report.Metadata[GoroutineDumpMetadataKey] = w.dump
+	}
+}
@@ -0,0 +1,48 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkflow_GoroutineDump(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "slow"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		time.Sleep(200 * time.Millisecond)
+		return false, nil
+	}, nil)
+
+	s2 := &Step{ID: "fast"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	s3 := &Step{ID: "failing"}
+	s3.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(s1, s2, s3),
+		WithGoroutineDump(GoroutineDumpOptions{SlowStep: 50 * time.Millisecond, MaxBytes: 512}))
+
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+
+	slow := report.StepReports[0].Metadata[GoroutineDumpMetadataKey]
+	assert.NotEmpty(t, slow)
+	assert.LessOrEqual(t, len(slow), 512)
+	assert.True(t, strings.HasPrefix(string(slow), "goroutine "))
+	assert.Nil(t, report.StepReports[1].Metadata[GoroutineDumpMetadataKey])
+	assert.NotEmpty(t, report.StepReports[2].Metadata[GoroutineDumpMetadataKey])
+
+	// disabled by default
+	report, _ = NewWorkflow("workflow_1", WithSteps(s3)).Start(ctx)
+	assert.Nil(t, report.StepReports[0].Metadata[GoroutineDumpMetadataKey])
+}
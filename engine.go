@@ -0,0 +1,189 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+	"sync"
+)
+
+// ErrEngineShutdown is returned by Engine when a run is requested after Shutdown was called
+var ErrEngineShutdown = errors.New("engine is shut down")
+
+// Engine owns the runs of the workflows embedded in a long-lived service, e.g. an API server
+// Runs started using the Engine are tracked so that Shutdown can stop them gracefully.
+type Engine struct {
+	mutex     sync.Mutex
+	closed    bool
+	inFlight  sync.WaitGroup
+	workflows map[*Workflow]int  // number of in-flight runs of the workflows run by the engine
+	paused    map[*Workflow]bool // workflows requested to pause by Shutdown
+	store     StateStore
+	logger    *zap.Logger
+}
+
+// EngineOption exposes "constructor with option" pattern for Engine
+type EngineOption func(e *Engine)
+
+// WithEngineStateStore allows the Engine to save the checkpoints of the runs paused by Shutdown in the store
+// The runs can then be resumed by another instance of the service using Workflow.Resume.
+func WithEngineStateStore(store StateStore) EngineOption {
+	return func(e *Engine) {
+		e.store = store
+	}
+}
+
+// WithEngineLogger allows Engine to be initialized with a logger
+// By default an Engine is initialized with a NoOp logger
+func WithEngineLogger(logger *zap.Logger) EngineOption {
+	return func(e *Engine) {
+		if logger != nil {
+			e.logger = logger
+		}
+	}
+}
+
+// NewEngine returns an instance of Engine
+func NewEngine(opts ...EngineOption) *Engine {
+	e := &Engine{
+		workflows: map[*Workflow]int{},
+		paused:    map[*Workflow]bool{},
+		logger:    zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Start starts a run of the workflow, see Workflow.Start
+// It returns ErrEngineShutdown if Shutdown was called.
+func (e *Engine) Start(ctx context.Context, wf *Workflow) (WorkflowReport, error) {
+	return e.run(ctx, wf, wf.Start)
+}
+
+// Resume resumes a paused run of the workflow, see Workflow.Resume
+// It returns ErrEngineShutdown if Shutdown was called.
+func (e *Engine) Resume(ctx context.Context, wf *Workflow, cp *Checkpoint) (WorkflowReport, error) {
+	return e.run(ctx, wf, func(ctx context.Context) (WorkflowReport, error) {
+		return wf.Resume(ctx, cp)
+	})
+}
+
+// Undo reverses the last successful run of the workflow, see Workflow.Undo
+// It returns ErrEngineShutdown if Shutdown was called.
+func (e *Engine) Undo(ctx context.Context, wf *Workflow) (WorkflowReport, error) {
+	return e.run(ctx, wf, wf.Undo)
+}
+
+// run tracks the execution of the given action of the workflow
+func (e *Engine) run(ctx context.Context, wf *Workflow, action func(ctx context.Context) (WorkflowReport, error)) (WorkflowReport, error) {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return WorkflowReport{}, errors.Wrapf(ErrEngineShutdown, "workflow %q cannot be run", wf.GetID())
+	}
+
+	e.workflows[wf]++
+	e.inFlight.Add(1)
+	e.mutex.Unlock()
+
+	defer func() {
+		e.mutex.Lock()
+		e.workflows[wf]--
+		if e.workflows[wf] == 0 {
+			delete(e.workflows, wf)
+		}
+		e.mutex.Unlock()
+		e.inFlight.Done()
+	}()
+
+	return action(withEngine(ctx, e))
+}
+
+// shuttingDown returns true if Shutdown was called
+func (e *Engine) shuttingDown() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.closed
+}
+
+// Shutdown stops the engine gracefully
+// It stops accepting new runs, requests the in-flight runs to pause once their current step completes and waits for
+// them. The checkpoints of the runs paused by Shutdown are saved in the StateStore of the engine, if any. Finally, the
+// workflows that were in flight are ended, see Workflow.End, which waits for their pending async callbacks and the
+// background retries of their quarantined steps, and delivers their pending reports to the ReportSink. Rollbacks are
+// not affected, i.e. the paused runs are not rolled back. Workflows whose runs already completed are left to their
+// owner.
+//
+// If the context is done before the in-flight runs are paused, it returns the error of the context without ending
+// the workflows. Shutdown can be called again, e.g. with a longer deadline.
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.mutex.Lock()
+	e.closed = true
+	for wf := range e.workflows {
+		if !e.paused[wf] {
+			e.paused[wf] = true
+			wf.Pause()
+		}
+	}
+
+	var workflows []*Workflow
+	for wf := range e.paused {
+		workflows = append(workflows, wf)
+	}
+	e.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "failed to wait for the in-flight runs")
+	}
+
+	var err error
+	for _, wf := range workflows {
+		cp, cpErr := wf.Checkpoint()
+		if cpErr != nil {
+			// the run completed before reaching the pause
+			continue
+		}
+
+		e.logger.Info("workflow run paused on shutdown",
+			zap.String("workflow_id", wf.GetID()), zap.String("run_id", cp.RunID), zap.String("next_step", cp.NextStep))
+
+		if e.store != nil {
+			if saveErr := e.store.Save(ctx, cp); saveErr != nil {
+				err = errors.CombineErrors(err, errors.Wrapf(saveErr, "failed to save checkpoint of run %q", cp.RunID))
+			}
+		}
+	}
+
+	for _, wf := range workflows {
+		wf.End(ctx)
+	}
+
+	return err
+}
+
+// withEngine returns a copy of the context with the given Engine
+func withEngine(ctx context.Context, e *Engine) context.Context {
+	return context.WithValue(ctx, ctxKeyEngine, e)
+}
+
+// engineFromContext returns the Engine running the workflow, if any
+func engineFromContext(ctx context.Context) *Engine {
+	if e, ok := ctx.Value(ctxKeyEngine).(*Engine); ok {
+		return e
+	}
+
+	return nil
+}
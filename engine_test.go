@@ -0,0 +1,154 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEngine_Shutdown(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStateStore(t.TempDir())
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (bool, error) {
+		close(started)
+		<-release
+		return false, nil
+	}, nil)
+	var s2Runs int32
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (bool, error) {
+		atomic.AddInt32(&s2Runs, 1)
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+
+	engine := NewEngine(WithEngineStateStore(store))
+
+	type result struct {
+		report WorkflowReport
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		report, err := engine.Start(ctx, workflow)
+		results <- result{report: report, err: err}
+	}()
+
+	<-started
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- engine.Shutdown(ctx)
+	}()
+
+	// new runs are rejected while the in-flight run completes its current step
+	assert.Eventually(t, engine.shuttingDown, time.Second, time.Millisecond)
+	_, err = engine.Start(ctx, NewWorkflow("workflow_2"))
+	assert.ErrorIs(t, err, ErrEngineShutdown)
+
+	close(release)
+	r := <-results
+	assert.ErrorIs(t, r.err, ErrWorkflowPaused)
+	assert.Equal(t, StatusPaused, r.report.Status)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&s2Runs))
+	assert.NoError(t, <-shutdown)
+
+	cp, err := store.Load(ctx, r.report.RunID)
+	assert.NoError(t, err)
+	assert.Equal(t, "step_2", cp.NextStep)
+
+	// the checkpoint can be resumed by another engine
+	report, err := NewEngine().Resume(ctx, workflow, cp)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&s2Runs))
+}
+
+func TestEngine_ShutdownTimeout(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (bool, error) {
+		close(started)
+		<-release
+		return false, nil
+	}, nil)
+
+	engine := NewEngine()
+	workflow := NewWorkflow("workflow_1", WithSteps(s1))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = engine.Start(ctx, workflow)
+	}()
+	<-started
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, engine.Shutdown(shutdownCtx), context.DeadlineExceeded)
+
+	close(release)
+	<-done
+	assert.NoError(t, engine.Shutdown(ctx))
+}
+
+func TestEngine_Undo(t *testing.T) {
+	ctx := context.Background()
+	engine := NewEngine()
+	var completions int32
+	workflow := NewWorkflow("workflow_1", WithSteps(&Step{ID: "step_1"}), WithUndoWindow(time.Hour),
+		WithAsyncCallbacks(1, 10, BlockOnFull),
+		WithOnCompletion(func(ctx context.Context, report WorkflowReport) {
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&completions, 1)
+		}))
+
+	report, err := engine.Start(ctx, workflow)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+
+	report, err = engine.Undo(ctx, workflow)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusUndone, report.Status)
+
+	// workflows whose runs completed are no longer tracked and are ended by their owner
+	engine.mutex.Lock()
+	assert.Empty(t, engine.workflows)
+	engine.mutex.Unlock()
+	assert.NoError(t, engine.Shutdown(ctx))
+	workflow.End(ctx)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&completions))
+
+	_, err = engine.Undo(ctx, workflow)
+	assert.ErrorIs(t, err, ErrEngineShutdown)
+}
+
+func TestEngine_ShutdownUserPausedRun(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStateStore(t.TempDir())
+	assert.NoError(t, err)
+
+	engine := NewEngine(WithEngineStateStore(store))
+	s1 := &Step{ID: "step_1"}
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, &Step{ID: "step_2"}))
+	s1.RegisterSaga(func(ctx context.Context) (bool, error) {
+		workflow.Pause()
+		return false, nil
+	}, nil)
+
+	report, err := engine.Start(ctx, workflow)
+	assert.ErrorIs(t, err, ErrWorkflowPaused)
+
+	// a run paused by the user is not checkpointed by Shutdown
+	assert.NoError(t, engine.Shutdown(ctx))
+	_, err = store.Load(ctx, report.RunID)
+	assert.Error(t, err)
+}
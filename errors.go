@@ -0,0 +1,163 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"strings"
+)
+
+// StepError wraps the error returned by the run or rollback action of a step
+type StepError struct {
+	StepID string
+	Action StepActionType
+	Err    error
+}
+
+// Error implements error interface for StepError
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %q %s failed: %v", e.StepID, e.Action, e.Err)
+}
+
+// Unwrap returns the wrapped error so that errors.Is and errors.As can inspect the cause
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// WorkflowError aggregates the errors of all failed step actions of a workflow run
+// The first error is the one that triggered the failure of the workflow, followed by any rollback failures.
+// errors.Is and errors.As match any of the aggregated errors.
+type WorkflowError struct {
+	WorkflowID string
+	Errors     []error
+}
+
+// Error implements error interface for WorkflowError
+func (e *WorkflowError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("workflow %q failed: %s", e.WorkflowID, strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the aggregated errors
+func (e *WorkflowError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is returns true if any of the aggregated errors matches the target
+func (e *WorkflowError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first aggregated error that matches the target
+func (e *WorkflowError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// joinErrors aggregates the errors into a WorkflowError ignoring nil errors
+// Errors that are already WorkflowError are flattened.
+func joinErrors(workflowID string, errs ...error) error {
+	joined := &WorkflowError{WorkflowID: workflowID}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		var wfErr *WorkflowError
+		if e, ok := err.(*WorkflowError); ok {
+			wfErr = e
+		}
+
+		if wfErr != nil {
+			joined.Errors = append(joined.Errors, wfErr.Errors...)
+		} else {
+			joined.Errors = append(joined.Errors, err)
+		}
+	}
+
+	if len(joined.Errors) == 0 {
+		return nil
+	}
+
+	return joined
+}
+
+// AsStepError returns the first StepError found in the error chain
+// It works for errors returned by automa as well as errors wrapped using cockroachdb/errors or fmt.Errorf.
+func AsStepError(err error) (*StepError, bool) {
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		return stepErr, true
+	}
+
+	return nil, false
+}
+
+// IsTimeout returns true if the error chain contains context.DeadlineExceeded or an error with a Timeout method that
+// returns true, e.g. net.Error
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var timeoutErr interface{ Timeout() bool }
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.Timeout()
+	}
+
+	return false
+}
+
+// IsRollbackFailure returns true if the error contains a StepError for a failed RollbackAction
+func IsRollbackFailure(err error) bool {
+	for _, stepErr := range stepErrors(err) {
+		if stepErr.Action == RollbackAction {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stepErrors returns all the StepError found in the error including the ones aggregated in a WorkflowError
+// A StepError is returned before the ones found in its cause, e.g. the WorkflowError of a nested workflow.
+func stepErrors(err error) []*StepError {
+	if err == nil {
+		return nil
+	}
+
+	if stepErr, ok := err.(*StepError); ok {
+		return append([]*StepError{stepErr}, stepErrors(stepErr.Err)...)
+	}
+
+	// WorkflowError as well as errors joined using errors.Join or fmt.Errorf with several %w
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var result []*StepError
+		for _, e := range joined.Unwrap() {
+			result = append(result, stepErrors(e)...)
+		}
+
+		return result
+	}
+
+	return stepErrors(errors.UnwrapOnce(err))
+}
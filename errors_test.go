@@ -0,0 +1,58 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestJoinErrors(t *testing.T) {
+	assert.Nil(t, joinErrors("test"))
+	assert.Nil(t, joinErrors("test", nil, nil))
+
+	err1 := errors.New("error 1")
+	err2 := &StepError{StepID: "step_2", Action: RollbackAction, Err: errors.New("error 2")}
+	joined := joinErrors("test", err1, nil)
+	joined = joinErrors("test", joined, err2)
+
+	var wfErr *WorkflowError
+	assert.True(t, errors.As(joined, &wfErr))
+	assert.Equal(t, 2, len(wfErr.Errors))
+	assert.True(t, errors.Is(joined, err1))
+	assert.False(t, errors.Is(joined, errors.New("error 3")))
+	assert.Equal(t, "workflow \"test\" failed: error 1; step \"step_2\" rollback failed: error 2", joined.Error())
+}
+
+type mockTimeoutError struct{}
+
+func (e mockTimeoutError) Error() string { return "mock timeout" }
+func (e mockTimeoutError) Timeout() bool { return true }
+
+func TestErrorHelpers(t *testing.T) {
+	runErr := &StepError{StepID: "step_2", Action: RunAction, Err: context.DeadlineExceeded}
+	rollbackErr := &StepError{StepID: "step_1", Action: RollbackAction, Err: errors.New("rollback error")}
+
+	stepErr, ok := AsStepError(errors.Wrap(runErr, "wrapped"))
+	assert.True(t, ok)
+	assert.Equal(t, "step_2", stepErr.StepID)
+	_, ok = AsStepError(errors.New("plain"))
+	assert.False(t, ok)
+
+	assert.True(t, IsTimeout(runErr))
+	assert.True(t, IsTimeout(fmt.Errorf("wrapped: %w", mockTimeoutError{})))
+	assert.False(t, IsTimeout(rollbackErr))
+	assert.False(t, IsTimeout(nil))
+
+	assert.False(t, IsRollbackFailure(runErr))
+	assert.True(t, IsRollbackFailure(rollbackErr))
+	assert.True(t, IsRollbackFailure(errors.Wrap(joinErrors("test", runErr, rollbackErr), "wrapped")))
+	assert.False(t, IsRollbackFailure(joinErrors("test", runErr)))
+	assert.False(t, IsRollbackFailure(nil))
+
+	// a failed rollback is detected even though it wraps the run errors of a nested workflow
+	nestedErr := &StepError{StepID: "step_3", Action: RollbackAction, Err: joinErrors("nested", runErr)}
+	assert.True(t, IsRollbackFailure(errors.Wrap(nestedErr, "wrapped")))
+	assert.Equal(t, []*StepError{nestedErr, runErr}, stepErrors(nestedErr))
+}
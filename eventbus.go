@@ -0,0 +1,113 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// EventType defines the type of a workflow lifecycle Event
+type EventType string
+
+const (
+	WorkflowStarted   EventType = "workflow_started"
+	WorkflowFinished  EventType = "workflow_finished"
+	StepStarted       EventType = "step_started"
+	StepCompleted     EventType = "step_completed"
+	StepFailed        EventType = "step_failed"
+	RollbackStarted   EventType = "rollback_started"
+	RollbackCompleted EventType = "rollback_completed"
+	RollbackFailed    EventType = "rollback_failed"
+	StepProgress      EventType = "step_progress"
+)
+
+// Event defines a workflow lifecycle event
+// StepID is empty for workflow events. Status is set for completed and failed events, e.g. StatusSkipped for a
+// skipped step, Err is set for failed events and Progress is set for StepProgress events, see ProgressWriter.
+type Event struct {
+	Type       EventType
+	WorkflowID string
+	RunID      string
+	StepID     string
+	Status     Status
+	Err        error
+	Progress   *Progress
+	Time       time.Time
+}
+
+// EventListener is a func definition to receive the events of a workflow run
+// Listeners are invoked synchronously in the order of registration, so they are expected to return quickly. They may
+// be invoked concurrently by the members of a ParallelGroup.
+type EventListener func(ctx context.Context, event Event)
+
+// EventEmitter defines the method to emit events to the listeners of a workflow run
+// It is available to the steps using EventEmitterFromContext in order to emit custom events.
+type EventEmitter interface {
+	Emit(ctx context.Context, event Event)
+}
+
+// eventBus dispatches the events of a workflow run to its listeners
+type eventBus struct {
+	listeners []EventListener
+}
+
+// Emit implements EventEmitter interface
+// It sets the workflow ID, run ID and time of the event if not set. A panicking listener is reported as a sink error.
+func (b *eventBus) Emit(ctx context.Context, event Event) {
+	if event.WorkflowID == "" {
+		event.WorkflowID, _ = WorkflowIdFromContext(ctx)
+	}
+
+	if event.RunID == "" {
+		event.RunID, _ = RunIdFromContext(ctx)
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	for _, listener := range b.listeners {
+		b.notify(ctx, listener, event)
+	}
+}
+
+// notify invokes the listener recovering from any panic
+func (b *eventBus) notify(ctx context.Context, listener EventListener, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			ReportSinkError(ctx, "event_listener", fmt.Errorf("listener panicked on %s: %v", event.Type, r))
+		}
+	}()
+
+	listener(ctx, event)
+}
+
+// WithEventListener registers a listener to receive the lifecycle events of every run of the Workflow
+func WithEventListener(listener EventListener) WorkflowOption {
+	return func(wf *Workflow) {
+		if wf.events == nil {
+			wf.events = &eventBus{}
+		}
+
+		wf.events.listeners = append(wf.events.listeners, listener)
+	}
+}
+
+// withEventEmitter returns a copy of the context with the given EventEmitter
+func withEventEmitter(ctx context.Context, emitter EventEmitter) context.Context {
+	return context.WithValue(ctx, ctxKeyEventEmitter, emitter)
+}
+
+// EventEmitterFromContext returns the EventEmitter of the current workflow run
+// It returns false if the context doesn't belong to a workflow run with event listeners.
+func EventEmitterFromContext(ctx context.Context) (EventEmitter, bool) {
+	emitter, ok := ctx.Value(ctxKeyEventEmitter).(EventEmitter)
+	return emitter, ok
+}
+
+// emitEvent emits an event to the listeners of the workflow run, if any
+func emitEvent(ctx context.Context, eventType EventType, stepID string, status Status, err error) {
+	if emitter, ok := EventEmitterFromContext(ctx); ok {
+		emitter.Emit(ctx, Event{Type: eventType, StepID: stepID, Status: status, Err: err})
+	}
+}
@@ -0,0 +1,159 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWorkflow_EventListener(t *testing.T) {
+	ctx := context.Background()
+
+	var events []string
+	listener := func(ctx context.Context, event Event) {
+		assert.Equal(t, "workflow_1", event.WorkflowID)
+		assert.NotEmpty(t, event.RunID)
+		assert.False(t, event.Time.IsZero())
+		events = append(events, fmt.Sprintf("%s %s %s", event.Type, event.StepID, event.Status))
+	}
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		emitter, ok := EventEmitterFromContext(ctx)
+		assert.True(t, ok)
+		emitter.Emit(ctx, Event{Type: "progress", StepID: "step_1"})
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	})
+
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return true, nil
+	}, nil)
+
+	s3 := &Step{ID: "step_3"}
+	s3.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock rollback error")
+	})
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(s1, s2, s3),
+		WithEventListener(listener),
+		WithEventListener(func(ctx context.Context, event Event) {
+			panic("mock panic")
+		}))
+
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"workflow_started  ",
+		"step_started step_1 ",
+		"progress step_1 ",
+		"step_completed step_1 SUCCESS",
+		"step_started step_2 ",
+		"step_completed step_2 SKIPPED",
+		"step_started step_3 ",
+		"step_failed step_3 FAILED",
+		"rollback_started step_3 ",
+		"rollback_failed step_3 FAILED",
+		"rollback_started step_2 ",
+		"rollback_completed step_2 SKIPPED",
+		"rollback_started step_1 ",
+		"rollback_completed step_1 SUCCESS",
+		"workflow_finished  FAILED",
+	}, events)
+	assert.NotEmpty(t, report.Diagnostics.SinkErrors)
+	assert.Equal(t, "event_listener", report.Diagnostics.SinkErrors[0].Sink)
+
+	// not available without listeners
+	s4 := &Step{ID: "step_4"}
+	s4.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		_, ok := EventEmitterFromContext(ctx)
+		assert.False(t, ok)
+		return false, nil
+	}, nil)
+	_, err = NewWorkflow("workflow_2", WithSteps(s4)).Start(ctx)
+	assert.NoError(t, err)
+}
+
+func TestWorkflow_EventListenerCompensation(t *testing.T) {
+	var events []string
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock rollback error")
+	})
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(s1),
+		WithExecutionMode(CompensateAndContinue),
+		WithEventListener(func(ctx context.Context, event Event) {
+			if event.StepID != "" {
+				events = append(events, fmt.Sprintf("%s %s", event.Type, event.Status))
+			}
+		}))
+
+	_, _ = workflow.Start(context.Background())
+	assert.Equal(t, []string{
+		"step_started ",
+		"step_failed FAILED",
+		"rollback_started ",
+		"rollback_failed FAILED",
+	}, events)
+}
+
+func TestWorkflow_EventListenerUndo(t *testing.T) {
+	ctx := context.Background()
+
+	var events []string
+	collector := &mockMetricsCollector{}
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	})
+
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return true, nil
+	})
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(s1, s2),
+		WithUndoWindow(time.Minute),
+		WithMetrics(collector),
+		WithEventListener(func(ctx context.Context, event Event) {
+			assert.Equal(t, "workflow_1", event.WorkflowID)
+			assert.NotEmpty(t, event.RunID)
+			events = append(events, fmt.Sprintf("%s %s %s", event.Type, event.StepID, event.Status))
+		}))
+	defer workflow.End(ctx)
+
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	events = nil
+	collector.calls = nil
+	_, err = workflow.Undo(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"rollback_started step_2 ",
+		"rollback_completed step_2 SKIPPED",
+		"rollback_started step_1 ",
+		"rollback_completed step_1 SUCCESS",
+	}, events)
+	assert.Equal(t, []string{
+		"step_2 rollback SKIPPED false",
+		"step_1 rollback SUCCESS false",
+	}, collector.calls)
+}
@@ -18,21 +18,27 @@ type Failure struct {
 
 // NewFailedRun returns a Failure event to be used for first Rollback method
 // It is used by a step to trigger its own rollback action
-// It sets the step's RunAction status as StatusFailed
+// It sets the step's RunAction status as StatusFailed, or StatusTimedOut if the error is a timeout
 func NewFailedRun(ctx context.Context, prevSuccess *Success, err error, report *StepReport) *Failure {
 	report.Action = RunAction
 	report.FailureReason = errors.EncodeError(ctx, err)
-	prevSuccess.workflowReport.Append(report, RunAction, StatusFailed)
-	return &Failure{error: err, workflowReport: prevSuccess.workflowReport}
+	prevSuccess.workflowReport.Append(report, RunAction, failureStatus(err))
+	emitEvent(ctx, StepFailed, report.StepID, report.Status, err)
+	stepErr := &StepError{StepID: report.StepID, Action: RunAction, Err: err}
+	return &Failure{error: stepErr, workflowReport: prevSuccess.workflowReport}
 }
 
 // NewFailedRollback returns a Failure event when steps rollback action failed
+// The rollback error is aggregated with the error of the previous Failure event in a WorkflowError.
 // It sets the step's RollbackAction status as StatusFailed
 func NewFailedRollback(ctx context.Context, prevFailure *Failure, err error, report *StepReport) *Failure {
 	report.Action = RollbackAction
 	report.FailureReason = errors.EncodeError(ctx, err)
 	prevFailure.workflowReport.Append(report, RollbackAction, StatusFailed)
-	return &Failure{error: err, workflowReport: prevFailure.workflowReport}
+	emitEvent(ctx, RollbackFailed, report.StepID, StatusFailed, err)
+	stepErr := &StepError{StepID: report.StepID, Action: RollbackAction, Err: err}
+	joined := joinErrors(prevFailure.workflowReport.WorkflowID, prevFailure.error, stepErr)
+	return &Failure{error: joined, workflowReport: prevFailure.workflowReport}
 }
 
 // NewStartTrigger returns a Success event to be use for Run method
@@ -72,3 +78,10 @@ func NewSkippedRollback(prevFailure *Failure, report *StepReport) *Failure {
 	prevFailure.workflowReport.Append(report, RollbackAction, StatusSkipped)
 	return &Failure{error: prevFailure.error, workflowReport: prevFailure.workflowReport}
 }
+
+// NewScheduledRollback creates a Failure event with StatusScheduled for RollbackAction
+// This is a helper method to be used in rollback action when the rollback action is deferred to a later time.
+func NewScheduledRollback(prevFailure *Failure, report *StepReport) *Failure {
+	prevFailure.workflowReport.Append(report, RollbackAction, StatusScheduled)
+	return &Failure{error: prevFailure.error, workflowReport: prevFailure.workflowReport}
+}
@@ -0,0 +1,107 @@
+// Package examples contains example workflows showcasing the automa API
+// Workflows are only constructed by the functions of this package, so that they can be inspected, planned or run by
+// the caller, e.g. the runner in the example directory and the tests of this package.
+package examples
+
+import (
+	"github.com/automa-saga/automa"
+	"go.uber.org/zap"
+	"sort"
+)
+
+// Constructor returns a new instance of an example workflow without any side effects
+type Constructor func(logger *zap.Logger) (*automa.Workflow, error)
+
+// Example describes an example workflow
+type Example struct {
+	ID          string
+	Description string
+	New         Constructor
+
+	// Fails is set if the run of the example is expected to fail and be rolled back
+	Fails bool
+}
+
+// registry of the examples by ID
+var registry = map[string]Example{
+	"restart_containers": {
+		ID:          "restart_containers",
+		Description: "restarts containers with the latest images, the restart fails and everything is rolled back",
+		New:         NewRestartContainersWorkflow,
+		Fails:       true,
+	},
+	"release": {
+		ID:          "release",
+		Description: "fetches images in parallel in a prepare phase, then stops containers in a deploy phase",
+		New:         NewReleaseWorkflow,
+	},
+}
+
+// All returns all the examples ordered by ID
+func All() []Example {
+	var examples []Example
+	for _, ex := range registry {
+		examples = append(examples, ex)
+	}
+
+	sort.Slice(examples, func(i, j int) bool {
+		return examples[i].ID < examples[j].ID
+	})
+
+	return examples
+}
+
+// Get returns the example with the given ID
+func Get(id string) (Example, bool) {
+	ex, ok := registry[id]
+	return ex, ok
+}
+
+// NewRestartContainersWorkflow returns a workflow built from a StepRegistry whose last step always fails
+// The step notifying on Slack in the middle is skipped both in run and rollback.
+func NewRestartContainersWorkflow(logger *zap.Logger) (*automa.Workflow, error) {
+	stop := NewStopContainers("stop_containers", logger)
+	fetch := NewFetchLatest("fetch_latest_images", logger)
+	notify := NewNotifyAll("notify_all_on_slack", logger)
+	restart := NewRestartContainers("restart_containers", logger)
+
+	registry := automa.NewStepRegistry(logger).RegisterSteps(map[string]automa.AtomicStep{
+		stop.ID:    stop,
+		fetch.ID:   fetch,
+		notify.ID:  notify,
+		restart.ID: restart,
+	})
+
+	// a new workflow with notify in the middle
+	workflow, err := registry.BuildWorkflow("workflow_1", automa.StepIDs{
+		stop.ID,
+		fetch.ID,
+		notify.ID,
+		restart.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return workflow.(*automa.Workflow), nil
+}
+
+// NewReleaseWorkflow returns a workflow with a ParallelGroup and phases
+func NewReleaseWorkflow(logger *zap.Logger) (*automa.Workflow, error) {
+	fetch := automa.NewParallelGroup("fetch_images",
+		NewFetchLatest("fetch_app_image", logger),
+		NewFetchLatest("fetch_db_image", logger),
+	).WithMaxConcurrency(2)
+
+	prepare := automa.NewPhase("prepare", fetch)
+	deploy := automa.NewPhase("deploy",
+		NewStopContainers("stop_containers", logger),
+		NewNotifyAll("notify_all_on_slack", logger),
+	)
+
+	return automa.NewWorkflow("release",
+		automa.WithLogger(logger),
+		automa.WithPhase(prepare),
+		automa.WithPhase(deploy),
+	), nil
+}
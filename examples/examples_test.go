@@ -0,0 +1,64 @@
+package examples
+
+import (
+	"context"
+	"github.com/automa-saga/automa"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	examples := All()
+	assert.Equal(t, 2, len(examples))
+	assert.Equal(t, "release", examples[0].ID)
+
+	ex, ok := Get("restart_containers")
+	assert.True(t, ok)
+	assert.True(t, ex.Fails)
+
+	_, ok = Get("unknown")
+	assert.False(t, ok)
+}
+
+func TestExamples_DryRun(t *testing.T) {
+	ctx := context.Background()
+	for _, ex := range All() {
+		t.Run(ex.ID, func(t *testing.T) {
+			wf, err := ex.New(zap.NewNop())
+			assert.NoError(t, err)
+			defer wf.End(ctx)
+
+			report, err := wf.Plan(ctx)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, report.StepReports)
+			for _, stepReport := range report.StepReports {
+				assert.Contains(t, []automa.Status{automa.StatusPlanned, automa.StatusSkipped}, stepReport.Status)
+				if stepReport.Action == automa.RunAction {
+					assert.Equal(t, automa.StatusPlanned, stepReport.Status, stepReport.StepID)
+					assert.NotEmpty(t, stepReport.Metadata[automa.PlanMetadataKey], stepReport.StepID)
+				}
+			}
+		})
+	}
+}
+
+func TestExamples_Run(t *testing.T) {
+	ctx := context.Background()
+	for _, ex := range All() {
+		t.Run(ex.ID, func(t *testing.T) {
+			wf, err := ex.New(zap.NewNop())
+			assert.NoError(t, err)
+			defer wf.End(ctx)
+
+			report, err := wf.Start(ctx)
+			if ex.Fails {
+				assert.Error(t, err)
+				assert.Equal(t, automa.StatusFailed, report.Status)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, automa.StatusSuccess, report.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+	"github.com/automa-saga/automa"
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+)
+
+// InMemCache is the simples map based in-memory cache
+// It is assumed the type casting will be done properly and safely when values are retrieved
+// This doesn't use mutex so shouldn't be used with coroutines
+// This is just for example purposes.
+type InMemCache map[string]interface{}
+
+// GetString returns the string value for the given key
+func (ic InMemCache) GetString(key string) string {
+	if s, ok := ic[key].(string); ok {
+		return s
+	}
+
+	return ""
+}
+
+// SetString returns the string value for the given key
+func (ic InMemCache) SetString(key string, val interface{}) {
+	s, ok := val.(string)
+	if ok {
+		ic[key] = s
+	}
+}
+
+const (
+	keyRollbackMsg = "rollbackMsg"
+)
+
+type StopContainers struct {
+	automa.Step
+	cache  InMemCache
+	logger *zap.Logger
+}
+
+type FetchLatest struct {
+	automa.Step
+	cache  InMemCache
+	logger *zap.Logger
+}
+
+// NotifyAll notifies on Slack
+// it cannot be rollback
+type NotifyAll struct {
+	automa.Step
+	cache  InMemCache
+	logger *zap.Logger
+}
+
+type RestartContainers struct {
+	automa.Step
+	cache  InMemCache
+	logger *zap.Logger
+}
+
+// NewStopContainers returns a StopContainers step
+func NewStopContainers(id string, logger *zap.Logger) *StopContainers {
+	s := &StopContainers{Step: automa.Step{ID: id}, cache: InMemCache{}, logger: logger}
+	s.RegisterSaga(s.run, s.rollback)
+	s.RegisterPlan(func(ctx context.Context) (*automa.StepPlan, error) {
+		return &automa.StepPlan{Run: "stop running containers", Rollback: "start the stopped containers"}, nil
+	})
+
+	return s
+}
+
+// NewFetchLatest returns a FetchLatest step
+func NewFetchLatest(id string, logger *zap.Logger) *FetchLatest {
+	s := &FetchLatest{Step: automa.Step{ID: id}, cache: InMemCache{}, logger: logger}
+	s.RegisterSaga(s.run, s.rollback)
+	s.RegisterPlan(func(ctx context.Context) (*automa.StepPlan, error) {
+		return &automa.StepPlan{Run: "pull the latest images", Rollback: "remove the pulled images"}, nil
+	})
+
+	return s
+}
+
+// NewNotifyAll returns a NotifyAll step
+func NewNotifyAll(id string, logger *zap.Logger) *NotifyAll {
+	s := &NotifyAll{Step: automa.Step{ID: id}, cache: InMemCache{}, logger: logger}
+	s.RegisterSaga(s.run, s.rollback)
+	s.RegisterPlan(func(ctx context.Context) (*automa.StepPlan, error) {
+		return &automa.StepPlan{Run: "notify all on Slack", NoRollback: true}, nil
+	})
+
+	return s
+}
+
+// NewRestartContainers returns a RestartContainers step that always fails
+func NewRestartContainers(id string, logger *zap.Logger) *RestartContainers {
+	s := &RestartContainers{Step: automa.Step{ID: id}, cache: InMemCache{}, logger: logger}
+	s.RegisterSaga(s.run, s.rollback)
+	s.RegisterPlan(func(ctx context.Context) (*automa.StepPlan, error) {
+		return &automa.StepPlan{Run: "restart containers", Rollback: "stop the restarted containers"}, nil
+	})
+
+	return s
+}
+
+func (s *StopContainers) run(ctx context.Context) (skipped bool, err error) {
+	// reset cache
+	s.cache = InMemCache{}
+
+	s.logger.Debug(fmt.Sprintf("RUN - %q", s.ID))
+	s.cache.SetString(keyRollbackMsg, fmt.Sprintf("ROLLBACK - %q", s.ID))
+
+	return false, nil
+}
+
+func (s *StopContainers) rollback(ctx context.Context) (skipped bool, err error) {
+	// use cache
+	s.logger.Debug(s.cache.GetString(keyRollbackMsg))
+
+	return false, nil
+}
+
+func (s *FetchLatest) run(ctx context.Context) (skipped bool, err error) {
+	// reset cache
+	s.cache = InMemCache{}
+
+	s.logger.Debug(fmt.Sprintf("RUN - %q", s.ID))
+	s.cache.SetString(keyRollbackMsg, fmt.Sprintf("ROLLBACK - %q", s.ID))
+
+	return false, nil
+}
+
+func (s *FetchLatest) rollback(ctx context.Context) (skipped bool, err error) {
+	// use cache
+	s.logger.Debug(s.cache.GetString(keyRollbackMsg))
+
+	return false, nil
+}
+
+func (s *NotifyAll) run(ctx context.Context) (skipped bool, err error) {
+	// reset cache
+	s.cache = InMemCache{}
+
+	s.logger.Debug(fmt.Sprintf("RUN - %q", s.ID))
+	s.cache.SetString(keyRollbackMsg, fmt.Sprintf("ROLLBACK - %q", s.ID))
+
+	// skip step
+	return true, nil
+}
+
+func (s *NotifyAll) rollback(ctx context.Context) (skipped bool, err error) {
+	// use cache
+	s.logger.Debug(s.cache.GetString(keyRollbackMsg))
+
+	return true, nil
+}
+
+func (s *RestartContainers) run(ctx context.Context) (skipped bool, err error) {
+	// reset cache
+	s.cache = InMemCache{}
+
+	s.logger.Debug(fmt.Sprintf("RUN - %q", s.ID))
+	s.cache.SetString(keyRollbackMsg, fmt.Sprintf("ROLLBACK - %q", s.ID))
+
+	return false, errors.Newf("Mock error during %q", s.GetID())
+}
+
+func (s *RestartContainers) rollback(ctx context.Context) (skipped bool, err error) {
+	// use cache
+	s.logger.Debug(s.cache.GetString(keyRollbackMsg))
+
+	return false, nil
+}
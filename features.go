@@ -0,0 +1,110 @@
+package automa
+
+import (
+	"context"
+	"sort"
+)
+
+// Feature is the name of a behavior of the engine that can be toggled per workflow, see WithFeatures
+type Feature string
+
+const (
+	// FeatureParallelExecution runs the members of a ParallelGroup concurrently
+	// If disabled, the members run one at a time in order, e.g. to troubleshoot a group.
+	FeatureParallelExecution Feature = "parallel_execution"
+)
+
+// FeatureStage defines the maturity of a Feature
+type FeatureStage string
+
+const (
+	// FeatureAlpha features are experimental and disabled by default, they may change or be removed in any release
+	FeatureAlpha FeatureStage = "alpha"
+
+	// FeatureBeta features are enabled by default, they may still change in a minor release
+	FeatureBeta FeatureStage = "beta"
+)
+
+// FeatureGate describes a Feature and whether it is enabled unless toggled using WithFeatures
+type FeatureGate struct {
+	Feature     Feature      `yaml:"feature" json:"feature"`
+	Stage       FeatureStage `yaml:"stage" json:"stage"`
+	Default     bool         `yaml:"default" json:"default"`
+	Description string       `yaml:"description" json:"description"`
+}
+
+// featureGates is the registry of the features of the engine
+var featureGates = map[Feature]FeatureGate{
+	FeatureParallelExecution: {
+		Feature:     FeatureParallelExecution,
+		Stage:       FeatureBeta,
+		Default:     true,
+		Description: "run the members of a ParallelGroup concurrently",
+	},
+}
+
+// FeatureGates returns the gates of all the features of the engine ordered by feature
+func FeatureGates() []FeatureGate {
+	gates := make([]FeatureGate, 0, len(featureGates))
+	for _, gate := range featureGates {
+		gates = append(gates, gate)
+	}
+
+	sort.Slice(gates, func(i, j int) bool {
+		return gates[i].Feature < gates[j].Feature
+	})
+
+	return gates
+}
+
+// WithFeatures enables or disables features of the engine for the runs of the Workflow
+// Features that are not toggled keep the default of their FeatureGate. Unknown features are ignored and reported as
+// warnings of the runs.
+func WithFeatures(features map[Feature]bool) WorkflowOption {
+	return func(wf *Workflow) {
+		if wf.features == nil {
+			wf.features = map[Feature]bool{}
+		}
+
+		for feature, enabled := range features {
+			wf.features[feature] = enabled
+		}
+	}
+}
+
+// withFeatures returns a copy of the context with the given toggled features
+func withFeatures(ctx context.Context, features map[Feature]bool) context.Context {
+	return context.WithValue(ctx, ctxKeyFeatures, features)
+}
+
+// FeatureEnabled returns true if the feature is enabled for the current workflow run
+// Outside a workflow run, it returns the default of the FeatureGate. Unknown features are never enabled.
+func FeatureEnabled(ctx context.Context, feature Feature) bool {
+	gate, ok := featureGates[feature]
+	if !ok {
+		return false
+	}
+
+	if features, ok := ctx.Value(ctxKeyFeatures).(map[Feature]bool); ok {
+		if enabled, ok := features[feature]; ok {
+			return enabled
+		}
+	}
+
+	return gate.Default
+}
+
+// warnUnknownFeatures adds a warning for every toggled feature that is not known by the engine
+func warnUnknownFeatures(ctx context.Context, features map[Feature]bool) {
+	var unknown []string
+	for feature := range features {
+		if _, ok := featureGates[feature]; !ok {
+			unknown = append(unknown, string(feature))
+		}
+	}
+
+	sort.Strings(unknown)
+	for _, feature := range unknown {
+		AddWarning(ctx, "unknown feature %q is ignored", feature)
+	}
+}
@@ -0,0 +1,64 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFeatureGates(t *testing.T) {
+	gates := FeatureGates()
+	assert.Equal(t, len(featureGates), len(gates))
+	for i := 1; i < len(gates); i++ {
+		assert.True(t, gates[i-1].Feature < gates[i].Feature)
+	}
+
+	ctx := context.Background()
+	assert.True(t, FeatureEnabled(ctx, FeatureParallelExecution))
+	assert.False(t, FeatureEnabled(ctx, Feature("unknown")))
+
+	ctx = withFeatures(ctx, map[Feature]bool{FeatureParallelExecution: false, "unknown": true})
+	assert.False(t, FeatureEnabled(ctx, FeatureParallelExecution))
+	assert.False(t, FeatureEnabled(ctx, Feature("unknown")))
+}
+
+func TestWithFeatures(t *testing.T) {
+	ctx := context.Background()
+
+	var mutex sync.Mutex
+	var running, maxRunning int
+	var order []string
+	newStep := func(id string) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			mutex.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			order = append(order, id)
+			mutex.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			return false, nil
+		}, nil)
+		return s
+	}
+
+	group := NewParallelGroup("install_tools", newStep("install_helm"), newStep("install_kubectl"), newStep("install_jq"))
+	workflow := NewWorkflow("workflow_1", WithSteps(group),
+		WithFeatures(map[Feature]bool{FeatureParallelExecution: false, "report_pooling": true}))
+	defer workflow.End(ctx)
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, maxRunning)
+	assert.Equal(t, []string{"install_helm", "install_kubectl", "install_jq"}, order)
+	assert.Equal(t, []string{`unknown feature "report_pooling" is ignored`}, report.Warnings)
+}
@@ -0,0 +1,156 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"strconv"
+	"time"
+)
+
+// ReportFormatter renders the timestamps and durations of a WorkflowReport consistently for printing
+// Raw time.Time fields render differently depending on the marshaller and the local timezone, so printers should
+// marshal the ReportView returned by View instead of the WorkflowReport itself.
+type ReportFormatter struct {
+	location     *time.Location
+	durationUnit time.Duration
+	relative     bool
+}
+
+// ReportFormatterOption allows setting various option for ReportFormatter
+type ReportFormatterOption func(f *ReportFormatter)
+
+// WithReportTimezone sets the timezone of the timestamps, by default UTC
+func WithReportTimezone(location *time.Location) ReportFormatterOption {
+	return func(f *ReportFormatter) {
+		if location != nil {
+			f.location = location
+		}
+	}
+}
+
+// WithDurationUnit sets the unit of the durations, e.g. time.Millisecond renders 1.5s as "1500ms"
+// Supported units are time.Nanosecond, time.Microsecond, time.Millisecond, time.Second, time.Minute and time.Hour.
+// By default, durations are rendered using time.Duration.String.
+func WithDurationUnit(unit time.Duration) ReportFormatterOption {
+	return func(f *ReportFormatter) {
+		if _, ok := durationUnits[unit]; ok {
+			f.durationUnit = unit
+		}
+	}
+}
+
+// WithRelativeTimestamps renders the timestamps of the steps relative to the start of the run, e.g. "+1.5s"
+// By default, all timestamps are rendered as RFC3339 with nanoseconds.
+func WithRelativeTimestamps(relative bool) ReportFormatterOption {
+	return func(f *ReportFormatter) {
+		f.relative = relative
+	}
+}
+
+// durationUnits maps the supported duration units to their suffix
+var durationUnits = map[time.Duration]string{
+	time.Nanosecond:  "ns",
+	time.Microsecond: "µs",
+	time.Millisecond: "ms",
+	time.Second:      "s",
+	time.Minute:      "m",
+	time.Hour:        "h",
+}
+
+// NewReportFormatter returns an instance of ReportFormatter
+func NewReportFormatter(opts ...ReportFormatterOption) *ReportFormatter {
+	f := &ReportFormatter{location: time.UTC}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+}
+
+// FormatTime renders the timestamp as RFC3339 with nanoseconds in the timezone of the formatter
+func (f *ReportFormatter) FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.In(f.location).Format(time.RFC3339Nano)
+}
+
+// FormatDuration renders the duration in the unit of the formatter
+func (f *ReportFormatter) FormatDuration(d time.Duration) string {
+	if f.durationUnit == 0 {
+		return d.String()
+	}
+
+	return strconv.FormatFloat(float64(d)/float64(f.durationUnit), 'f', -1, 64) + durationUnits[f.durationUnit]
+}
+
+// formatStepTime renders the timestamp of a step, relative to the start of the run if relative timestamps are enabled
+func (f *ReportFormatter) formatStepTime(t time.Time, runStart time.Time) string {
+	if f.relative && !t.IsZero() {
+		return "+" + f.FormatDuration(t.Sub(runStart))
+	}
+
+	return f.FormatTime(t)
+}
+
+// ReportView is the printable form of a WorkflowReport with formatted timestamps and durations
+type ReportView struct {
+	WorkflowID string            `yaml:"workflow_id" json:"workflowID"`
+	RunID      string            `yaml:"run_id" json:"runID"`
+	Status     Status            `yaml:"status" json:"status"`
+	StartTime  string            `yaml:"start_time" json:"startTime"`
+	EndTime    string            `yaml:"end_time" json:"endTime"`
+	Duration   string            `yaml:"duration" json:"duration"`
+	Steps      []*StepReportView `yaml:"steps" json:"steps"`
+	Warnings   []string          `yaml:"warnings,omitempty" json:"warnings,omitempty"`
+}
+
+// StepReportView is the printable form of a StepReport with formatted timestamps and durations
+type StepReportView struct {
+	StepID        string         `yaml:"step_id" json:"stepID"`
+	DisplayName   string         `yaml:"display_name,omitempty" json:"displayName,omitempty"`
+	Group         string         `yaml:"group,omitempty" json:"group,omitempty"`
+	Action        StepActionType `yaml:"action" json:"action"`
+	Status        Status         `yaml:"status" json:"status"`
+	StartTime     string         `yaml:"start_time" json:"startTime"`
+	EndTime       string         `yaml:"end_time" json:"endTime"`
+	Duration      string         `yaml:"duration" json:"duration"`
+	FailureReason string         `yaml:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// View returns the printable form of the report
+func (f *ReportFormatter) View(report WorkflowReport) *ReportView {
+	view := &ReportView{
+		WorkflowID: report.WorkflowID,
+		RunID:      report.RunID,
+		Status:     report.Status,
+		StartTime:  f.FormatTime(report.StartTime),
+		EndTime:    f.FormatTime(report.EndTime),
+		Duration:   f.FormatDuration(report.EndTime.Sub(report.StartTime)),
+		Steps:      []*StepReportView{},
+		Warnings:   report.Warnings,
+	}
+
+	for _, stepReport := range report.StepReports {
+		stepView := &StepReportView{
+			StepID:      stepReport.StepID,
+			DisplayName: stepReport.DisplayName,
+			Group:       stepReport.Group,
+			Action:      stepReport.Action,
+			Status:      stepReport.Status,
+			StartTime:   f.formatStepTime(stepReport.StartTime, report.StartTime),
+			EndTime:     f.formatStepTime(stepReport.EndTime, report.StartTime),
+			Duration:    f.FormatDuration(stepReport.EndTime.Sub(stepReport.StartTime)),
+		}
+
+		if stepReport.FailureReason.Error != nil {
+			stepView.FailureReason = errors.DecodeError(context.Background(), stepReport.FailureReason).Error()
+		}
+
+		view.Steps = append(view.Steps, stepView)
+	}
+
+	return view
+}
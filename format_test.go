@@ -0,0 +1,58 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+	"time"
+)
+
+func TestReportFormatter_FormatDuration(t *testing.T) {
+	assert.Equal(t, "1.5s", NewReportFormatter().FormatDuration(1500*time.Millisecond))
+	assert.Equal(t, "1500ms", NewReportFormatter(WithDurationUnit(time.Millisecond)).FormatDuration(1500*time.Millisecond))
+	assert.Equal(t, "0.025m", NewReportFormatter(WithDurationUnit(time.Minute)).FormatDuration(1500*time.Millisecond))
+
+	// unsupported units are ignored
+	assert.Equal(t, "1.5s", NewReportFormatter(WithDurationUnit(3*time.Second)).FormatDuration(1500*time.Millisecond))
+}
+
+func TestReportFormatter_FormatTime(t *testing.T) {
+	ts := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2023-03-01T10:00:00Z", NewReportFormatter().FormatTime(ts))
+	assert.Equal(t, "", NewReportFormatter().FormatTime(time.Time{}))
+
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	assert.Equal(t, "2023-03-01T12:00:00+02:00", NewReportFormatter(WithReportTimezone(loc)).FormatTime(ts))
+}
+
+func TestReportFormatter_View(t *testing.T) {
+	start := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	report := NewWorkflowReport("workflow_1", nil)
+	report.RunID = "run_1"
+	report.Status = StatusFailed
+	report.StartTime = start
+	report.EndTime = start.Add(3 * time.Second)
+
+	stepReport := NewStepReport("step_1", RunAction)
+	stepReport.Status = StatusFailed
+	stepReport.StartTime = start.Add(time.Second)
+	stepReport.EndTime = start.Add(2500 * time.Millisecond)
+	stepReport.FailureReason = errors.EncodeError(context.Background(), errors.New("mock error"))
+	report.StepReports = append(report.StepReports, stepReport)
+
+	view := NewReportFormatter(WithDurationUnit(time.Millisecond), WithRelativeTimestamps(true)).View(*report)
+	assert.Equal(t, "2023-03-01T10:00:00Z", view.StartTime)
+	assert.Equal(t, "3000ms", view.Duration)
+	assert.Equal(t, 1, len(view.Steps))
+	assert.Equal(t, "+1000ms", view.Steps[0].StartTime)
+	assert.Equal(t, "+2500ms", view.Steps[0].EndTime)
+	assert.Equal(t, "1500ms", view.Steps[0].Duration)
+	assert.Equal(t, "mock error", view.Steps[0].FailureReason)
+
+	out, err := yaml.Marshal(view)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "start_time: +1000ms")
+}
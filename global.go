@@ -0,0 +1,43 @@
+package automa
+
+import (
+	"fmt"
+)
+
+// defaultRegistry is the package-level StepRegistry, see Register
+var defaultRegistry = NewStepRegistry(nil)
+
+// DefaultRegistry returns the package-level StepRegistry where step libraries register their builders
+func DefaultRegistry() *StepRegistry {
+	return defaultRegistry
+}
+
+// Register registers a StepBuilder by name with the default registry
+// It is meant to be called in the init function of a step library so that the steps can be referenced by
+// consumers using NamedSteps or workflow specs, without wiring every builder. It panics if the name is invalid or
+// the builder is nil, in order to fail fast on programming errors as init functions cannot return errors.
+func Register(name string, builder StepBuilder) {
+	if err := validateBuilderName(name); err != nil {
+		panic(err)
+	}
+
+	if builder == nil {
+		panic(fmt.Sprintf("automa: step builder %q is nil", name))
+	}
+
+	defaultRegistry.RegisterStepBuilder(name, builder)
+}
+
+// RegisterVersioned registers a StepBuilder by name and semantic version with the default registry
+// Like Register, it is meant to be called in init functions and panics if AddVersioned fails, e.g. if the same
+// version of a builder is registered twice.
+func RegisterVersioned(name string, version string, builder StepBuilder) {
+	if err := defaultRegistry.AddVersioned(name, version, builder); err != nil {
+		panic(err)
+	}
+}
+
+// NamedSteps builds the steps using the builders of the default registry, see StepRegistry.BuildSteps
+func NamedSteps(specs ...StepSpec) ([]AtomicStep, error) {
+	return defaultRegistry.BuildSteps(specs...)
+}
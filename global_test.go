@@ -0,0 +1,62 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	Register("test/unversioned", newVersionedBuilder("unversioned"))
+	RegisterVersioned("test/versioned", "v1.0.0", newVersionedBuilder("v1.0.0"))
+	RegisterVersioned("test/versioned", "v1.1.0", newVersionedBuilder("v1.1.0"))
+
+	assert.Panics(t, func() { Register("test/", newVersionedBuilder("v1.0.0")) })
+	assert.Panics(t, func() { Register("test/nil", nil) })
+	assert.Panics(t, func() { RegisterVersioned("test/versioned", "v1.0.0", newVersionedBuilder("v1.0.0")) })
+
+	info, err := DefaultRegistry().Describe("test/versioned")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.1.0", info.Version)
+
+	steps, err := NamedSteps(
+		StepSpec{ID: "step_1", Uses: "test/unversioned"},
+		StepSpec{ID: "step_2", Uses: "test/versioned@~1.0"},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(steps))
+	assert.Equal(t, "unversioned", steps[0].(*mockVersionedStep).version)
+	assert.Equal(t, "v1.0.0", steps[1].(*mockVersionedStep).version)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(steps...))
+	defer workflow.End(ctx)
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	_, err = NamedSteps(StepSpec{ID: "step_1", Uses: "test/unknown"})
+	assert.Error(t, err)
+}
+
+func TestStepRegistry_Concurrency(t *testing.T) {
+	registry := NewStepRegistry(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			registry.RegisterStepBuilder("test/step", newVersionedBuilder("unversioned"))
+			registry.RegisterSteps(map[string]AtomicStep{"step_1": &Step{ID: "step_1"}})
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = registry.Of("test/step")
+			_ = registry.List()
+			_ = registry.GetStep("step_1")
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 1, len(registry.List()))
+}
@@ -0,0 +1,114 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"sort"
+	"sync"
+)
+
+// ErrGoroutineBudgetExceeded is returned by Go when the step already runs as many goroutines as its budget
+var ErrGoroutineBudgetExceeded = errors.New("goroutine budget exceeded")
+
+// GoroutineLeak defines the goroutines of a step that were still running at the end of a workflow run
+type GoroutineLeak struct {
+	StepID string `yaml:"step_id" json:"stepID"`
+	Count  int    `yaml:"count" json:"count"`
+}
+
+// goroutines tracks the goroutines started by the steps of a workflow run using Go
+type goroutines struct {
+	mutex   sync.Mutex
+	running map[string]int
+}
+
+// newGoroutines returns an empty goroutines tracker
+func newGoroutines() *goroutines {
+	return &goroutines{running: map[string]int{}}
+}
+
+// start reserves a goroutine for the step if its budget allows it
+// A budget less than 1 means that the step is not limited.
+func (g *goroutines) start(stepID string, budget int) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if budget > 0 && g.running[stepID] >= budget {
+		return false
+	}
+
+	g.running[stepID]++
+
+	return true
+}
+
+// done releases a goroutine of the step
+func (g *goroutines) done(stepID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.running[stepID]--
+	if g.running[stepID] == 0 {
+		delete(g.running, stepID)
+	}
+}
+
+// leaks returns the goroutines still running sorted by step ID
+func (g *goroutines) leaks() []*GoroutineLeak {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var leaks []*GoroutineLeak
+	for stepID, count := range g.running {
+		leaks = append(leaks, &GoroutineLeak{StepID: stepID, Count: count})
+	}
+
+	sort.Slice(leaks, func(i, j int) bool {
+		return leaks[i].StepID < leaks[j].StepID
+	})
+
+	return leaks
+}
+
+// withGoroutines returns a copy of the context with the given goroutines tracker
+func withGoroutines(ctx context.Context, g *goroutines) context.Context {
+	return context.WithValue(ctx, ctxKeyGoroutines, g)
+}
+
+// WithGoroutineBudget sets the maximum number of goroutines the step may run at the same time using Go
+func (s *Step) WithGoroutineBudget(budget int) *Step {
+	s.goroutineBudget = budget
+
+	return s
+}
+
+// Go runs fn in a goroutine tracked by the engine on behalf of the current step
+// It returns ErrGoroutineBudgetExceeded if the step already runs as many goroutines as its budget, see
+// Step.WithGoroutineBudget. Goroutines still running at the end of the workflow run are reported as leaks in
+// WorkflowReport.Diagnostics. A panic in fn is recovered and reported as a warning.
+// It returns error if the context doesn't belong to a workflow step.
+func Go(ctx context.Context, fn func(ctx context.Context)) error {
+	g, ok := ctx.Value(ctxKeyGoroutines).(*goroutines)
+	stepID, hasStep := StepFromContext(ctx)
+	if !ok || !hasStep {
+		return errors.New("goroutine cannot be tracked outside of a workflow step")
+	}
+
+	budget, _ := ctx.Value(ctxKeyGoroutineBudget).(int)
+	if !g.start(stepID, budget) {
+		return errors.Wrapf(ErrGoroutineBudgetExceeded, "step %q", stepID)
+	}
+
+	go func() {
+		defer g.done(stepID)
+		defer func() {
+			if r := recover(); r != nil {
+				AddWarning(ctx, "goroutine of step %q panicked: %v", stepID, r)
+			}
+		}()
+
+		fn(ctx)
+	}()
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestGo(t *testing.T) {
+	ctx := context.Background()
+
+	// not allowed outside of a workflow step
+	assert.Error(t, Go(ctx, func(ctx context.Context) {}))
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	s1 := &Step{ID: "worker"}
+	s1.WithGoroutineBudget(2)
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		// finishes before the end of the run
+		done := make(chan struct{})
+		assert.NoError(t, Go(ctx, func(ctx context.Context) { close(done) }))
+		<-done
+
+		// leaks
+		assert.NoError(t, Go(ctx, func(ctx context.Context) {
+			defer wg.Done()
+			<-release
+		}))
+		assert.NoError(t, Go(ctx, func(ctx context.Context) {
+			panic("mock panic")
+		}))
+
+		return false, nil
+	}, nil)
+
+	s2 := &Step{ID: "limited"}
+	s2.WithGoroutineBudget(1)
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		block := make(chan struct{})
+		defer close(block)
+
+		assert.NoError(t, Go(ctx, func(ctx context.Context) { <-block }))
+		assert.ErrorIs(t, Go(ctx, func(ctx context.Context) {}), ErrGoroutineBudgetExceeded)
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+
+	var leaked []string
+	for _, leak := range report.Diagnostics.LeakedGoroutines {
+		leaked = append(leaked, leak.StepID)
+	}
+	assert.Contains(t, leaked, "worker")
+
+	close(release)
+	wg.Wait()
+}
+
+func TestGoroutines_Leaks(t *testing.T) {
+	g := newGoroutines()
+	assert.True(t, g.start("b", 0))
+	assert.True(t, g.start("a", 1))
+	assert.False(t, g.start("a", 1))
+	assert.True(t, g.start("b", 0))
+	assert.Equal(t, []*GoroutineLeak{{StepID: "a", Count: 1}, {StepID: "b", Count: 2}}, g.leaks())
+
+	g.done("a")
+	g.done("b")
+	assert.Equal(t, []*GoroutineLeak{{StepID: "b", Count: 1}}, g.leaks())
+}
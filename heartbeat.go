@@ -0,0 +1,193 @@
+package automa
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// Heartbeat defines the liveness record of a workflow run
+// External monitors can use it to detect dead runs, i.e. in-flight runs whose heartbeat is not updated anymore.
+type Heartbeat struct {
+	WorkflowID    string         `yaml:"workflow_id" json:"workflowID"`
+	RunID         string         `yaml:"run_id" json:"runID"`
+	CurrentStep   string         `yaml:"current_step" json:"currentStep"`
+	CurrentAction StepActionType `yaml:"current_action" json:"currentAction"`
+	Status        Status         `yaml:"status" json:"status"`
+	StartTime     time.Time      `yaml:"start_time" json:"startTime"`
+	Time          time.Time      `yaml:"time" json:"time"`
+}
+
+// HeartbeatStore defines the methods to persist heartbeats of workflow runs
+type HeartbeatStore interface {
+	// SaveHeartbeat saves the heartbeat replacing the previous heartbeat of the same run
+	SaveHeartbeat(ctx context.Context, hb Heartbeat) error
+
+	// LoadHeartbeat returns the last heartbeat of the run, if any
+	LoadHeartbeat(ctx context.Context, runID string) (Heartbeat, bool, error)
+}
+
+// InMemHeartbeatStore is an in-memory implementation of HeartbeatStore
+type InMemHeartbeatStore struct {
+	mutex      sync.Mutex
+	heartbeats map[string]Heartbeat
+}
+
+// NewInMemHeartbeatStore returns an instance of InMemHeartbeatStore
+func NewInMemHeartbeatStore() *InMemHeartbeatStore {
+	return &InMemHeartbeatStore{heartbeats: map[string]Heartbeat{}}
+}
+
+// SaveHeartbeat implements HeartbeatStore interface
+func (s *InMemHeartbeatStore) SaveHeartbeat(ctx context.Context, hb Heartbeat) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.heartbeats[hb.RunID] = hb
+
+	return nil
+}
+
+// LoadHeartbeat implements HeartbeatStore interface
+func (s *InMemHeartbeatStore) LoadHeartbeat(ctx context.Context, runID string) (Heartbeat, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hb, ok := s.heartbeats[runID]
+
+	return hb, ok, nil
+}
+
+// runTracker tracks the step being executed in a workflow run
+type runTracker struct {
+	mutex  sync.Mutex
+	stepID string
+	action StepActionType
+}
+
+// set sets the step and action being executed
+func (t *runTracker) set(stepID string, action StepActionType) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.stepID = stepID
+	t.action = action
+}
+
+// get returns the step and action being executed
+func (t *runTracker) get() (string, StepActionType) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.stepID, t.action
+}
+
+// withRunTracker returns a copy of the context with the given runTracker
+func withRunTracker(ctx context.Context, t *runTracker) context.Context {
+	return context.WithValue(ctx, ctxKeyRunTracker, t)
+}
+
+// trackStep records the step and action being executed in the runTracker of the context, if any
+func trackStep(ctx context.Context, stepID string, action StepActionType) {
+	if t, ok := ctx.Value(ctxKeyRunTracker).(*runTracker); ok {
+		t.set(stepID, action)
+	}
+}
+
+// heartbeater periodically saves the heartbeat of a workflow run in a HeartbeatStore
+type heartbeater struct {
+	store    HeartbeatStore
+	interval time.Duration
+	logger   *zap.Logger
+	tracker  *runTracker
+	base     Heartbeat
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// startHeartbeat saves the first heartbeat of the run and starts saving heartbeats at every interval
+func startHeartbeat(ctx context.Context, store HeartbeatStore, interval time.Duration, logger *zap.Logger,
+	tracker *runTracker, base Heartbeat) *heartbeater {
+	h := &heartbeater{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+		tracker:  tracker,
+		base:     base,
+		stopCh:   make(chan struct{}),
+	}
+
+	h.save(ctx, StatusUndefined)
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.stopCh:
+				return
+			case <-ticker.C:
+				h.save(ctx, StatusUndefined)
+			}
+		}
+	}()
+
+	return h
+}
+
+// save saves a heartbeat with the current step of the run and the given status
+// Failures are logged and reported as sink errors only, since a monitoring failure must not fail the workflow.
+func (h *heartbeater) save(ctx context.Context, status Status) {
+	hb := h.base
+	hb.CurrentStep, hb.CurrentAction = h.tracker.get()
+	hb.Status = status
+	hb.Time = time.Now()
+
+	if err := h.store.SaveHeartbeat(ctx, hb); err != nil {
+		h.logger.Warn("failed to save heartbeat",
+			zap.String("workflow_id", hb.WorkflowID), zap.String("run_id", hb.RunID), zap.Error(err))
+		ReportSinkError(ctx, "heartbeat", err)
+	}
+}
+
+// stop stops the periodic heartbeat and saves the final heartbeat with the given status
+func (h *heartbeater) stop(ctx context.Context, status Status) {
+	close(h.stopCh)
+	h.wg.Wait()
+	h.save(ctx, status)
+}
+
+// Expired returns true if the run is still in-flight as per the heartbeat and the heartbeat is older than the ttl
+// An expired heartbeat means that the process executing the run has most likely crashed, i.e. it is a zombie run.
+func (hb Heartbeat) Expired(ttl time.Duration, now time.Time) bool {
+	return hb.Status == StatusUndefined && now.Sub(hb.Time) > ttl
+}
+
+// LeaseStore is a HeartbeatStore with lease semantics allowing a process to take over a zombie run
+type LeaseStore interface {
+	HeartbeatStore
+
+	// TakeOver atomically refreshes the heartbeat of the run if it is expired as per Heartbeat.Expired
+	// It returns the expired heartbeat and true if the lease is acquired, so that only one process takes over the run.
+	TakeOver(ctx context.Context, runID string, ttl time.Duration, now time.Time) (Heartbeat, bool, error)
+}
+
+// TakeOver implements LeaseStore interface
+func (s *InMemHeartbeatStore) TakeOver(ctx context.Context, runID string, ttl time.Duration, now time.Time) (Heartbeat, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hb, ok := s.heartbeats[runID]
+	if !ok || !hb.Expired(ttl, now) {
+		return Heartbeat{}, false, nil
+	}
+
+	refreshed := hb
+	refreshed.Time = now
+	s.heartbeats[runID] = refreshed
+
+	return hb, true, nil
+}
@@ -0,0 +1,49 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWorkflow_WithHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemHeartbeatStore()
+
+	var runID string
+	var inFlight Heartbeat
+	migrate := &Step{ID: "migrate_db"}
+	migrate.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		runID, _ = RunIdFromContext(ctx)
+		assert.Eventually(t, func() bool {
+			hb, ok, _ := store.LoadHeartbeat(ctx, runID)
+			if ok && hb.Time.After(hb.StartTime.Add(10*time.Millisecond)) {
+				inFlight = hb
+				return true
+			}
+			return false
+		}, time.Second, 5*time.Millisecond)
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(migrate), WithHeartbeat(store, 5*time.Millisecond))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "workflow_1", inFlight.WorkflowID)
+	assert.Equal(t, report.RunID, inFlight.RunID)
+	assert.Equal(t, "migrate_db", inFlight.CurrentStep)
+	assert.Equal(t, RunAction, inFlight.CurrentAction)
+	assert.Equal(t, StatusUndefined, inFlight.Status)
+
+	final, ok, err := store.LoadHeartbeat(ctx, runID)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, StatusSuccess, final.Status)
+	assert.False(t, final.Time.Before(inFlight.Time))
+
+	_, ok, err = store.LoadHeartbeat(ctx, "unknown")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,89 @@
+package automa
+
+// MessageCatalog defines the hook to localize user facing texts of steps
+// The display name and description of a step are used as message keys. If the catalog has no message for a key, the
+// key itself is used as the text.
+type MessageCatalog interface {
+	Message(key string) (string, bool)
+}
+
+// MapCatalog is a simple MessageCatalog backed by a map of message key to localized text
+type MapCatalog map[string]string
+
+// Message implements MessageCatalog interface
+func (c MapCatalog) Message(key string) (string, bool) {
+	msg, ok := c[key]
+	return msg, ok
+}
+
+// StepPresenter defines the methods to get user facing texts of a step
+// Step implements it and the texts can be set using Step.WithDisplayName and Step.WithDescription.
+type StepPresenter interface {
+	GetDisplayName() string
+	GetDescription() string
+}
+
+// WithDisplayName sets the user facing name of the step, or a message key if a MessageCatalog is used
+func (s *Step) WithDisplayName(name string) *Step {
+	s.displayName = name
+
+	return s
+}
+
+// WithDescription sets the user facing description of the step, or a message key if a MessageCatalog is used
+func (s *Step) WithDescription(description string) *Step {
+	s.description = description
+
+	return s
+}
+
+// GetDisplayName returns the display name of the step
+// It returns the step ID if no display name is set.
+func (s *Step) GetDisplayName() string {
+	if s.displayName == "" {
+		return s.GetID()
+	}
+
+	return s.displayName
+}
+
+// GetDescription returns the description of the step
+func (s *Step) GetDescription() string {
+	return s.description
+}
+
+// WithMessageCatalog allows Workflow to be initialized with a MessageCatalog to localize step texts
+func WithMessageCatalog(catalog MessageCatalog) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.catalog = catalog
+	}
+}
+
+// StepText returns the localized display name and description of the step with the given ID
+// It returns the step ID as display name if the step doesn't implement StepPresenter or is not part of the Workflow.
+func (wf *Workflow) StepText(stepID string) (displayName string, description string) {
+	for _, step := range wf.steps {
+		if step.GetID() != stepID {
+			continue
+		}
+
+		if p, ok := step.(StepPresenter); ok {
+			return wf.localize(p.GetDisplayName()), wf.localize(p.GetDescription())
+		}
+	}
+
+	return stepID, ""
+}
+
+// localize returns the message for the key from the MessageCatalog, if any, otherwise the key itself
+func (wf *Workflow) localize(key string) string {
+	if wf.catalog == nil || key == "" {
+		return key
+	}
+
+	if msg, ok := wf.catalog.Message(key); ok {
+		return msg
+	}
+
+	return key
+}
@@ -0,0 +1,41 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWorkflow_StepText(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "install_kind"}
+	s1.WithDisplayName("step.install_kind.name").WithDescription("step.install_kind.description")
+	s2 := &Step{ID: "create_cluster"}
+	assert.Equal(t, "create_cluster", s2.GetDisplayName())
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	name, desc := workflow.StepText("install_kind")
+	assert.Equal(t, "step.install_kind.name", name)
+	assert.Equal(t, "step.install_kind.description", desc)
+
+	workflow = NewWorkflow("workflow_1", WithSteps(s1, s2), WithMessageCatalog(MapCatalog{
+		"step.install_kind.name":        "Kind installieren",
+		"step.install_kind.description": "Installiert das kind Werkzeug",
+	}))
+	name, desc = workflow.StepText("install_kind")
+	assert.Equal(t, "Kind installieren", name)
+	assert.Equal(t, "Installiert das kind Werkzeug", desc)
+
+	name, desc = workflow.StepText("create_cluster")
+	assert.Equal(t, "create_cluster", name)
+	assert.Equal(t, "", desc)
+
+	name, _ = workflow.StepText("INVALID")
+	assert.Equal(t, "INVALID", name)
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "install_kind", report.StepReports[0].StepID)
+	assert.Equal(t, "Kind installieren", report.StepReports[0].DisplayName)
+}
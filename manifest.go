@@ -0,0 +1,325 @@
+package automa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// StepManifest defines the canonical description of a step
+type StepManifest struct {
+	ID            string            `yaml:"id" json:"id"`
+	Version       string            `yaml:"version,omitempty" json:"version,omitempty"`
+	Parameters    map[string]string `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Severity      Severity          `yaml:"severity,omitempty" json:"severity,omitempty"`
+	ExecutionMode ExecutionMode     `yaml:"execution_mode,omitempty" json:"executionMode,omitempty"`
+	RollbackMode  RollbackMode      `yaml:"rollback_mode,omitempty" json:"rollbackMode,omitempty"`
+	Memoize       bool              `yaml:"memoize,omitempty" json:"memoize,omitempty"`
+	MaxAttempts   int               `yaml:"max_attempts,omitempty" json:"maxAttempts,omitempty"`
+	Timeout       time.Duration     `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Destructive denotes that the step makes destructive changes, see Step.WithDestructive
+	Destructive             bool   `yaml:"destructive,omitempty" json:"destructive,omitempty"`
+	NoRollbackJustification string `yaml:"no_rollback_justification,omitempty" json:"noRollbackJustification,omitempty"`
+
+	// Outputs and Inputs are the typed outputs and inputs of the step, see DeclareOutput and RequireInput
+	Outputs []StepIO `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+	Inputs  []StepIO `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+
+	// Priority is the scheduling priority of the step within a ParallelGroup, see Step.WithPriority
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// MaxConcurrency and Members describe a ParallelGroup, see ParallelGroup.Describe
+	MaxConcurrency int            `yaml:"max_concurrency,omitempty" json:"maxConcurrency,omitempty"`
+	Members        []StepManifest `yaml:"members,omitempty" json:"members,omitempty"`
+}
+
+// StepDescriber is an optional interface for steps to describe themselves in a WorkflowManifest
+// Step implements it with the settings of the engine. Step implementations may override Describe in order to add
+// their version and parameters.
+type StepDescriber interface {
+	Describe() StepManifest
+}
+
+// WorkflowManifest defines the canonical description of a workflow definition
+// Two workflows with the same manifest hash are expected to behave the same for the same inputs.
+type WorkflowManifest struct {
+	WorkflowID      string           `yaml:"workflow_id" json:"workflowID"`
+	ExecutionMode   ExecutionMode    `yaml:"execution_mode" json:"executionMode"`
+	RollbackMode    RollbackMode     `yaml:"rollback_mode" json:"rollbackMode"`
+	NilReportPolicy NilReportPolicy  `yaml:"nil_report_policy" json:"nilReportPolicy"`
+	CancelBehavior  CancelBehavior   `yaml:"cancel_behavior,omitempty" json:"cancelBehavior,omitempty"`
+	Timeout         time.Duration    `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	MaxAttempts     int              `yaml:"max_attempts,omitempty" json:"maxAttempts,omitempty"`
+	Features        map[Feature]bool `yaml:"features,omitempty" json:"features,omitempty"`
+	Quarantine      []string         `yaml:"quarantine,omitempty" json:"quarantine,omitempty"`
+	Phases          []PhaseManifest  `yaml:"phases,omitempty" json:"phases,omitempty"`
+	Steps           []StepManifest   `yaml:"steps" json:"steps"`
+}
+
+// PhaseManifest defines the canonical description of a Phase
+type PhaseManifest struct {
+	Name         string       `yaml:"name" json:"name"`
+	RollbackMode RollbackMode `yaml:"rollback_mode,omitempty" json:"rollbackMode,omitempty"`
+	OnStart      bool         `yaml:"on_start,omitempty" json:"onStart,omitempty"`
+	OnEnd        bool         `yaml:"on_end,omitempty" json:"onEnd,omitempty"`
+	Steps        StepIDs      `yaml:"steps" json:"steps"`
+}
+
+// Hash returns the hex encoded SHA-256 hash of the canonical JSON representation of the manifest
+func (m WorkflowManifest) Hash() string {
+	// marshalling cannot fail since the manifest contains only strings, numbers, bools, maps with string keys and slices
+	// of structs of those
+	// map keys are sorted by encoding/json which keeps the representation canonical
+	b, _ := json.Marshal(m)
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Describe implements StepDescriber interface
+func (s *Step) Describe() StepManifest {
+	m := StepManifest{
+		ID:            s.GetID(),
+		Severity:      s.GetSeverity(),
+		ExecutionMode: s.executionMode,
+		RollbackMode:  s.rollbackMode,
+		Memoize:       s.memoize,
+		Timeout:       s.timeout,
+		Priority:      s.priority,
+
+		Destructive:             s.destructive,
+		NoRollbackJustification: s.noRollbackJustification,
+
+		Outputs: append([]StepIO(nil), s.outputs...),
+		Inputs:  append([]StepIO(nil), s.inputs...),
+	}
+
+	if s.retryPolicy != nil {
+		m.MaxAttempts = s.retryPolicy.MaxAttempts
+	}
+
+	return m
+}
+
+// Manifest returns the WorkflowManifest of the Workflow
+// Steps that don't implement StepDescriber are described by their ID only.
+func (wf *Workflow) Manifest() WorkflowManifest {
+	m := WorkflowManifest{
+		WorkflowID:      wf.id,
+		ExecutionMode:   wf.executionMode,
+		RollbackMode:    wf.rollbackMode,
+		NilReportPolicy: wf.nilReportPolicy,
+		CancelBehavior:  wf.cancelBehavior,
+		Timeout:         wf.timeout,
+		Steps:           []StepManifest{},
+	}
+
+	if wf.retryPolicy != nil {
+		m.MaxAttempts = wf.retryPolicy.MaxAttempts
+	}
+
+	if len(wf.features) > 0 {
+		m.Features = map[Feature]bool{}
+		for feature, enabled := range wf.features {
+			m.Features[feature] = enabled
+		}
+	}
+
+	if wf.quarantine != nil {
+		for id := range wf.quarantine.ids {
+			m.Quarantine = append(m.Quarantine, id)
+		}
+		sort.Strings(m.Quarantine)
+	}
+
+	seenPhases := map[*Phase]bool{}
+	for _, step := range wf.steps {
+		if p, ok := wf.stepPhases[step.GetID()]; ok && !seenPhases[p] {
+			seenPhases[p] = true
+			m.Phases = append(m.Phases, p.manifest())
+		}
+
+		if d, ok := step.(StepDescriber); ok {
+			m.Steps = append(m.Steps, d.Describe())
+		} else {
+			m.Steps = append(m.Steps, StepManifest{ID: step.GetID()})
+		}
+	}
+
+	return m
+}
+
+// FieldChange defines the change of a field between two manifests
+type FieldChange struct {
+	Field string `yaml:"field" json:"field"`
+	Old   string `yaml:"old" json:"old"`
+	New   string `yaml:"new" json:"new"`
+}
+
+// StepChange defines the changes of a step present in both manifests
+type StepChange struct {
+	StepID  string        `yaml:"step_id" json:"stepID"`
+	Changes []FieldChange `yaml:"changes" json:"changes"`
+}
+
+// ManifestDiff defines the differences between two WorkflowManifest
+type ManifestDiff struct {
+	OldHash         string        `yaml:"old_hash" json:"oldHash"`
+	NewHash         string        `yaml:"new_hash" json:"newHash"`
+	WorkflowChanges []FieldChange `yaml:"workflow_changes,omitempty" json:"workflowChanges,omitempty"`
+	AddedSteps      StepIDs       `yaml:"added_steps,omitempty" json:"addedSteps,omitempty"`
+	RemovedSteps    StepIDs       `yaml:"removed_steps,omitempty" json:"removedSteps,omitempty"`
+	ChangedSteps    []StepChange  `yaml:"changed_steps,omitempty" json:"changedSteps,omitempty"`
+}
+
+// IsEmpty returns true if there is no difference between the manifests
+func (d ManifestDiff) IsEmpty() bool {
+	return d.OldHash == d.NewHash
+}
+
+// DiffManifests returns the differences between the old and the new manifest
+// Steps are matched by their ID. Reordering of steps is reported as a change of the "step_sequence" workflow field.
+func DiffManifests(old WorkflowManifest, new WorkflowManifest) ManifestDiff {
+	d := ManifestDiff{OldHash: old.Hash(), NewHash: new.Hash()}
+
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "workflow_id", old.WorkflowID, new.WorkflowID)
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "execution_mode", string(old.ExecutionMode), string(new.ExecutionMode))
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "rollback_mode", string(old.RollbackMode), string(new.RollbackMode))
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "nil_report_policy", string(old.NilReportPolicy), string(new.NilReportPolicy))
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "cancel_behavior", string(old.CancelBehavior), string(new.CancelBehavior))
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "timeout", old.Timeout.String(), new.Timeout.String())
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "max_attempts", fmt.Sprint(old.MaxAttempts), fmt.Sprint(new.MaxAttempts))
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "features", formatFeatures(old.Features), formatFeatures(new.Features))
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "quarantine", fmt.Sprint(old.Quarantine), fmt.Sprint(new.Quarantine))
+	d.WorkflowChanges = appendChange(d.WorkflowChanges, "phases", formatPhases(old.Phases), formatPhases(new.Phases))
+
+	oldSteps := map[string]StepManifest{}
+	var oldSequence, newSequence StepIDs
+	for _, step := range old.Steps {
+		oldSteps[step.ID] = step
+		oldSequence = append(oldSequence, step.ID)
+	}
+
+	newSteps := map[string]StepManifest{}
+	for _, step := range new.Steps {
+		newSteps[step.ID] = step
+		newSequence = append(newSequence, step.ID)
+
+		oldStep, ok := oldSteps[step.ID]
+		if !ok {
+			d.AddedSteps = append(d.AddedSteps, step.ID)
+			continue
+		}
+
+		if changes := diffSteps(oldStep, step); len(changes) > 0 {
+			d.ChangedSteps = append(d.ChangedSteps, StepChange{StepID: step.ID, Changes: changes})
+		}
+	}
+
+	for _, step := range old.Steps {
+		if _, ok := newSteps[step.ID]; !ok {
+			d.RemovedSteps = append(d.RemovedSteps, step.ID)
+		}
+	}
+
+	if len(d.AddedSteps) == 0 && len(d.RemovedSteps) == 0 {
+		d.WorkflowChanges = appendChange(d.WorkflowChanges, "step_sequence", fmt.Sprint(oldSequence), fmt.Sprint(newSequence))
+	}
+
+	return d
+}
+
+// diffSteps returns the changes between two manifests of the same step
+func diffSteps(old StepManifest, new StepManifest) []FieldChange {
+	var changes []FieldChange
+	changes = appendChange(changes, "version", old.Version, new.Version)
+	changes = appendChange(changes, "severity", string(old.Severity), string(new.Severity))
+	changes = appendChange(changes, "execution_mode", string(old.ExecutionMode), string(new.ExecutionMode))
+	changes = appendChange(changes, "rollback_mode", string(old.RollbackMode), string(new.RollbackMode))
+	changes = appendChange(changes, "memoize", fmt.Sprint(old.Memoize), fmt.Sprint(new.Memoize))
+	changes = appendChange(changes, "max_attempts", fmt.Sprint(old.MaxAttempts), fmt.Sprint(new.MaxAttempts))
+	changes = appendChange(changes, "timeout", old.Timeout.String(), new.Timeout.String())
+	changes = appendChange(changes, "destructive", fmt.Sprint(old.Destructive), fmt.Sprint(new.Destructive))
+	changes = appendChange(changes, "no_rollback_justification", old.NoRollbackJustification, new.NoRollbackJustification)
+	changes = appendChange(changes, "outputs", formatStepIO(old.Outputs), formatStepIO(new.Outputs))
+	changes = appendChange(changes, "inputs", formatStepIO(old.Inputs), formatStepIO(new.Inputs))
+	changes = appendChange(changes, "priority", fmt.Sprint(old.Priority), fmt.Sprint(new.Priority))
+	changes = appendChange(changes, "max_concurrency", fmt.Sprint(old.MaxConcurrency), fmt.Sprint(new.MaxConcurrency))
+	changes = appendChange(changes, "members", formatMembers(old.Members), formatMembers(new.Members))
+
+	keys := map[string]bool{}
+	for key := range old.Parameters {
+		keys[key] = true
+	}
+	for key := range new.Parameters {
+		keys[key] = true
+	}
+
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, key := range sortedKeys {
+		changes = appendChange(changes, "parameters."+key, old.Parameters[key], new.Parameters[key])
+	}
+
+	return changes
+}
+
+// formatStepIO returns the typed outputs or inputs as a list of key:type
+func formatStepIO(ios []StepIO) string {
+	s := make([]string, 0, len(ios))
+	for _, sio := range ios {
+		s = append(s, sio.Key+":"+sio.Type)
+	}
+
+	return fmt.Sprint(s)
+}
+
+// formatFeatures returns the toggled features as a list of feature=enabled sorted by feature
+func formatFeatures(features map[Feature]bool) string {
+	s := make([]string, 0, len(features))
+	for feature, enabled := range features {
+		s = append(s, fmt.Sprintf("%s=%t", feature, enabled))
+	}
+	sort.Strings(s)
+
+	return fmt.Sprint(s)
+}
+
+// formatPhases returns the phases as a list of their canonical JSON representation
+func formatPhases(phases []PhaseManifest) string {
+	s := make([]string, 0, len(phases))
+	for _, p := range phases {
+		b, _ := json.Marshal(p)
+		s = append(s, string(b))
+	}
+
+	return fmt.Sprint(s)
+}
+
+// formatMembers returns the members of a ParallelGroup as a list of their canonical JSON representation
+func formatMembers(members []StepManifest) string {
+	s := make([]string, 0, len(members))
+	for _, m := range members {
+		b, _ := json.Marshal(m)
+		s = append(s, string(b))
+	}
+
+	return fmt.Sprint(s)
+}
+
+// appendChange appends a FieldChange if the old and new values are different
+func appendChange(changes []FieldChange, field string, old string, new string) []FieldChange {
+	if old == new {
+		return changes
+	}
+
+	return append(changes, FieldChange{Field: field, Old: old, New: new})
+}
@@ -0,0 +1,184 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// mockVersionedStep is an example of a step that adds its version and parameters to the manifest
+type mockVersionedStep struct {
+	Step
+	version string
+}
+
+func (s *mockVersionedStep) Describe() StepManifest {
+	m := s.Step.Describe()
+	m.Version = s.version
+	m.Parameters = map[string]string{"image": "nginx"}
+	return m
+}
+
+func TestWorkflow_Manifest(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "step_1"}
+	s1.WithSeverity(SeverityWarning)
+	s2 := &mockVersionedStep{Step: Step{ID: "step_2"}, version: "v1.0.0"}
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2), WithQuarantine("step_2", "step_1"))
+	m := workflow.Manifest()
+	assert.Equal(t, "workflow_1", m.WorkflowID)
+	assert.Equal(t, StopOnError, m.ExecutionMode)
+	assert.Equal(t, []string{"step_1", "step_2"}, m.Quarantine)
+	assert.Equal(t, 2, len(m.Steps))
+	assert.Equal(t, SeverityWarning, m.Steps[0].Severity)
+	assert.Equal(t, "v1.0.0", m.Steps[1].Version)
+
+	// hash is stable and changes with the definition
+	hash := m.Hash()
+	assert.Equal(t, hash, workflow.Manifest().Hash())
+	s2.version = "v1.1.0"
+	assert.NotEqual(t, hash, workflow.Manifest().Hash())
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, workflow.Manifest().Hash(), report.ManifestHash)
+}
+
+func TestDiffManifests(t *testing.T) {
+	old := WorkflowManifest{
+		WorkflowID:    "workflow_1",
+		ExecutionMode: StopOnError,
+		Steps: []StepManifest{
+			{ID: "step_1", Version: "v1.0.0", Parameters: map[string]string{"image": "nginx", "tag": "1.0"}},
+			{ID: "step_2"},
+		},
+	}
+	new := WorkflowManifest{
+		WorkflowID:    "workflow_1",
+		ExecutionMode: CompensateAndContinue,
+		Steps: []StepManifest{
+			{ID: "step_1", Version: "v1.1.0", Parameters: map[string]string{"image": "nginx", "port": "80"}},
+			{ID: "step_3"},
+		},
+	}
+
+	d := DiffManifests(old, old)
+	assert.True(t, d.IsEmpty())
+	assert.Empty(t, d.WorkflowChanges)
+	assert.Empty(t, d.ChangedSteps)
+
+	d = DiffManifests(old, new)
+	assert.False(t, d.IsEmpty())
+	assert.Equal(t, []FieldChange{{Field: "execution_mode", Old: "stop_on_error", New: "compensate_and_continue"}}, d.WorkflowChanges)
+	assert.Equal(t, StepIDs{"step_3"}, d.AddedSteps)
+	assert.Equal(t, StepIDs{"step_2"}, d.RemovedSteps)
+	assert.Equal(t, []StepChange{{StepID: "step_1", Changes: []FieldChange{
+		{Field: "version", Old: "v1.0.0", New: "v1.1.0"},
+		{Field: "parameters.port", Old: "", New: "80"},
+		{Field: "parameters.tag", Old: "1.0", New: ""},
+	}}}, d.ChangedSteps)
+
+	// reordering of steps
+	reordered := old
+	reordered.Steps = []StepManifest{old.Steps[1], old.Steps[0]}
+	d = DiffManifests(old, reordered)
+	assert.Equal(t, "step_sequence", d.WorkflowChanges[0].Field)
+}
+
+func TestDiffManifests_AllFields(t *testing.T) {
+	newWorkflow := func(s *Step, opts ...WorkflowOption) *Workflow {
+		return NewWorkflow("workflow_1", append([]WorkflowOption{WithPhase(NewPhase("install", s))}, opts...)...)
+	}
+
+	s1 := &Step{ID: "step_1"}
+	old := newWorkflow(s1).Manifest()
+
+	// every change of the hash is listed in the diff
+	changes := []func(){
+		func() { DeclareOutput[string](s1, "version") },
+		func() { RequireInput[int](s1, "replicas") },
+		func() { s1.WithNoRollbackJustification("bucket is versioned") },
+		func() { s1.WithTimeout(time.Minute) },
+	}
+	for _, change := range changes {
+		change()
+		m := newWorkflow(s1).Manifest()
+		d := DiffManifests(old, m)
+		assert.False(t, d.IsEmpty())
+		assert.Equal(t, 1, len(d.ChangedSteps))
+		assert.Equal(t, 1, len(d.ChangedSteps[0].Changes))
+		old = m
+	}
+
+	opts := []WorkflowOption{
+		WithTimeout(time.Hour),
+		WithCancelBehavior(StopOnCancel),
+		WithRetryPolicy(3, nil),
+		WithFeatures(map[Feature]bool{FeatureParallelExecution: false}),
+	}
+	for i, opt := range opts {
+		d := DiffManifests(old, newWorkflow(s1, opt).Manifest())
+		assert.False(t, d.IsEmpty(), i)
+		assert.Equal(t, 1, len(d.WorkflowChanges), i)
+	}
+
+	phases := NewWorkflow("workflow_1", WithPhase(NewPhase("install", s1).WithRollbackMode(StopOnRollbackError))).Manifest()
+	d := DiffManifests(old, phases)
+	assert.False(t, d.IsEmpty())
+	assert.Equal(t, "phases", d.WorkflowChanges[0].Field)
+}
+
+func TestWorkflow_ManifestDiff(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "step_1"}
+	workflow := NewWorkflow("workflow_1", WithSteps(s1))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, report.ManifestDiff)
+
+	prev := workflow.Manifest()
+	s1.WithSeverity(SeverityWarning)
+	report, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, report.ManifestDiff)
+	assert.Equal(t, "step_1", report.ManifestDiff.ChangedSteps[0].StepID)
+
+	// diff is only embedded in the first report after the change
+	report, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, report.ManifestDiff)
+
+	workflow = NewWorkflow("workflow_1", WithSteps(s1), WithPreviousManifest(prev))
+	report, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, report.ManifestDiff)
+}
+
+func TestParallelGroup_Describe(t *testing.T) {
+	helm := &mockVersionedStep{Step: Step{ID: "install_helm"}, version: "v1.0.0"}
+	jq := &Step{ID: "install_jq"}
+	group := NewParallelGroup("install_tools", helm, jq).WithMaxConcurrency(2)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(group))
+	old := workflow.Manifest()
+	assert.Equal(t, 2, old.Steps[0].MaxConcurrency)
+	assert.Equal(t, 2, len(old.Steps[0].Members))
+	assert.Equal(t, "v1.0.0", old.Steps[0].Members[0].Version)
+
+	// changing a member changes the hash and the diff of the group
+	helm.version = "v1.1.0"
+	jq.WithPriority(10)
+	d := DiffManifests(old, workflow.Manifest())
+	assert.False(t, d.IsEmpty())
+	assert.Equal(t, 1, len(d.ChangedSteps))
+	assert.Equal(t, "install_tools", d.ChangedSteps[0].StepID)
+	assert.Equal(t, "members", d.ChangedSteps[0].Changes[0].Field)
+
+	group.WithMaxConcurrency(1)
+	d = DiffManifests(old, workflow.Manifest())
+	assert.Equal(t, "max_concurrency", d.ChangedSteps[0].Changes[0].Field)
+}
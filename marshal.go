@@ -0,0 +1,308 @@
+package automa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/cockroachdb/errors"
+	"github.com/gogo/protobuf/jsonpb"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reasonKey is the key of the FailureReason field in the serialized reports
+const reasonKey = "reason"
+
+// encodeReason returns the protobuf JSON representation of the failure reason, or nil if there is no failure
+// The default encoding of errors.EncodedError cannot be decoded back since the error is a protobuf oneof field.
+func encodeReason(reason errors.EncodedError) (json.RawMessage, error) {
+	if reason.Error == nil {
+		return json.RawMessage("null"), nil
+	}
+
+	var buf bytes.Buffer
+	m := jsonpb.Marshaler{OrigName: true}
+	if err := m.Marshal(&buf, &reason); err != nil {
+		return nil, errors.Wrap(err, "failed to encode failure reason")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeReason returns the failure reason from its protobuf JSON representation
+func decodeReason(raw json.RawMessage) (errors.EncodedError, error) {
+	var reason errors.EncodedError
+	if len(raw) == 0 || string(raw) == "null" {
+		return reason, nil
+	}
+
+	u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err := u.Unmarshal(bytes.NewReader(raw), &reason); err != nil {
+		return reason, errors.Wrap(err, "failed to decode failure reason")
+	}
+
+	return reason, nil
+}
+
+// marshalJSONWithReason marshals v as JSON with the failure reason in its protobuf JSON representation
+// The FailureReason of v is expected to be empty since it is replaced by reason.
+func marshalJSONWithReason(v interface{}, reason errors.EncodedError) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+
+	if fields[reasonKey], err = encodeReason(reason); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fields)
+}
+
+// unmarshalJSONWithReason unmarshals the JSON into v and the failure reason from its protobuf JSON representation
+func unmarshalJSONWithReason(b []byte, v interface{}, reason *errors.EncodedError) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	raw := fields[reasonKey]
+	delete(fields, reasonKey)
+
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(rest, v); err != nil {
+		return err
+	}
+
+	*reason, err = decodeReason(raw)
+
+	return err
+}
+
+// marshalYAMLWithReason returns the YAML node of v with the failure reason in its protobuf JSON representation
+// The FailureReason of v is expected to be empty since its default YAML encoding cannot be parsed back.
+func marshalYAMLWithReason(v interface{}, reason errors.EncodedError) (interface{}, error) {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+
+	raw, err := encodeReason(reason)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+
+	var value interface{}
+	if err = d.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == reasonKey {
+			node.Content[i+1] = jsonValueNode(value)
+		}
+	}
+
+	return &node, nil
+}
+
+// jsonValueNode returns the YAML node of a value decoded from JSON
+// Strings spanning multiple lines such as stack traces are double-quoted since yaml.v3 does not always emit them in a
+// block style that can be parsed back.
+func jsonValueNode(value interface{}) *yaml.Node {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			node.Content = append(node.Content, jsonValueNode(k), jsonValueNode(v[k]))
+		}
+
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, jsonValueNode(item))
+		}
+
+		return node
+	case string:
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+		if strings.ContainsAny(v, "\n\t") {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+
+		return node
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: v.String()}
+		}
+
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+}
+
+// unmarshalYAMLWithReason decodes the YAML node into v and the failure reason from its protobuf JSON representation
+func unmarshalYAMLWithReason(node *yaml.Node, v interface{}, reason *errors.EncodedError) error {
+	rest := *node
+	rest.Content = nil
+
+	var reasonNode *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == reasonKey {
+			reasonNode = node.Content[i+1]
+			continue
+		}
+
+		rest.Content = append(rest.Content, node.Content[i], node.Content[i+1])
+	}
+
+	if err := rest.Decode(v); err != nil {
+		return err
+	}
+
+	*reason = errors.EncodedError{}
+	if reasonNode == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := reasonNode.Decode(&value); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	*reason, err = decodeReason(raw)
+
+	return err
+}
+
+// MarshalJSON implements json.Marshaler interface for StepReport
+func (sr StepReport) MarshalJSON() ([]byte, error) {
+	type alias StepReport
+	v := alias(sr)
+	v.FailureReason = errors.EncodedError{}
+
+	return marshalJSONWithReason(v, sr.FailureReason)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface for StepReport
+// Values in Extra are decoded as generic JSON values, e.g. float64 for numbers.
+func (sr *StepReport) UnmarshalJSON(b []byte) error {
+	type alias StepReport
+	return unmarshalJSONWithReason(b, (*alias)(sr), &sr.FailureReason)
+}
+
+// MarshalYAML implements yaml.Marshaler interface for StepReport
+func (sr StepReport) MarshalYAML() (interface{}, error) {
+	type alias StepReport
+	v := alias(sr)
+	v.FailureReason = errors.EncodedError{}
+
+	return marshalYAMLWithReason(v, sr.FailureReason)
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler interface for StepReport
+func (sr *StepReport) UnmarshalYAML(node *yaml.Node) error {
+	type alias StepReport
+	return unmarshalYAMLWithReason(node, (*alias)(sr), &sr.FailureReason)
+}
+
+// FailureError returns the error reconstructed from the FailureReason, or nil if the step action didn't fail
+// The message and the chain of the error are preserved, while the Go types of errors that are not registered with
+// github.com/cockroachdb/errors are replaced with opaque types.
+func (sr *StepReport) FailureError(ctx context.Context) error {
+	if sr.FailureReason.Error == nil {
+		return nil
+	}
+
+	return errors.DecodeError(ctx, sr.FailureReason)
+}
+
+// MarshalJSON implements json.Marshaler interface for CallbackFailure
+func (cf CallbackFailure) MarshalJSON() ([]byte, error) {
+	type alias CallbackFailure
+	v := alias(cf)
+	v.FailureReason = errors.EncodedError{}
+
+	return marshalJSONWithReason(v, cf.FailureReason)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface for CallbackFailure
+func (cf *CallbackFailure) UnmarshalJSON(b []byte) error {
+	type alias CallbackFailure
+	return unmarshalJSONWithReason(b, (*alias)(cf), &cf.FailureReason)
+}
+
+// MarshalYAML implements yaml.Marshaler interface for CallbackFailure
+func (cf CallbackFailure) MarshalYAML() (interface{}, error) {
+	type alias CallbackFailure
+	v := alias(cf)
+	v.FailureReason = errors.EncodedError{}
+
+	return marshalYAMLWithReason(v, cf.FailureReason)
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler interface for CallbackFailure
+func (cf *CallbackFailure) UnmarshalYAML(node *yaml.Node) error {
+	type alias CallbackFailure
+	return unmarshalYAMLWithReason(node, (*alias)(cf), &cf.FailureReason)
+}
+
+// MarshalJSON implements json.Marshaler interface for ResourceCleanup
+func (rc ResourceCleanup) MarshalJSON() ([]byte, error) {
+	type alias ResourceCleanup
+	v := alias(rc)
+	v.FailureReason = errors.EncodedError{}
+
+	return marshalJSONWithReason(v, rc.FailureReason)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface for ResourceCleanup
+func (rc *ResourceCleanup) UnmarshalJSON(b []byte) error {
+	type alias ResourceCleanup
+	return unmarshalJSONWithReason(b, (*alias)(rc), &rc.FailureReason)
+}
+
+// MarshalYAML implements yaml.Marshaler interface for ResourceCleanup
+func (rc ResourceCleanup) MarshalYAML() (interface{}, error) {
+	type alias ResourceCleanup
+	v := alias(rc)
+	v.FailureReason = errors.EncodedError{}
+
+	return marshalYAMLWithReason(v, rc.FailureReason)
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler interface for ResourceCleanup
+func (rc *ResourceCleanup) UnmarshalYAML(node *yaml.Node) error {
+	type alias ResourceCleanup
+	return unmarshalYAMLWithReason(node, (*alias)(rc), &rc.FailureReason)
+}
@@ -0,0 +1,91 @@
+package automa
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func newFailedReport(ctx context.Context) *WorkflowReport {
+	stepReport1Run := NewStepReport("step-1", RunAction)
+	stepReport1Run.Outputs["version"] = []byte("v1.0.0")
+	stepReport2Run := NewStepReport("step-2", RunAction)
+	stepReport2Run.FailureReason = errors.EncodeError(ctx, errors.Wrap(errors.New("disk full"), "copy failed"))
+	stepReport2Run.Attempts = 2
+	stepReport2Run.AttemptErrors = []string{"timeout"}
+	stepReport1Rollback := NewStepReport("step-1", RollbackAction)
+
+	workflowReport := NewWorkflowReport("workflow-id", StepIDs{"step-1", "step-2"})
+	workflowReport.Append(stepReport1Run, RunAction, StatusSuccess)
+	workflowReport.Append(stepReport2Run, RunAction, StatusFailed)
+	workflowReport.Append(stepReport1Rollback, RollbackAction, StatusSuccess)
+	workflowReport.Status = StatusFailed
+	workflowReport.CallbackFailures = append(workflowReport.CallbackFailures, &CallbackFailure{
+		FailureReason: errors.EncodeError(ctx, errors.New("webhook unreachable")),
+	})
+
+	return workflowReport
+}
+
+func assertRoundTrip(t *testing.T, ctx context.Context, expected *WorkflowReport, actual *WorkflowReport) {
+	assert.Equal(t, expected.WorkflowID, actual.WorkflowID)
+	assert.Equal(t, expected.Status, actual.Status)
+	assert.Equal(t, 3, len(actual.StepReports))
+	for i, stepReport := range expected.StepReports {
+		assert.Equal(t, stepReport.StepID, actual.StepReports[i].StepID)
+		assert.Equal(t, stepReport.Action, actual.StepReports[i].Action)
+		assert.Equal(t, stepReport.Status, actual.StepReports[i].Status)
+		assert.True(t, stepReport.StartTime.Equal(actual.StepReports[i].StartTime))
+	}
+
+	assert.Nil(t, actual.StepReports[0].FailureError(ctx))
+	assert.Equal(t, []byte("v1.0.0"), actual.StepReports[0].Outputs["version"])
+	assert.Nil(t, actual.StepReports[2].FailureError(ctx))
+	assert.Equal(t, RollbackAction, actual.StepReports[2].Action)
+
+	err := actual.StepReports[1].FailureError(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, "copy failed: disk full", err.Error())
+	assert.Equal(t, 2, actual.StepReports[1].Attempts)
+	assert.Equal(t, []string{"timeout"}, actual.StepReports[1].AttemptErrors)
+
+	assert.Equal(t, 1, len(actual.CallbackFailures))
+	assert.Equal(t, "webhook unreachable", errors.DecodeError(ctx, actual.CallbackFailures[0].FailureReason).Error())
+}
+
+func TestReport_JSONRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	workflowReport := newFailedReport(ctx)
+
+	out, err := json.Marshal(workflowReport)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "disk full")
+
+	var decoded WorkflowReport
+	assert.NoError(t, json.Unmarshal(out, &decoded))
+	assertRoundTrip(t, ctx, workflowReport, &decoded)
+}
+
+func TestReport_YAMLRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	workflowReport := newFailedReport(ctx)
+
+	out, err := yaml.Marshal(workflowReport)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "disk full")
+
+	var decoded WorkflowReport
+	assert.NoError(t, yaml.Unmarshal(out, &decoded))
+	assertRoundTrip(t, ctx, workflowReport, &decoded)
+}
+
+func TestStepReport_FailureError(t *testing.T) {
+	stepReport := NewStepReport("step-1", RunAction)
+	assert.Nil(t, stepReport.FailureError(context.Background()))
+
+	var decoded StepReport
+	assert.Error(t, json.Unmarshal([]byte(`{"stepID":"step-1","reason":{"unknown":1,"leaf":"x"}}`), &decoded))
+}
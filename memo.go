@@ -0,0 +1,60 @@
+package automa
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoizedMetadataKey is the Metadata key of the report of a memoized step that reused the result of an earlier
+// execution, see Step.WithMemoize
+const MemoizedMetadataKey = "memoized"
+
+// runMemo holds the reports of memoized steps executed in a workflow run including its nested workflows
+type runMemo struct {
+	mutex   sync.Mutex
+	reports map[string]*StepReport
+}
+
+// newRunMemo returns an empty runMemo
+func newRunMemo() *runMemo {
+	return &runMemo{reports: map[string]*StepReport{}}
+}
+
+// get returns a copy of the report of the step if it has already been executed successfully in the run
+func (m *runMemo) get(stepID string) *StepReport {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	report, ok := m.reports[stepID]
+	if !ok || report.Status != StatusSuccess {
+		return nil
+	}
+
+	memoized := report.Clone()
+	memoized.StartTime = time.Now()
+	memoized.Metadata[MemoizedMetadataKey] = []byte("true")
+	// the costs were incurred by the first execution only
+	memoized.Costs = nil
+
+	return memoized
+}
+
+// put stores the report of the step
+func (m *runMemo) put(stepID string, report *StepReport) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.reports[stepID] = report
+}
+
+// withRunMemo returns a copy of the context with the given runMemo
+func withRunMemo(ctx context.Context, m *runMemo) context.Context {
+	return context.WithValue(ctx, ctxKeyRunMemo, m)
+}
+
+// runMemoFromContext returns the runMemo of the current run, if any
+func runMemoFromContext(ctx context.Context) *runMemo {
+	m, _ := ctx.Value(ctxKeyRunMemo).(*runMemo)
+	return m
+}
@@ -0,0 +1,92 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStep_WithMemoize(t *testing.T) {
+	ctx := context.Background()
+
+	executions := 0
+	ensureDocker := &Step{ID: "ensure_docker_running"}
+	ensureDocker.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		executions++
+		return false, nil
+	}, nil).WithMemoize(true)
+
+	nested1 := NewWorkflow("nested_1", WithSteps(ensureDocker))
+	nested2 := NewWorkflow("nested_2", WithSteps(ensureDocker))
+
+	var nestedReports []WorkflowReport
+	runNested := func(wf *Workflow) SagaRun {
+		return func(ctx context.Context) (skipped bool, err error) {
+			report, err := wf.Start(ctx)
+			nestedReports = append(nestedReports, report)
+			return false, err
+		}
+	}
+
+	s1 := &Step{ID: "setup_1"}
+	s1.RegisterSaga(runNested(nested1), nil)
+	s2 := &Step{ID: "setup_2"}
+	s2.RegisterSaga(runNested(nested2), nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, executions)
+	assert.Equal(t, 2, len(nestedReports))
+	assert.Nil(t, nestedReports[0].StepReports[0].Metadata["memoized"])
+	assert.Equal(t, []byte("true"), nestedReports[1].StepReports[0].Metadata["memoized"])
+	assert.Equal(t, StatusSuccess, nestedReports[1].StepReports[0].Status)
+
+	// memoization is scoped to a run
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, executions)
+
+	// without memoization the step is executed every time
+	ensureDocker.WithMemoize(false)
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, executions)
+}
+
+func TestStep_WithMemoize_Rollback(t *testing.T) {
+	ctx := context.Background()
+
+	runs, undos := 0, 0
+	newEnsureDocker := func() *Step {
+		s := &Step{ID: "ensure_docker_running"}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			runs++
+			return false, PublishOutput(ctx, "socket", []byte("/var/run/docker.sock"))
+		}, func(ctx context.Context) (skipped bool, err error) {
+			undos++
+			return false, nil
+		}).WithMemoize(true)
+		return s
+	}
+
+	failing := &Step{ID: "deploy"}
+	failing.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil)
+
+	// only the occurrence that was executed is rolled back
+	workflow := NewWorkflow("workflow_1", WithSteps(newEnsureDocker(), newEnsureDocker(), failing))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, 1, undos)
+	assert.Equal(t, []byte("true"), report.StepReports[1].Metadata[MemoizedMetadataKey])
+	assert.Equal(t, StatusSkipped, report.StepReports[4].Status)
+	assert.Equal(t, StatusSuccess, report.StepReports[5].Status)
+
+	// the memoized report does not share its maps with the report of the first execution
+	report.StepReports[1].Outputs["socket"] = []byte("changed")
+	assert.Equal(t, []byte("/var/run/docker.sock"), report.StepReports[0].Outputs["socket"])
+}
@@ -0,0 +1,112 @@
+package automa
+
+import (
+	"context"
+	"sync"
+)
+
+// StateMergePolicy defines the outputs of the nested workflows that are merged back into the workflow running them
+// A nested workflow is a workflow started from the SagaRun of a step of another workflow using the context of SagaRun.
+type StateMergePolicy struct {
+	all  bool
+	keys map[string]bool
+}
+
+var (
+	// MergeNone discards the outputs of the nested workflows, which is the default
+	MergeNone = StateMergePolicy{}
+
+	// MergeGlobals merges all the outputs of the nested workflows
+	MergeGlobals = StateMergePolicy{all: true}
+)
+
+// MergeKeys returns a StateMergePolicy merging only the given outputs of the nested workflows
+func MergeKeys(keys ...string) StateMergePolicy {
+	p := StateMergePolicy{keys: map[string]bool{}}
+	for _, key := range keys {
+		p.keys[key] = true
+	}
+
+	return p
+}
+
+// selects returns true if the output is to be merged
+func (p StateMergePolicy) selects(key string) bool {
+	return p.all || p.keys[key]
+}
+
+// WithStateMergePolicy allows the Workflow to receive the outputs of the nested workflows run by its steps
+// Once a nested workflow completes with StatusSuccess or StatusPartial, its outputs selected by the policy are added
+// to the StepReport.Outputs of the RunAction of the step that started it, and therefore to WorkflowReport.Outputs. The
+// outputs published by the step itself take precedence. The outputs of a nested workflow started by a nested workflow
+// are only merged into the latter.
+func WithStateMergePolicy(policy StateMergePolicy) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.mergePolicy = &policy
+	}
+}
+
+// stateMerge collects the outputs of the nested workflows of a run per step, as per a StateMergePolicy
+type stateMerge struct {
+	mutex   sync.Mutex
+	policy  StateMergePolicy
+	outputs map[string]map[string][]byte
+}
+
+// newStateMerge returns an empty stateMerge
+func newStateMerge(policy StateMergePolicy) *stateMerge {
+	return &stateMerge{policy: policy, outputs: map[string]map[string][]byte{}}
+}
+
+// merge collects the outputs of a nested workflow started by the step
+// Outputs of later nested workflows of the same step take precedence.
+func (m *stateMerge) merge(stepID string, outputs map[string][]byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for key, val := range outputs {
+		if !m.policy.selects(key) {
+			continue
+		}
+
+		if m.outputs[stepID] == nil {
+			m.outputs[stepID] = map[string][]byte{}
+		}
+
+		m.outputs[stepID][key] = val
+	}
+}
+
+// apply adds the collected outputs to the RunAction reports of the steps that started the nested workflows
+func (m *stateMerge) apply(report *WorkflowReport) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, stepReport := range report.StepReports {
+		merged, ok := m.outputs[stepReport.StepID]
+		if !ok || stepReport.Action != RunAction {
+			continue
+		}
+
+		if stepReport.Outputs == nil {
+			stepReport.Outputs = map[string][]byte{}
+		}
+
+		for key, val := range merged {
+			if _, exists := stepReport.Outputs[key]; !exists {
+				stepReport.Outputs[key] = val
+			}
+		}
+	}
+}
+
+// withStateMerge returns a copy of the context with the given stateMerge, nil if the run doesn't merge any output
+func withStateMerge(ctx context.Context, m *stateMerge) context.Context {
+	return context.WithValue(ctx, ctxKeyStateMerge, m)
+}
+
+// stateMergeFromContext returns the stateMerge of the run of the parent workflow, if any
+func stateMergeFromContext(ctx context.Context) *stateMerge {
+	m, _ := ctx.Value(ctxKeyStateMerge).(*stateMerge)
+	return m
+}
@@ -0,0 +1,126 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// mockPublishStep is an example of a step publishing outputs
+type mockPublishStep struct {
+	Step
+	outputs map[string]string
+}
+
+func (s *mockPublishStep) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	report := NewStepReport(s.GetID(), RunAction)
+	for key, val := range s.outputs {
+		report.Outputs[key] = []byte(val)
+	}
+
+	return s.RunNext(ctx, prevSuccess, report)
+}
+
+// runNestedSaga returns a SagaRun starting the nested workflow
+func runNestedSaga(nested *Workflow) SagaRun {
+	return func(ctx context.Context) (skipped bool, err error) {
+		_, err = nested.Start(ctx)
+		return false, err
+	}
+}
+
+func newNestedWorkflow(opts ...WorkflowOption) *Workflow {
+	return NewWorkflow("nested", append([]WorkflowOption{WithSteps(&mockPublishStep{
+		Step:    Step{ID: "provision"},
+		outputs: map[string]string{"cluster": "c-1", "region": "us-east-1", "token": "s3cr3t"},
+	})}, opts...)...)
+}
+
+func TestWithStateMergePolicy(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		opts    []WorkflowOption
+		outputs map[string][]byte
+	}{
+		{
+			name:    "default",
+			outputs: map[string][]byte{},
+		},
+		{
+			name:    "none",
+			opts:    []WorkflowOption{WithStateMergePolicy(MergeNone)},
+			outputs: map[string][]byte{},
+		},
+		{
+			name: "globals",
+			opts: []WorkflowOption{WithStateMergePolicy(MergeGlobals)},
+			outputs: map[string][]byte{
+				"region":  []byte("us-east-1"),
+				"cluster": []byte("c-1"),
+				"token":   []byte("s3cr3t"),
+			},
+		},
+		{
+			name:    "keys",
+			opts:    []WorkflowOption{WithStateMergePolicy(MergeKeys("cluster", "region"))},
+			outputs: map[string][]byte{"region": []byte("us-east-1"), "cluster": []byte("c-1")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nested := newNestedWorkflow()
+			defer nested.End(ctx)
+
+			step := &Step{ID: "create_cluster"}
+			step.RegisterSaga(runNestedSaga(nested), nil)
+			workflow := NewWorkflow("parent", append([]WorkflowOption{WithSteps(step)}, test.opts...)...)
+			defer workflow.End(ctx)
+
+			report, err := workflow.Start(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, test.outputs, report.Outputs)
+			assert.Equal(t, test.outputs, report.StepReports[0].Outputs)
+			assert.Equal(t, StatusSuccess, nested.report.Status)
+		})
+	}
+}
+
+func TestWithStateMergePolicy_Grandchild(t *testing.T) {
+	ctx := context.Background()
+
+	// the outputs of the innermost workflow are not merged into the intermediate one, hence not into the parent
+	innermost := newNestedWorkflow()
+	defer innermost.End(ctx)
+	nestedStep := &Step{ID: "nested"}
+	nestedStep.RegisterSaga(runNestedSaga(innermost), nil)
+	intermediate := NewWorkflow("intermediate", WithSteps(nestedStep))
+	defer intermediate.End(ctx)
+	step := &Step{ID: "create_cluster"}
+	step.RegisterSaga(runNestedSaga(intermediate), nil)
+	workflow := NewWorkflow("parent", WithSteps(step), WithStateMergePolicy(MergeGlobals))
+	defer workflow.End(ctx)
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{}, report.Outputs)
+}
+
+func TestStateMerge_Apply(t *testing.T) {
+	m := newStateMerge(MergeKeys("cluster", "region"))
+	m.merge("step_1", map[string][]byte{"cluster": []byte("c-1"), "region": []byte("us-east-1")})
+	m.merge("step_1", map[string][]byte{"cluster": []byte("c-2"), "token": []byte("s3cr3t")})
+
+	report := NewWorkflowReport("workflow_1", StepIDs{"step_1"})
+	runReport := NewStepReport("step_1", RunAction)
+	runReport.Outputs["region"] = []byte("eu-west-1")
+	report.Append(runReport, RunAction, StatusSuccess)
+	rollbackReport := NewStepReport("step_1", RollbackAction)
+	report.Append(rollbackReport, RollbackAction, StatusSuccess)
+
+	// the outputs of the step take precedence over the outputs of its nested workflows
+	m.apply(report)
+	assert.Equal(t, map[string][]byte{"cluster": []byte("c-2"), "region": []byte("eu-west-1")}, runReport.Outputs)
+	assert.Equal(t, 0, len(rollbackReport.Outputs))
+}
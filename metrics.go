@@ -0,0 +1,86 @@
+package automa
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MetricsCollector defines the methods to record the metrics of workflow and step executions
+// Implementations must be safe for concurrent use since steps of a ParallelGroup run concurrently.
+type MetricsCollector interface {
+	// WorkflowStarted records the start of a run of the workflow
+	WorkflowStarted(workflowID string)
+
+	// WorkflowFinished records the end of a run of the workflow with its final status
+	WorkflowFinished(workflowID string, status Status, duration time.Duration)
+
+	// StepFinished records the end of a step action with its status, e.g. StatusSuccess, StatusFailed or StatusSkipped
+	StepFinished(workflowID string, stepID string, action StepActionType, status Status, duration time.Duration)
+}
+
+// metricsKey identifies a step action of a workflow run
+type metricsKey struct {
+	runID  string
+	stepID string
+	action StepActionType
+}
+
+// metricsRecorder translates the lifecycle events of the workflow runs into the calls of a MetricsCollector
+type metricsRecorder struct {
+	collector MetricsCollector
+
+	mutex  sync.Mutex
+	starts map[metricsKey]time.Time
+}
+
+// WithMetrics allows the executions of the Workflow to be recorded by the MetricsCollector, see PrometheusCollector
+func WithMetrics(collector MetricsCollector) WorkflowOption {
+	r := &metricsRecorder{collector: collector, starts: map[metricsKey]time.Time{}}
+	return WithEventListener(r.onEvent)
+}
+
+// onEvent implements EventListener for metricsRecorder
+func (r *metricsRecorder) onEvent(ctx context.Context, event Event) {
+	switch event.Type {
+	case WorkflowStarted:
+		r.start(metricsKey{runID: event.RunID}, event.Time)
+		r.collector.WorkflowStarted(event.WorkflowID)
+	case WorkflowFinished:
+		r.collector.WorkflowFinished(event.WorkflowID, event.Status, r.elapsed(metricsKey{runID: event.RunID}, event.Time))
+	case StepStarted:
+		r.start(metricsKey{runID: event.RunID, stepID: event.StepID, action: RunAction}, event.Time)
+	case RollbackStarted:
+		r.start(metricsKey{runID: event.RunID, stepID: event.StepID, action: RollbackAction}, event.Time)
+	case StepCompleted, StepFailed:
+		key := metricsKey{runID: event.RunID, stepID: event.StepID, action: RunAction}
+		r.collector.StepFinished(event.WorkflowID, event.StepID, RunAction, event.Status, r.elapsed(key, event.Time))
+	case RollbackCompleted, RollbackFailed:
+		key := metricsKey{runID: event.RunID, stepID: event.StepID, action: RollbackAction}
+		r.collector.StepFinished(event.WorkflowID, event.StepID, RollbackAction, event.Status, r.elapsed(key, event.Time))
+	}
+}
+
+// start records the start time of the workflow run or the step action
+func (r *metricsRecorder) start(key metricsKey, t time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.starts[key] = t
+}
+
+// elapsed returns the duration since the start of the workflow run or the step action and forgets it
+// It returns 0 if the start was not recorded, e.g. for a step cancelled before its run.
+func (r *metricsRecorder) elapsed(key metricsKey, t time.Time) time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	start, ok := r.starts[key]
+	if !ok {
+		return 0
+	}
+
+	delete(r.starts, key)
+
+	return t.Sub(start)
+}
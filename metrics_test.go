@@ -0,0 +1,69 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockMetricsCollector struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (c *mockMetricsCollector) WorkflowStarted(workflowID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.calls = append(c.calls, "started "+workflowID)
+}
+
+func (c *mockMetricsCollector) WorkflowFinished(workflowID string, status Status, duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.calls = append(c.calls, fmt.Sprintf("finished %s %s %t", workflowID, status, duration > 0))
+}
+
+func (c *mockMetricsCollector) StepFinished(workflowID string, stepID string, action StepActionType, status Status, duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.calls = append(c.calls, fmt.Sprintf("%s %s %s %t", stepID, action, status, duration >= 10*time.Millisecond))
+}
+
+func TestWorkflow_Metrics(t *testing.T) {
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		time.Sleep(10 * time.Millisecond)
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	})
+
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return true, nil
+	}, nil)
+
+	s3 := &Step{ID: "step_3"}
+	s3.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil)
+
+	collector := &mockMetricsCollector{}
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2, s3), WithMetrics(collector))
+	_, err := workflow.Start(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"started workflow_1",
+		"step_1 run SUCCESS true",
+		"step_2 run SKIPPED false",
+		"step_3 run FAILED false",
+		"step_3 rollback SKIPPED false",
+		"step_2 rollback SKIPPED false",
+		"step_1 rollback SUCCESS false",
+		"finished workflow_1 FAILED true",
+	}, collector.calls)
+}
@@ -0,0 +1,136 @@
+package automa
+
+import (
+	"context"
+)
+
+// ExecutionMode defines how a workflow reacts when the run action of a step fails
+type ExecutionMode string
+
+const (
+	// StopOnError stops the workflow on the first failure and rolls back all the steps executed so far
+	// This is the default ExecutionMode.
+	StopOnError ExecutionMode = "stop_on_error"
+
+	// CompensateAndContinue rolls back only the failed step and continues the workflow with the next step
+	// The workflow completes with StatusPartial if any step failed.
+	CompensateAndContinue ExecutionMode = "compensate_and_continue"
+)
+
+// RollbackMode defines how a workflow reacts when the rollback action of a step fails
+type RollbackMode string
+
+const (
+	// ContinueOnRollbackError continues the rollback of the previous steps when a rollback fails
+	// This is the default RollbackMode.
+	ContinueOnRollbackError RollbackMode = "continue_on_rollback_error"
+
+	// StopOnRollbackError stops the rollback on the first rollback failure leaving the previous steps as they are
+	StopOnRollbackError RollbackMode = "stop_on_rollback_error"
+)
+
+// CancelBehavior defines how a workflow reacts when its context is cancelled between steps or while a step is running
+type CancelBehavior string
+
+const (
+	// RollbackOnCancel rolls back the steps executed so far using a context that is not cancelled
+	// This is the default CancelBehavior.
+	RollbackOnCancel CancelBehavior = "rollback_on_cancel"
+
+	// StopOnCancel stops the workflow leaving the steps executed so far as they are
+	StopOnCancel CancelBehavior = "stop_on_cancel"
+)
+
+// Severity defines the impact of the failure of a step on the workflow
+type Severity string
+
+const (
+	// SeverityCritical failures trigger the failure path of the workflow as per its ExecutionMode
+	// This is the default Severity of a step.
+	SeverityCritical Severity = "critical"
+
+	// SeverityWarning failures mark the step as failed but the workflow continues without any rollback
+	SeverityWarning Severity = "warning"
+
+	// SeverityInfo failures are treated like SeverityWarning failures and are only informational
+	SeverityInfo Severity = "info"
+)
+
+// withRollbackMode returns a copy of the context with the given RollbackMode
+func withRollbackMode(ctx context.Context, mode RollbackMode) context.Context {
+	return context.WithValue(ctx, ctxKeyRollbackMode, mode)
+}
+
+// rollbackModeFromContext returns the RollbackMode set in the context by the Workflow
+// It returns ContinueOnRollbackError if the context doesn't have any RollbackMode.
+func rollbackModeFromContext(ctx context.Context) RollbackMode {
+	if mode, ok := ctx.Value(ctxKeyRollbackMode).(RollbackMode); ok {
+		return mode
+	}
+
+	return ContinueOnRollbackError
+}
+
+// withCancelBehavior returns a copy of the context with the given CancelBehavior
+func withCancelBehavior(ctx context.Context, behavior CancelBehavior) context.Context {
+	return context.WithValue(ctx, ctxKeyCancelBehavior, behavior)
+}
+
+// cancelBehaviorFromContext returns the CancelBehavior set in the context by the Workflow
+// It returns RollbackOnCancel if the context doesn't have any CancelBehavior.
+func cancelBehaviorFromContext(ctx context.Context) CancelBehavior {
+	if behavior, ok := ctx.Value(ctxKeyCancelBehavior).(CancelBehavior); ok {
+		return behavior
+	}
+
+	return RollbackOnCancel
+}
+
+// NilReportPolicy defines how the helper methods of Step handle a nil StepReport provided by a step implementation
+type NilReportPolicy string
+
+const (
+	// WarnOnNilReport replaces the nil report with a default report and adds a warning to the workflow report
+	// This is the default NilReportPolicy.
+	WarnOnNilReport NilReportPolicy = "warn"
+
+	// SkipOnNilReport replaces the nil report with a default report and marks the action as skipped
+	SkipOnNilReport NilReportPolicy = "skip"
+
+	// FailOnNilReport treats a nil report as a failure of the action
+	FailOnNilReport NilReportPolicy = "fail"
+
+	// RetryOnNilReport invokes the action of the step again as per the RetryPolicy of the step, see WithRetryPolicy
+	// A nil report is treated as a failure of the action once the attempts are exhausted, or if there is no RetryPolicy.
+	RetryOnNilReport NilReportPolicy = "retry"
+)
+
+// withNilReportPolicy returns a copy of the context with the given NilReportPolicy
+func withNilReportPolicy(ctx context.Context, policy NilReportPolicy) context.Context {
+	return context.WithValue(ctx, ctxKeyNilReportPolicy, policy)
+}
+
+// nilReportPolicyFromContext returns the NilReportPolicy set in the context by the Workflow
+// It returns WarnOnNilReport if the context doesn't have any NilReportPolicy.
+func nilReportPolicyFromContext(ctx context.Context) NilReportPolicy {
+	if policy, ok := ctx.Value(ctxKeyNilReportPolicy).(NilReportPolicy); ok {
+		return policy
+	}
+
+	return WarnOnNilReport
+}
+
+// withExecutionMode returns a copy of the context with the given ExecutionMode
+func withExecutionMode(ctx context.Context, mode ExecutionMode) context.Context {
+	return context.WithValue(ctx, ctxKeyExecutionMode, mode)
+}
+
+// executionModeFromContext returns the ExecutionMode set in the context by the Workflow
+// It returns StopOnError if the context doesn't have any ExecutionMode.
+func executionModeFromContext(ctx context.Context) ExecutionMode {
+	if mode, ok := ctx.Value(ctxKeyExecutionMode).(ExecutionMode); ok {
+		return mode
+	}
+
+	return StopOnError
+}
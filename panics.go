@@ -0,0 +1,125 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PanicInfo defines the report data model for a panic raised by the SagaRun or SagaUndo of a step
+// Function, File and Line denote the location where the panic was raised.
+type PanicInfo struct {
+	StepID   string         `yaml:"step_id" json:"stepID"`
+	Action   StepActionType `yaml:"action" json:"action"`
+	Time     time.Time      `yaml:"time" json:"time"`
+	Value    string         `yaml:"value" json:"value"`
+	Function string         `yaml:"function" json:"function"`
+	File     string         `yaml:"file" json:"file"`
+	Line     int            `yaml:"line" json:"line"`
+	Stack    string         `yaml:"stack" json:"stack"`
+}
+
+// PanicError is the error returned when the SagaRun or SagaUndo of a step panicked
+type PanicError struct {
+	Info *PanicInfo
+}
+
+// Error implements error interface for PanicError
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("step %q panicked at %s:%d: %s", e.Info.StepID, e.Info.File, e.Info.Line, e.Info.Value)
+}
+
+// panics collects the panics recovered during a workflow run
+type panics struct {
+	mutex sync.Mutex
+	infos []*PanicInfo
+}
+
+// add adds the panic info
+func (p *panics) add(info *PanicInfo) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.infos = append(p.infos, info)
+}
+
+// list returns the collected panics in the order they were recovered
+func (p *panics) list() []*PanicInfo {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.infos) == 0 {
+		return nil
+	}
+
+	infos := make([]*PanicInfo, len(p.infos))
+	copy(infos, p.infos)
+
+	return infos
+}
+
+// withPanics returns a copy of the context with the given panics collector
+func withPanics(ctx context.Context, p *panics) context.Context {
+	return context.WithValue(ctx, ctxKeyPanics, p)
+}
+
+// callSaga invokes the saga logic of a step recovering from any panic raised by it
+// A panic is returned as a PanicError and recorded in the report of the workflow run.
+func callSaga(ctx context.Context, stepID string, action StepActionType, saga func(ctx context.Context) (bool, error)) (skipped bool, err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		info := &PanicInfo{
+			StepID: stepID,
+			Action: action,
+			Time:   time.Now(),
+			Value:  fmt.Sprintf("%v", r),
+			Stack:  string(debug.Stack()),
+		}
+		info.Function, info.File, info.Line = panicLocation()
+
+		if p, ok := ctx.Value(ctxKeyPanics).(*panics); ok {
+			p.add(info)
+		}
+
+		skipped, err = false, &PanicError{Info: info}
+	}()
+
+	return saga(ctx)
+}
+
+// panicFromError returns the PanicInfo of the error if it was returned by callSaga for a panic, otherwise nil
+func panicFromError(err error) *PanicInfo {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		return panicErr.Info
+	}
+
+	return nil
+}
+
+// panicLocation returns the location where the panic being recovered was raised
+// It is the first frame of the panicking goroutine that doesn't belong to the runtime.
+func panicLocation() (function string, file string, line int) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			return frame.Function, frame.File, frame.Line
+		}
+
+		if !more {
+			return "", "", 0
+		}
+	}
+}
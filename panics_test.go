@@ -0,0 +1,57 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestWorkflow_Panics(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		panic(errors.New("mock rollback panic"))
+	})
+
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		var m map[string]string
+		m["key"] = "value"
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+
+	var panicErr *PanicError
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "step_2", panicErr.Info.StepID)
+
+	assert.Equal(t, 2, len(report.Panics))
+	info := report.Panics[0]
+	assert.Equal(t, "step_2", info.StepID)
+	assert.Equal(t, RunAction, info.Action)
+	assert.Contains(t, info.Value, "nil map")
+	assert.True(t, strings.HasSuffix(info.File, "panics_test.go"))
+	assert.Equal(t, 24, info.Line)
+	assert.Contains(t, info.Function, "TestWorkflow_Panics")
+	assert.Contains(t, info.Stack, "goroutine ")
+
+	assert.Equal(t, "step_1", report.Panics[1].StepID)
+	assert.Equal(t, RollbackAction, report.Panics[1].Action)
+	assert.Equal(t, "mock rollback panic", report.Panics[1].Value)
+	assert.Equal(t, 18, report.Panics[1].Line)
+	assert.Equal(t, StatusFailed, report.StepReports[len(report.StepReports)-1].Status)
+
+	// the report is isolated from its clone
+	c := report.Clone()
+	c.Panics[0].StepID = "changed"
+	assert.Equal(t, "step_2", report.Panics[0].StepID)
+}
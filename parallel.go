@@ -0,0 +1,289 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"sort"
+	"sync"
+)
+
+// ParallelGroup is an AtomicStep that runs a group of independent steps concurrently
+// The reports of the members are added to the workflow report in the order of the members. If any member fails, the
+// members that completed are rolled back and the workflow rolls back the steps before the group. If a later step
+// fails, all members are rolled back in reverse order.
+//
+// Members must not be added to the workflow using WithSteps since the group links them to its own terminal steps.
+type ParallelGroup struct {
+	Step
+
+	members        []AtomicStep
+	maxConcurrency int
+
+	// members whose run succeeded in the last run of the group
+	completed []AtomicStep
+}
+
+// prioritizer is implemented by the members of a ParallelGroup that have a scheduling priority, see Step.WithPriority
+type prioritizer interface {
+	GetPriority() int
+}
+
+// memberResult holds the result of the execution of a member of a ParallelGroup
+type memberResult struct {
+	report WorkflowReport
+	err    error
+}
+
+// NewParallelGroup returns a ParallelGroup with the given members
+// By default, all members run at the same time, see WithMaxConcurrency and FeatureParallelExecution.
+func NewParallelGroup(id string, members ...AtomicStep) *ParallelGroup {
+	for _, member := range members {
+		bindStep(member)
+		member.SetPrev(&failedStep{})
+		member.SetNext(&successStep{})
+	}
+
+	return &ParallelGroup{
+		Step:    Step{ID: id},
+		members: members,
+	}
+}
+
+// WithMaxConcurrency sets the maximum number of members running at the same time
+// A value less than 1 means that all members run at the same time.
+func (g *ParallelGroup) WithMaxConcurrency(maxConcurrency int) *ParallelGroup {
+	g.maxConcurrency = maxConcurrency
+
+	return g
+}
+
+// Members returns the IDs of the members of the group
+func (g *ParallelGroup) Members() StepIDs {
+	var ids StepIDs
+	for _, member := range g.members {
+		ids = append(ids, member.GetID())
+	}
+
+	return ids
+}
+
+// Run implements Forward interface for ParallelGroup
+func (g *ParallelGroup) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	if pauseRequested(ctx) {
+		return prevSuccess.workflowReport, ErrWorkflowPaused
+	}
+
+	if ctx.Err() != nil {
+		return g.CancelledRun(ctx, prevSuccess)
+	}
+
+	saveState(ctx, prevSuccess.workflowReport)
+
+	report := NewStepReport(g.GetID(), RunAction)
+	report.Group = g.group
+	trackStep(ctx, g.GetID(), RunAction)
+	emitEvent(ctx, StepStarted, g.GetID(), "", nil)
+
+	results := g.runMembers(withStepIO(ctx, newStepIO(ctx, prevSuccess.workflowReport)), prevSuccess.workflowReport)
+
+	g.completed = nil
+	var errs []error
+	for i, result := range results {
+		g.merge(&prevSuccess.workflowReport, result.report)
+		if result.err != nil {
+			errs = append(errs, result.err)
+		} else {
+			g.completed = append(g.completed, g.members[i])
+		}
+	}
+
+	if len(errs) == 0 {
+		return g.RunNext(ctx, prevSuccess, report)
+	}
+
+	// the errors of the members are not wrapped in a StepError of the group so that they are reported as is
+	err := joinErrors(prevSuccess.workflowReport.WorkflowID, errs...)
+	status := StatusFailed
+	rollbackCtx := ctx
+	if ctx.Err() != nil {
+		// the run was cancelled while the members were running, the compensations must complete nevertheless
+		status = cancelledStatus(ctx)
+		rollbackCtx = detachedContext{ctx}
+	}
+
+	report.FailureReason = errors.EncodeError(ctx, err)
+	prevSuccess.workflowReport.Append(report, RunAction, status)
+	emitEvent(ctx, StepFailed, g.GetID(), status, err)
+
+	if ctx.Err() != nil && cancelBehaviorFromContext(ctx) == StopOnCancel {
+		return prevSuccess.workflowReport, err
+	}
+
+	// failed members have already rolled back themselves
+	return g.Rollback(rollbackCtx, &Failure{error: err, workflowReport: prevSuccess.workflowReport})
+}
+
+// Rollback implements Backward interface for ParallelGroup
+// It rolls back the members that completed in the last run of the group in reverse order.
+func (g *ParallelGroup) Rollback(ctx context.Context, prevFailure *Failure) (WorkflowReport, error) {
+	report := NewStepReport(g.GetID(), RollbackAction)
+	report.Group = g.group
+	trackStep(ctx, g.GetID(), RollbackAction)
+	emitEvent(ctx, RollbackStarted, g.GetID(), "", nil)
+
+	// members track their own step in a tracker of their own so that the heartbeat reports the group instead
+	memberCtx := withRunTracker(ctx, &runTracker{})
+
+	var errs []error
+	for i := len(g.completed) - 1; i >= 0; i-- {
+		member := g.completed[i]
+		memberReport, err := rollbackStep(memberCtx, member, &Failure{workflowReport: g.newMemberReport(prevFailure.workflowReport)})
+		g.merge(&prevFailure.workflowReport, memberReport)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	g.completed = nil
+
+	if len(errs) > 0 {
+		return g.failedRollback(ctx, prevFailure, joinErrors(prevFailure.workflowReport.WorkflowID, errs...), report)
+	}
+
+	return g.RollbackPrev(ctx, prevFailure, report)
+}
+
+// failedRollback reports the rollback of the group as failed and triggers the rollback of the previous step
+// Like in Run, the errors of the members are not wrapped in a StepError of the group so that they are reported as is.
+func (g *ParallelGroup) failedRollback(ctx context.Context, prevFailure *Failure, err error, report *StepReport) (WorkflowReport, error) {
+	report.FailureReason = errors.EncodeError(ctx, err)
+	prevFailure.workflowReport.Append(report, RollbackAction, StatusFailed)
+	emitEvent(ctx, RollbackFailed, g.GetID(), StatusFailed, err)
+
+	failure := &Failure{
+		error:          joinErrors(prevFailure.workflowReport.WorkflowID, prevFailure.error, err),
+		workflowReport: prevFailure.workflowReport,
+	}
+	if g.getRollbackMode(ctx) == StopOnRollbackError || g.Prev == nil {
+		return failure.workflowReport, failure.error
+	}
+
+	return rollbackStep(ctx, g.Prev, failure)
+}
+
+// runMembers runs the members concurrently and returns their results in the order of the members
+// Members are started by decreasing priority, see Step.WithPriority, which matters under bounded concurrency only.
+func (g *ParallelGroup) runMembers(ctx context.Context, parent WorkflowReport) []memberResult {
+	limit := g.maxConcurrency
+	if limit < 1 || limit > len(g.members) {
+		limit = len(g.members)
+	}
+
+	if !FeatureEnabled(ctx, FeatureParallelExecution) {
+		limit = 1
+	}
+
+	// members cannot be paused, checkpointed or taken over individually, the group is handled as a single step instead
+	ctx = withPauseSignal(ctx, &pauseSignal{})
+	ctx = withStateRecorder(ctx, nil)
+	ctx = withRunTracker(ctx, &runTracker{})
+
+	results := make([]memberResult, len(g.members))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, i := range g.schedule() {
+		member := g.members[i]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, member AtomicStep) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			memberReport, err := runStep(ctx, member, NewStartTrigger(g.newMemberReport(parent)))
+			results[i] = memberResult{report: memberReport, err: err}
+		}(i, member)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// schedule returns the indexes of the members in the order they are to be started, i.e. by decreasing priority
+func (g *ParallelGroup) schedule() []int {
+	priorities := make([]int, len(g.members))
+	order := make([]int, len(g.members))
+	for i, member := range g.members {
+		order[i] = i
+		if p, ok := member.(prioritizer); ok {
+			priorities[i] = p.GetPriority()
+		}
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return priorities[order[a]] > priorities[order[b]]
+	})
+
+	return order
+}
+
+// newMemberReport returns an empty report for the execution of a member
+// Every member has its own report so that members do not append to the same slice concurrently.
+func (g *ParallelGroup) newMemberReport(parent WorkflowReport) WorkflowReport {
+	return WorkflowReport{
+		WorkflowID:  parent.WorkflowID,
+		RunID:       parent.RunID,
+		StepReports: []*StepReport{},
+	}
+}
+
+// merge appends the step reports of a member to the workflow report
+func (g *ParallelGroup) merge(wfr *WorkflowReport, memberReport WorkflowReport) {
+	wfr.StepReports = append(wfr.StepReports, memberReport.StepReports...)
+}
+
+// Describe implements StepDescriber interface for ParallelGroup
+// The manifest includes the maximum concurrency and the manifests of the members in the order of the members, so
+// that changing a member changes the hash of the workflow manifest.
+func (g *ParallelGroup) Describe() StepManifest {
+	m := g.Step.Describe()
+	m.MaxConcurrency = g.maxConcurrency
+	for _, member := range g.members {
+		if d, ok := member.(StepDescriber); ok {
+			m.Members = append(m.Members, d.Describe())
+		} else {
+			m.Members = append(m.Members, StepManifest{ID: member.GetID()})
+		}
+	}
+
+	return m
+}
+
+// Plan implements Planner interface for ParallelGroup
+// It joins the descriptions of the members that implement Planner, one per line prefixed by the member ID.
+func (g *ParallelGroup) Plan(ctx context.Context) (*StepPlan, error) {
+	plan := &StepPlan{NoRollback: true}
+	for _, member := range g.members {
+		p, ok := member.(Planner)
+		if !ok {
+			plan.NoRollback = false
+			continue
+		}
+
+		memberPlan, err := p.Plan(withStepID(ctx, member.GetID()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "plan of member %q failed", member.GetID())
+		}
+
+		plan.Run += fmt.Sprintf("%s: %s\n", member.GetID(), memberPlan.Run)
+		if !memberPlan.NoRollback {
+			plan.NoRollback = false
+			plan.Rollback += fmt.Sprintf("%s: %s\n", member.GetID(), memberPlan.Rollback)
+		}
+	}
+
+	return plan, nil
+}
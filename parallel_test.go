@@ -0,0 +1,184 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelGroup(t *testing.T) {
+	ctx := context.Background()
+
+	var mutex sync.Mutex
+	var rollbacks []string
+	var running, maxRunning int32
+	newStep := func(id string, runErr error) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return false, runErr
+		}, func(ctx context.Context) (skipped bool, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			rollbacks = append(rollbacks, id)
+			return false, nil
+		})
+		return s
+	}
+
+	t.Run("success", func(t *testing.T) {
+		rollbacks = nil
+		maxRunning = 0
+		group := NewParallelGroup("install_tools",
+			newStep("install_helm", nil), newStep("install_kubectl", nil), newStep("install_jq", nil)).
+			WithMaxConcurrency(2)
+		assert.Equal(t, StepIDs{"install_helm", "install_kubectl", "install_jq"}, group.Members())
+
+		workflow := NewWorkflow("workflow_1", WithSteps(newStep("prepare", nil), group))
+		report, err := workflow.Start(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSuccess, report.Status)
+		assert.Equal(t, int32(2), maxRunning)
+
+		var ids []string
+		for _, stepReport := range report.StepReports {
+			ids = append(ids, stepReport.StepID)
+		}
+		assert.Equal(t, []string{"prepare", "install_helm", "install_kubectl", "install_jq", "install_tools"}, ids)
+		assert.Empty(t, rollbacks)
+	})
+
+	t.Run("member failure", func(t *testing.T) {
+		rollbacks = nil
+		group := NewParallelGroup("install_tools",
+			newStep("install_helm", nil), newStep("install_kubectl", errors.New("mock error")), newStep("install_jq", nil))
+
+		workflow := NewWorkflow("workflow_1", WithSteps(newStep("prepare", nil), group))
+		report, err := workflow.Start(ctx)
+		assert.Error(t, err)
+		assert.Equal(t, StatusFailed, report.Status)
+		stepErr, ok := AsStepError(err)
+		assert.True(t, ok)
+		assert.Equal(t, "install_kubectl", stepErr.StepID)
+
+		// the failed member rolls back itself, then completed members are rolled back in reverse order
+		assert.Equal(t, []string{"install_kubectl", "install_jq", "install_helm", "prepare"}, rollbacks)
+	})
+
+	t.Run("later step failure", func(t *testing.T) {
+		rollbacks = nil
+		group := NewParallelGroup("install_tools", newStep("install_helm", nil), newStep("install_jq", nil))
+
+		workflow := NewWorkflow("workflow_1", WithSteps(group, newStep("deploy", errors.New("mock error"))))
+		_, err := workflow.Start(ctx)
+		assert.Error(t, err)
+		assert.Equal(t, []string{"deploy", "install_jq", "install_helm"}, rollbacks)
+	})
+}
+
+func TestParallelGroup_Priority(t *testing.T) {
+	ctx := context.Background()
+
+	var mutex sync.Mutex
+	var started []string
+	newStep := func(id string) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			started = append(started, id)
+			return false, nil
+		}, nil)
+		return s
+	}
+
+	// higher priority members are started first, the reports remain in the order of the members
+	group := NewParallelGroup("install_tools",
+		newStep("install_jq"), newStep("install_kubectl").WithPriority(10), newStep("install_yq"),
+		newStep("install_helm").WithPriority(5)).
+		WithMaxConcurrency(1)
+	assert.Equal(t, 10, group.members[1].(*Step).GetPriority())
+
+	workflow := NewWorkflow("workflow_1", WithSteps(group))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"install_kubectl", "install_helm", "install_jq", "install_yq"}, started)
+
+	var ids []string
+	for _, stepReport := range report.StepReports {
+		ids = append(ids, stepReport.StepID)
+	}
+	assert.Equal(t, []string{"install_jq", "install_kubectl", "install_yq", "install_helm", "install_tools"}, ids)
+}
+
+func TestParallelGroup_Cancel(t *testing.T) {
+	var mutex sync.Mutex
+	var rollbacks []string
+	newStep := func(id string, run SagaRun) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(run, func(ctx context.Context) (skipped bool, err error) {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			rollbacks = append(rollbacks, id)
+			return false, nil
+		})
+		return s
+	}
+	succeed := func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}
+
+	// members and previous steps are compensated even though the run is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	group := NewParallelGroup("install_tools",
+		newStep("install_helm", succeed),
+		newStep("install_jq", func(ctx context.Context) (skipped bool, err error) {
+			cancel()
+			<-ctx.Done()
+			return false, ctx.Err()
+		})).
+		WithMaxConcurrency(1)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(newStep("prepare", succeed), group))
+	report, err := workflow.Start(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StatusCancelled, report.Status)
+	assert.Equal(t, []string{"install_jq", "install_helm", "prepare"}, rollbacks)
+	assert.Empty(t, report.HardFailures())
+	for _, stepReport := range report.StepReports {
+		if stepReport.Action == RollbackAction {
+			assert.Equal(t, StatusSuccess, stepReport.Status, stepReport.StepID)
+		}
+	}
+
+	// the errors of failed member rollbacks are not nested in the error of the group
+	failing := &Step{ID: "install_kubectl"}
+	failing.RegisterSaga(succeed, func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock rollback error")
+	})
+	group = NewParallelGroup("install_tools", failing)
+	workflow = NewWorkflow("workflow_1", WithSteps(group, newStep("deploy", func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	})))
+	_, err = workflow.Start(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, strings.Count(err.Error(), `workflow "workflow_1" failed`), err.Error())
+	assert.Contains(t, err.Error(), "mock rollback error")
+}
@@ -0,0 +1,217 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+)
+
+// PhaseHook is a func definition to be invoked at a boundary of a Phase
+type PhaseHook func(ctx context.Context, phase string) error
+
+// Phase is a named stage of a workflow grouping consecutive steps, e.g. "preflight", "install" and "verify"
+// A phase has optional hooks invoked when it starts and ends, and its own RollbackMode for the rollback of its steps.
+// The summary of every phase is reported in WorkflowReport.Phases.
+type Phase struct {
+	name         string
+	steps        []AtomicStep
+	onStart      PhaseHook
+	onEnd        PhaseHook
+	rollbackMode RollbackMode
+}
+
+// PhaseError is the error returned when a hook of a Phase fails
+type PhaseError struct {
+	Phase string
+	Hook  string
+	Err   error
+}
+
+// Error implements error interface for PhaseError
+func (e *PhaseError) Error() string {
+	return fmt.Sprintf("%s hook of phase %q failed: %v", e.Hook, e.Phase, e.Err)
+}
+
+// Unwrap returns the error of the hook
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// NewPhase returns a Phase with the given ordered steps
+func NewPhase(name string, steps ...AtomicStep) *Phase {
+	return &Phase{name: name, steps: steps}
+}
+
+// GetName returns the name of the Phase
+func (p *Phase) GetName() string {
+	return p.name
+}
+
+// OnStart registers a hook invoked before the first step of the phase
+// If the hook fails, the steps before the phase are rolled back.
+func (p *Phase) OnStart(hook PhaseHook) *Phase {
+	p.onStart = hook
+
+	return p
+}
+
+// OnEnd registers a hook invoked after the last step of the phase succeeded
+// If the hook fails, the steps of the phase and the steps before it are rolled back.
+func (p *Phase) OnEnd(hook PhaseHook) *Phase {
+	p.onEnd = hook
+
+	return p
+}
+
+// WithRollbackMode sets the RollbackMode of the steps of the phase overriding the RollbackMode of the workflow
+func (p *Phase) WithRollbackMode(mode RollbackMode) *Phase {
+	p.rollbackMode = mode
+
+	return p
+}
+
+// WithPhase appends the steps of the phase to the Workflow
+// Phases and steps are executed in the order of the options.
+func WithPhase(p *Phase) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.addStep(&phaseBoundary{wf: wf, phase: p, start: true})
+		WithSteps(p.steps...)(wf)
+		wf.addStep(&phaseBoundary{wf: wf, phase: p})
+
+		if wf.stepPhases == nil {
+			wf.stepPhases = map[string]*Phase{}
+		}
+
+		for _, step := range p.steps {
+			wf.stepPhases[step.GetID()] = p
+		}
+	}
+}
+
+// PhaseFromContext returns the name of the phase being executed
+// It returns false if the step doesn't belong to any phase.
+func PhaseFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(ctxKeyPhase).(string)
+	return name, ok && name != ""
+}
+
+// enterPhase returns a copy of the context for the steps of the phase
+func (wf *Workflow) enterPhase(ctx context.Context, p *Phase) context.Context {
+	ctx = context.WithValue(ctx, ctxKeyPhase, p.name)
+	if p.rollbackMode != "" {
+		return withRollbackMode(ctx, p.rollbackMode)
+	}
+
+	return withRollbackMode(ctx, wf.rollbackMode)
+}
+
+// manifest returns the PhaseManifest of the phase
+func (p *Phase) manifest() PhaseManifest {
+	m := PhaseManifest{
+		Name:         p.name,
+		RollbackMode: p.rollbackMode,
+		OnStart:      p.onStart != nil,
+		OnEnd:        p.onEnd != nil,
+		Steps:        StepIDs{},
+	}
+
+	for _, step := range p.steps {
+		m.Steps = append(m.Steps, step.GetID())
+	}
+
+	return m
+}
+
+// leavePhase returns a copy of the context for the steps outside any phase
+func (wf *Workflow) leavePhase(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, ctxKeyPhase, "")
+	return withRollbackMode(ctx, wf.rollbackMode)
+}
+
+// phaseContext returns a copy of the context for the phase of the step, if any
+// It is used when a run starts from a step in the middle of a phase, e.g. on Resume.
+func (wf *Workflow) phaseContext(ctx context.Context, stepID string) context.Context {
+	if p, ok := wf.stepPhases[stepID]; ok {
+		return wf.enterPhase(ctx, p)
+	}
+
+	return ctx
+}
+
+// phaseBoundary is a hidden AtomicStep marking the start or the end of a Phase in the list of steps
+// It invokes the hooks of the phase and switches the context when the workflow enters or leaves the phase in either
+// direction. It doesn't add any report.
+type phaseBoundary struct {
+	wf    *Workflow
+	phase *Phase
+	start bool
+	next  Forward
+	prev  Backward
+}
+
+// GetID implements AtomicStep interface for phaseBoundary
+func (b *phaseBoundary) GetID() string {
+	if b.start {
+		return "phase:" + b.phase.name + ":start"
+	}
+
+	return "phase:" + b.phase.name + ":end"
+}
+
+// Run implements Forward interface for phaseBoundary
+func (b *phaseBoundary) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	hook, hookName := b.phase.onEnd, "end"
+	if b.start {
+		ctx = b.wf.enterPhase(ctx, b.phase)
+		hook, hookName = b.phase.onStart, "start"
+	}
+
+	if hook != nil {
+		// the hook is user code, a panic is recovered and fails the phase like an error of the hook
+		_, err := callSaga(ctx, b.GetID(), RunAction, func(ctx context.Context) (bool, error) {
+			return false, hook(ctx, b.phase.name)
+		})
+		if err != nil {
+			err = &PhaseError{Phase: b.phase.name, Hook: hookName, Err: err}
+			if b.start {
+				ctx = b.wf.leavePhase(ctx)
+			}
+
+			return rollbackStep(ctx, b.prev, &Failure{error: err, workflowReport: prevSuccess.workflowReport})
+		}
+	}
+
+	if !b.start {
+		ctx = b.wf.leavePhase(ctx)
+	}
+
+	return runStep(ctx, b.next, prevSuccess)
+}
+
+// Rollback implements Backward interface for phaseBoundary
+func (b *phaseBoundary) Rollback(ctx context.Context, prevFailure *Failure) (WorkflowReport, error) {
+	if b.start {
+		return rollbackStep(b.wf.leavePhase(ctx), b.prev, prevFailure)
+	}
+
+	return rollbackStep(b.wf.enterPhase(ctx, b.phase), b.prev, prevFailure)
+}
+
+// SetNext implements Choreographer interface for phaseBoundary
+func (b *phaseBoundary) SetNext(next Forward) {
+	b.next = next
+}
+
+// SetPrev implements Choreographer interface for phaseBoundary
+func (b *phaseBoundary) SetPrev(prev Backward) {
+	b.prev = prev
+}
+
+// GetNext implements Choreographer interface for phaseBoundary
+func (b *phaseBoundary) GetNext() Forward {
+	return b.next
+}
+
+// GetPrev implements Choreographer interface for phaseBoundary
+func (b *phaseBoundary) GetPrev() Backward {
+	return b.prev
+}
@@ -0,0 +1,160 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWorkflow_Phases(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+	newStep := func(id string, runErr error, rollbackErr error) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			phase, _ := PhaseFromContext(ctx)
+			calls = append(calls, "run "+id+" in "+phase)
+			return false, runErr
+		}, func(ctx context.Context) (skipped bool, err error) {
+			phase, _ := PhaseFromContext(ctx)
+			calls = append(calls, "rollback "+id+" in "+phase)
+			return false, rollbackErr
+		})
+		return s
+	}
+	hook := func(name string) PhaseHook {
+		return func(ctx context.Context, phase string) error {
+			calls = append(calls, name+" "+phase)
+			return nil
+		}
+	}
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(newStep("setup", nil, nil)),
+		WithPhase(NewPhase("preflight", newStep("check", nil, nil)).OnStart(hook("start")).OnEnd(hook("end"))),
+		WithPhase(NewPhase("install", newStep("copy", nil, nil), newStep("link", nil, nil)).OnEnd(hook("end"))))
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"run setup in ",
+		"start preflight",
+		"run check in preflight",
+		"end preflight",
+		"run copy in install",
+		"run link in install",
+		"end install",
+	}, calls)
+	assert.Equal(t, StepIDs{"setup", "check", "copy", "link"}, report.StepSequence)
+	assert.Equal(t, 4, len(report.StepReports))
+	assert.Equal(t, "", report.StepReports[0].Phase)
+	assert.Equal(t, "install", report.StepReports[3].Phase)
+	assert.Equal(t, 2, len(report.Phases))
+	assert.Equal(t, "preflight", report.Phases[0].Group)
+	assert.Equal(t, StepIDs{"copy", "link"}, report.Phases[1].StepIDs)
+	assert.Equal(t, StatusSuccess, report.Phases[1].Status)
+
+	// undo crosses the phase boundaries backwards
+	calls = nil
+	workflow = NewWorkflow("workflow_1",
+		WithUndoWindow(time.Hour),
+		WithSteps(newStep("setup", nil, nil)),
+		WithPhase(NewPhase("install", newStep("copy", nil, nil))))
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	calls = nil
+	_, err = workflow.Undo(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rollback copy in install", "rollback setup in "}, calls)
+}
+
+func TestWorkflow_PhaseRollbackMode(t *testing.T) {
+	var rolledBack []string
+	newStep := func(id string, runErr error, rollbackErr error) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			return false, runErr
+		}, func(ctx context.Context) (skipped bool, err error) {
+			rolledBack = append(rolledBack, id)
+			return false, rollbackErr
+		})
+		return s
+	}
+
+	workflow := NewWorkflow("workflow_1",
+		WithPhase(NewPhase("preflight", newStep("check", nil, nil))),
+		WithPhase(NewPhase("install",
+			newStep("copy", nil, errors.New("mock rollback error")),
+			newStep("link", errors.New("mock error"), nil)).
+			WithRollbackMode(StopOnRollbackError)))
+
+	report, err := workflow.Start(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, []string{"link", "copy"}, rolledBack)
+	assert.Equal(t, StatusFailed, report.Phases[1].Status)
+	assert.Equal(t, StatusSuccess, report.Phases[0].Status)
+}
+
+func TestWorkflow_PhaseHookFailure(t *testing.T) {
+	var rolledBack []string
+	s1 := &Step{ID: "check"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		_, inPhase := PhaseFromContext(ctx)
+		assert.True(t, inPhase)
+		rolledBack = append(rolledBack, "check")
+		return false, nil
+	})
+
+	s2 := &Step{ID: "copy"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		assert.Fail(t, "copy must not run")
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1",
+		WithPhase(NewPhase("preflight", s1)),
+		WithPhase(NewPhase("install", s2).OnStart(func(ctx context.Context, phase string) error {
+			return errors.New("disk is full")
+		})))
+
+	report, err := workflow.Start(context.Background())
+	var phaseErr *PhaseError
+	assert.ErrorAs(t, err, &phaseErr)
+	assert.Equal(t, "install", phaseErr.Phase)
+	assert.Equal(t, "start", phaseErr.Hook)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, []string{"check"}, rolledBack)
+}
+
+func TestWorkflow_PhaseHookPanic(t *testing.T) {
+	var rolledBack []string
+	s1 := &Step{ID: "copy"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		rolledBack = append(rolledBack, "copy")
+		return false, nil
+	})
+
+	workflow := NewWorkflow("workflow_1",
+		WithPhase(NewPhase("install", s1).OnEnd(func(ctx context.Context, phase string) error {
+			panic("mock panic")
+		})))
+
+	report, err := workflow.Start(context.Background())
+	var phaseErr *PhaseError
+	assert.ErrorAs(t, err, &phaseErr)
+	assert.Equal(t, "install", phaseErr.Phase)
+	assert.Equal(t, "end", phaseErr.Hook)
+	var panicErr *PanicError
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, []string{"copy"}, rolledBack)
+	assert.Equal(t, 1, len(report.Panics))
+	assert.Equal(t, "phase:install:end", report.Panics[0].StepID)
+}
@@ -0,0 +1,125 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"time"
+)
+
+// PlanMetadataKey is the key of the StepReport.Metadata containing the description of the planned action
+const PlanMetadataKey = "plan"
+
+// StepPlan describes what the actions of a step would do without performing them
+type StepPlan struct {
+	// Run describes the changes the run action would make, e.g. "delete /opt/app"
+	Run string
+
+	// Rollback describes the changes the rollback action would make, e.g. "restore /opt/app from backup"
+	Rollback string
+
+	// NoRollback denotes that the step has no rollback action
+	NoRollback bool
+}
+
+// PlanFunc is a func definition to describe the actions of a step without side effects
+type PlanFunc func(ctx context.Context) (*StepPlan, error)
+
+// Planner defines the method to describe the actions of a step for Workflow.Plan
+// Steps that don't implement Planner are planned with an empty description.
+type Planner interface {
+	Plan(ctx context.Context) (*StepPlan, error)
+}
+
+// RegisterPlan registers the logic to describe the actions of the step for Workflow.Plan
+func (s *Step) RegisterPlan(plan PlanFunc) *Step {
+	s.plan = plan
+
+	return s
+}
+
+// Plan implements Planner interface
+// It invokes the PlanFunc registered using RegisterPlan, if any. NoRollback is always set if the step has no SagaUndo.
+func (s *Step) Plan(ctx context.Context) (*StepPlan, error) {
+	plan := &StepPlan{}
+	if s.plan != nil {
+		p, err := s.plan(s.stepContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		if p != nil {
+			plan = p
+		}
+	}
+
+	plan.NoRollback = plan.NoRollback || s.rollback == nil
+
+	return plan, nil
+}
+
+// withDryRun returns a copy of the context marked as a dry-run
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyDryRun, true)
+}
+
+// IsDryRun returns true if the context belongs to Workflow.Plan
+// It allows logic shared between PlanFunc and SagaRun to avoid side effects during a dry-run.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(ctxKeyDryRun).(bool)
+	return dryRun
+}
+
+// Plan walks the steps of the workflow without executing them and returns a report of what would be executed
+// The report contains a RunAction report for every step in order, followed by a RollbackAction report for every
+// step in reverse order describing how it would be rolled back if the last step failed. Planned actions have
+// StatusPlanned and their description in Metadata[PlanMetadataKey], while rollbacks of steps without any rollback
+// action have StatusSkipped. If the plan of a step fails, its RunAction report has StatusFailed, the report has
+// StatusFailed and the returned error is a WorkflowError aggregating the failures of all steps.
+func (wf *Workflow) Plan(ctx context.Context) (WorkflowReport, error) {
+	report := NewWorkflowReport(wf.id, wf.stepIDs)
+	manifest := wf.Manifest()
+	report.ManifestHash = manifest.Hash()
+	ctx = withDryRun(ctx)
+
+	var errs []error
+	var rollbacks []*StepReport
+	for _, step := range wf.steps {
+		plan := &StepPlan{}
+		var err error
+		if p, ok := step.(Planner); ok {
+			plan, err = p.Plan(withStepID(ctx, step.GetID()))
+		}
+
+		runReport := NewStepReport(step.GetID(), RunAction)
+		if err != nil {
+			runReport.FailureReason = errors.EncodeError(ctx, err)
+			report.Append(runReport, RunAction, StatusFailed)
+			errs = append(errs, &StepError{StepID: step.GetID(), Action: RunAction, Err: err})
+			continue
+		}
+
+		runReport.Metadata[PlanMetadataKey] = []byte(plan.Run)
+		report.Append(runReport, RunAction, StatusPlanned)
+
+		rollbackReport := NewStepReport(step.GetID(), RollbackAction)
+		rollbackReport.Status = StatusSkipped
+		if !plan.NoRollback {
+			rollbackReport.Status = StatusPlanned
+			rollbackReport.Metadata[PlanMetadataKey] = []byte(plan.Rollback)
+		}
+		rollbacks = append(rollbacks, rollbackReport)
+	}
+
+	for i := len(rollbacks) - 1; i >= 0; i-- {
+		report.Append(rollbacks[i], RollbackAction, rollbacks[i].Status)
+	}
+
+	report.Status = StatusPlanned
+	err := joinErrors(wf.id, errs...)
+	if err != nil {
+		report.Status = StatusFailed
+	}
+	report.EndTime = time.Now()
+
+	return *report, err
+}
@@ -0,0 +1,100 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWorkflow_Plan(t *testing.T) {
+	ctx := context.Background()
+	executed := false
+
+	s1 := &Step{ID: "backup"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		executed = true
+		return false, nil
+	}, nil)
+
+	s2 := &Step{ID: "delete_app"}
+	s2.WithContextValue("dir", "/opt/app")
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		executed = true
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	})
+	s2.RegisterPlan(func(ctx context.Context) (*StepPlan, error) {
+		assert.True(t, IsDryRun(ctx))
+		stepID, _ := StepFromContext(ctx)
+		assert.Equal(t, "delete_app", stepID)
+		return &StepPlan{
+			Run:      "delete " + ctx.Value("dir").(string),
+			Rollback: "restore " + ctx.Value("dir").(string),
+		}, nil
+	})
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	report, err := workflow.Plan(ctx)
+	assert.NoError(t, err)
+	assert.False(t, executed)
+	assert.Equal(t, StatusPlanned, report.Status)
+	assert.Equal(t, StepIDs{"backup", "delete_app"}, report.StepSequence)
+	assert.Equal(t, workflow.Manifest().Hash(), report.ManifestHash)
+
+	assert.Equal(t, 4, len(report.StepReports))
+	expected := []struct {
+		stepID string
+		action StepActionType
+		status Status
+		plan   string
+	}{
+		{"backup", RunAction, StatusPlanned, ""},
+		{"delete_app", RunAction, StatusPlanned, "delete /opt/app"},
+		{"delete_app", RollbackAction, StatusPlanned, "restore /opt/app"},
+		{"backup", RollbackAction, StatusSkipped, ""},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.stepID, report.StepReports[i].StepID)
+		assert.Equal(t, e.action, report.StepReports[i].Action)
+		assert.Equal(t, e.status, report.StepReports[i].Status)
+		assert.Equal(t, e.plan, string(report.StepReports[i].Metadata[PlanMetadataKey]))
+	}
+
+	// the workflow can still be started after planning
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.True(t, executed)
+	assert.False(t, IsDryRun(ctx))
+}
+
+func TestWorkflow_PlanFailure(t *testing.T) {
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterPlan(func(ctx context.Context) (*StepPlan, error) {
+		return nil, errors.New("mock error")
+	})
+
+	m1 := &Step{ID: "member_1"}
+	m1.RegisterSaga(nil, func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	})
+	m1.RegisterPlan(func(ctx context.Context) (*StepPlan, error) {
+		return &StepPlan{Run: "install", Rollback: "uninstall"}, nil
+	})
+	m2 := &Step{ID: "member_2"}
+	g := NewParallelGroup("group_1", m1, m2)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, g))
+	report, err := workflow.Plan(context.Background())
+	assert.Error(t, err)
+	stepErr, ok := AsStepError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "step_1", stepErr.StepID)
+	assert.Equal(t, StatusFailed, report.Status)
+
+	assert.Equal(t, 3, len(report.StepReports))
+	assert.Equal(t, StatusFailed, report.StepReports[0].Status)
+	assert.Equal(t, "member_1: install\nmember_2: \n", string(report.StepReports[1].Metadata[PlanMetadataKey]))
+	assert.Equal(t, "member_1: uninstall\n", string(report.StepReports[2].Metadata[PlanMetadataKey]))
+}
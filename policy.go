@@ -0,0 +1,152 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"strings"
+	"time"
+)
+
+// AdmissionAction defines the action submitted to the admission policies of a workflow
+type AdmissionAction string
+
+const (
+	AdmitStart  AdmissionAction = "start"
+	AdmitResume AdmissionAction = "resume"
+)
+
+// AdmissionRequest defines the run submitted to the admission policies of a workflow
+// It is serializable as JSON so that it can be used as the input of a policy engine, e.g. OPA.
+type AdmissionRequest struct {
+	WorkflowID string          `yaml:"workflow_id" json:"workflowID"`
+	Action     AdmissionAction `yaml:"action" json:"action"`
+	Time       time.Time       `yaml:"time" json:"time"`
+
+	// RunID is the ID of the run being resumed, it is empty when a run is started
+	RunID string `yaml:"run_id,omitempty" json:"runID,omitempty"`
+
+	// Manifest is the definition of the workflow including the parameters of the steps
+	Manifest WorkflowManifest `yaml:"manifest" json:"manifest"`
+
+	// Inputs are the inputs imported using ImportInputs, secret values are redacted
+	Inputs map[string]PortableValue `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+}
+
+// PolicyViolation defines the reason an admission policy rejected a run
+type PolicyViolation struct {
+	Policy string `yaml:"policy" json:"policy"`
+	StepID string `yaml:"step_id,omitempty" json:"stepID,omitempty"`
+	Reason string `yaml:"reason" json:"reason"`
+}
+
+// String returns the violation prefixed by the policy and the step, if any
+func (v PolicyViolation) String() string {
+	if v.StepID != "" {
+		return fmt.Sprintf("%s: step %q: %s", v.Policy, v.StepID, v.Reason)
+	}
+
+	return fmt.Sprintf("%s: %s", v.Policy, v.Reason)
+}
+
+// AdmissionPolicy evaluates whether a run of a workflow is compliant before any step is executed
+// Evaluate returns the violations of the policy, an empty list means that the run is admitted. An error means that
+// the policy could not be evaluated, e.g. the policy engine is unreachable, in which case the run is rejected too.
+type AdmissionPolicy interface {
+	Evaluate(ctx context.Context, req AdmissionRequest) ([]PolicyViolation, error)
+}
+
+// AdmissionPolicyFunc is an adapter to use a func as an AdmissionPolicy
+type AdmissionPolicyFunc func(ctx context.Context, req AdmissionRequest) ([]PolicyViolation, error)
+
+// Evaluate implements AdmissionPolicy interface for AdmissionPolicyFunc
+func (f AdmissionPolicyFunc) Evaluate(ctx context.Context, req AdmissionRequest) ([]PolicyViolation, error) {
+	return f(ctx, req)
+}
+
+// AdmissionRule returns a simple AdmissionPolicy rejecting the run with the reason returned by check, if not empty
+func AdmissionRule(check func(req AdmissionRequest) string) AdmissionPolicy {
+	return AdmissionPolicyFunc(func(ctx context.Context, req AdmissionRequest) ([]PolicyViolation, error) {
+		if reason := check(req); reason != "" {
+			return []PolicyViolation{{Reason: reason}}, nil
+		}
+
+		return nil, nil
+	})
+}
+
+// PolicyViolationError is the error returned by Start and Resume when an admission policy rejects the run
+type PolicyViolationError struct {
+	WorkflowID string
+	Action     AdmissionAction
+	Violations []PolicyViolation
+}
+
+// Error implements error interface for PolicyViolationError
+func (e *PolicyViolationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+
+	return fmt.Sprintf("%s of workflow %q is rejected by policy: %s", e.Action, e.WorkflowID, strings.Join(msgs, "; "))
+}
+
+// namedPolicy is an AdmissionPolicy registered with a Workflow
+type namedPolicy struct {
+	name   string
+	policy AdmissionPolicy
+}
+
+// WithAdmissionPolicy allows the runs of the Workflow to be checked by the policy before any step is executed
+// Start, Resume and TakeOver with ResumeRun return a PolicyViolationError without executing any step if the policy reports violations or fails.
+// Policies are evaluated in the order of the options and the violations are reported with the given name, unless the
+// policy sets it.
+func WithAdmissionPolicy(name string, policy AdmissionPolicy) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.policies = append(wf.policies, namedPolicy{name: name, policy: policy})
+	}
+}
+
+// admit evaluates the admission policies of the workflow for the action
+func (wf *Workflow) admit(ctx context.Context, action AdmissionAction, runID string) error {
+	if len(wf.policies) == 0 {
+		return nil
+	}
+
+	req := AdmissionRequest{
+		WorkflowID: wf.id,
+		Action:     action,
+		Time:       time.Now(),
+		RunID:      runID,
+		Manifest:   wf.Manifest(),
+	}
+
+	if wf.inputs != nil {
+		req.Inputs = wf.inputs.Redacted().Values
+	}
+
+	var violations []PolicyViolation
+	for _, p := range wf.policies {
+		policyViolations, err := p.policy.Evaluate(ctx, req)
+		if err != nil {
+			policyViolations = []PolicyViolation{{
+				Reason: errors.Wrap(err, "policy evaluation failed").Error(),
+			}}
+		}
+
+		for _, v := range policyViolations {
+			if v.Policy == "" {
+				v.Policy = p.name
+			}
+
+			violations = append(violations, v)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyViolationError{WorkflowID: wf.id, Action: action, Violations: violations}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWithAdmissionPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	executed := false
+	step := &Step{ID: "deploy"}
+	step.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		executed = true
+		return false, nil
+	}, nil)
+
+	var req AdmissionRequest
+	noProdDeploys := AdmissionRule(func(r AdmissionRequest) string {
+		req = r
+		if env, ok := r.Inputs["env"]; ok && string(env.Data) == "prod" {
+			return "prod deploys are frozen"
+		}
+
+		return ""
+	})
+	inputs := &PortableState{Values: map[string]PortableValue{
+		"env":   {Type: PortableString, Data: []byte("prod")},
+		"token": {Type: PortableString, Data: []byte("s3cr3t"), Secret: true},
+	}}
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(step),
+		ImportInputs(inputs),
+		WithAdmissionPolicy("change_freeze", noProdDeploys),
+		WithAdmissionPolicy("engine", AdmissionPolicyFunc(func(ctx context.Context, req AdmissionRequest) ([]PolicyViolation, error) {
+			return nil, errors.New("policy engine unreachable")
+		})),
+	)
+	defer workflow.End(ctx)
+
+	_, err := workflow.Start(ctx)
+	assert.False(t, executed)
+	var policyErr *PolicyViolationError
+	assert.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, AdmitStart, policyErr.Action)
+	assert.Equal(t, []PolicyViolation{
+		{Policy: "change_freeze", Reason: "prod deploys are frozen"},
+		{Policy: "engine", Reason: "policy evaluation failed: policy engine unreachable"},
+	}, policyErr.Violations)
+	assert.Equal(t, `start of workflow "workflow_1" is rejected by policy: `+
+		`change_freeze: prod deploys are frozen; engine: policy evaluation failed: policy engine unreachable`, err.Error())
+
+	assert.Equal(t, "workflow_1", req.WorkflowID)
+	assert.Equal(t, "deploy", req.Manifest.Steps[0].ID)
+	assert.Nil(t, req.Inputs["token"].Data)
+	assert.Equal(t, []byte("s3cr3t"), inputs.Values["token"].Data)
+
+	// compliant runs are admitted
+	inputs.Values["env"] = PortableValue{Type: PortableString, Data: []byte("staging")}
+	workflow = NewWorkflow("workflow_1", WithSteps(step), ImportInputs(inputs),
+		WithAdmissionPolicy("change_freeze", noProdDeploys))
+	defer workflow.End(ctx)
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.True(t, executed)
+	assert.Equal(t, StatusSuccess, report.Status)
+}
+
+func TestWithAdmissionPolicy_Resume(t *testing.T) {
+	ctx := context.Background()
+
+	var actions []AdmissionAction
+	policy := AdmissionPolicyFunc(func(ctx context.Context, req AdmissionRequest) ([]PolicyViolation, error) {
+		actions = append(actions, req.Action)
+		if req.Action == AdmitResume {
+			return []PolicyViolation{{StepID: "step_1", Reason: "resume is not allowed"}}, nil
+		}
+
+		return nil, nil
+	})
+
+	workflow := NewWorkflow("workflow_1", WithSteps(&Step{ID: "step_1"}), WithAdmissionPolicy("no_resume", policy))
+	defer workflow.End(ctx)
+
+	_, err := workflow.Resume(ctx, &Checkpoint{WorkflowID: "workflow_1", ManifestHash: workflow.Manifest().Hash(), NextStep: "step_1"})
+	assert.EqualError(t, err, `resume of workflow "workflow_1" is rejected by policy: no_resume: step "step_1": resume is not allowed`)
+
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []AdmissionAction{AdmitResume, AdmitStart}, actions)
+}
@@ -0,0 +1,174 @@
+package automa
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cockroachdb/errors"
+	"time"
+)
+
+// PortableStateVersion is the version of the PortableState format produced by this package
+const PortableStateVersion = "v1"
+
+// PortableValueType defines the type of the data of a PortableValue
+type PortableValueType string
+
+const (
+	PortableBytes  PortableValueType = "bytes"
+	PortableString PortableValueType = "string"
+	PortableJSON   PortableValueType = "json"
+)
+
+// PortableValue defines a typed value in a PortableState
+// Secret values are exported as is so that they can be handed off, but they are removed by PortableState.Redacted.
+type PortableValue struct {
+	Type   PortableValueType `yaml:"type" json:"type"`
+	Data   []byte            `yaml:"data" json:"data"`
+	Secret bool              `yaml:"secret,omitempty" json:"secret,omitempty"`
+}
+
+// PortableState defines the versioned format to hand off the outputs of a workflow run to another workflow
+// It is meant to be serialized as JSON and transferred to a different process or machine, see ExportOutputs and
+// ImportInputs.
+type PortableState struct {
+	Version    string                   `yaml:"version" json:"version"`
+	WorkflowID string                   `yaml:"workflow_id" json:"workflowID"`
+	RunID      string                   `yaml:"run_id" json:"runID"`
+	ExportTime time.Time                `yaml:"export_time" json:"exportTime"`
+	Values     map[string]PortableValue `yaml:"values" json:"values"`
+}
+
+// ExportOutputs returns the outputs of a successful run as a PortableState
+// All values are exported as PortableBytes. The given keys are marked as secrets.
+func ExportOutputs(report WorkflowReport, secretKeys ...string) (*PortableState, error) {
+	if report.Status != StatusSuccess {
+		return nil, errors.Newf("outputs of run %q cannot be exported since its status is %s", report.RunID, report.Status)
+	}
+
+	state := &PortableState{
+		Version:    PortableStateVersion,
+		WorkflowID: report.WorkflowID,
+		RunID:      report.RunID,
+		ExportTime: time.Now().UTC(),
+		Values:     map[string]PortableValue{},
+	}
+
+	for key, val := range report.Outputs {
+		state.Values[key] = PortableValue{Type: PortableBytes, Data: val}
+	}
+
+	for _, key := range secretKeys {
+		if val, ok := state.Values[key]; ok {
+			val.Secret = true
+			state.Values[key] = val
+		}
+	}
+
+	return state, nil
+}
+
+// SetType sets the type of the value of the given key, e.g. PortableJSON for a JSON document
+// It returns error if the key doesn't exist or the data is not valid for the type.
+func (ps *PortableState) SetType(key string, valueType PortableValueType) error {
+	val, ok := ps.Values[key]
+	if !ok {
+		return errors.Newf("portable value %q not found", key)
+	}
+
+	val.Type = valueType
+	if err := val.validate(); err != nil {
+		return errors.Wrapf(err, "invalid portable value %q", key)
+	}
+
+	ps.Values[key] = val
+
+	return nil
+}
+
+// Redacted returns a copy of the state without the data of the secret values, e.g. for logging
+func (ps *PortableState) Redacted() *PortableState {
+	redacted := *ps
+	redacted.Values = map[string]PortableValue{}
+	for key, val := range ps.Values {
+		if val.Secret {
+			val.Data = nil
+		}
+		redacted.Values[key] = val
+	}
+
+	return &redacted
+}
+
+// Marshal returns the JSON representation of the state
+func (ps *PortableState) Marshal() ([]byte, error) {
+	return json.Marshal(ps)
+}
+
+// UnmarshalPortableState parses and validates the JSON representation of a PortableState
+func UnmarshalPortableState(data []byte) (*PortableState, error) {
+	state := &PortableState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrap(err, "failed to parse portable state")
+	}
+
+	if err := state.validate(); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// validate checks that the state has a supported version and valid values
+func (ps *PortableState) validate() error {
+	if ps.Version != PortableStateVersion {
+		return errors.Newf("unsupported portable state version %q", ps.Version)
+	}
+
+	for key, val := range ps.Values {
+		if err := val.validate(); err != nil {
+			return errors.Wrapf(err, "invalid portable value %q", key)
+		}
+	}
+
+	return nil
+}
+
+// validate checks that the data of the value is valid for its type
+func (v PortableValue) validate() error {
+	switch v.Type {
+	case PortableBytes, PortableString:
+		return nil
+	case PortableJSON:
+		if !json.Valid(v.Data) {
+			return errors.New("data is not valid JSON")
+		}
+		return nil
+	default:
+		return errors.Newf("unsupported type %q", v.Type)
+	}
+}
+
+// ImportInputs allows the runs of the Workflow to use the values of a PortableState as inputs
+// Steps read the inputs using InputValue. The state should be validated beforehand, e.g. using UnmarshalPortableState.
+func ImportInputs(state *PortableState) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.inputs = state
+	}
+}
+
+// withInputs returns a copy of the context with the given inputs
+func withInputs(ctx context.Context, state *PortableState) context.Context {
+	return context.WithValue(ctx, ctxKeyInputs, state)
+}
+
+// InputValue returns the input of the current workflow run imported from a PortableState, see ImportInputs
+func InputValue(ctx context.Context, key string) (PortableValue, bool) {
+	state, ok := ctx.Value(ctxKeyInputs).(*PortableState)
+	if !ok {
+		return PortableValue{}, false
+	}
+
+	val, ok := state.Values[key]
+
+	return val, ok
+}
@@ -0,0 +1,67 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPortableState(t *testing.T) {
+	ctx := context.Background()
+
+	report := NewWorkflowReport("provision", nil)
+	report.RunID = "run_1"
+	report.Outputs["kubeconfig"] = []byte("secret-config")
+	report.Outputs["cluster"] = []byte(`{"name":"test"}`)
+
+	_, err := ExportOutputs(*report)
+	assert.Error(t, err)
+
+	report.Status = StatusSuccess
+	state, err := ExportOutputs(*report, "kubeconfig", "unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, PortableStateVersion, state.Version)
+	assert.True(t, state.Values["kubeconfig"].Secret)
+	assert.False(t, state.Values["cluster"].Secret)
+	assert.Equal(t, PortableBytes, state.Values["cluster"].Type)
+
+	assert.NoError(t, state.SetType("cluster", PortableJSON))
+	assert.Error(t, state.SetType("kubeconfig", PortableJSON))
+	assert.Error(t, state.SetType("unknown", PortableString))
+
+	redacted := state.Redacted()
+	assert.Nil(t, redacted.Values["kubeconfig"].Data)
+	assert.Equal(t, []byte("secret-config"), state.Values["kubeconfig"].Data)
+
+	data, err := state.Marshal()
+	assert.NoError(t, err)
+
+	imported, err := UnmarshalPortableState(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "run_1", imported.RunID)
+	assert.Equal(t, PortableJSON, imported.Values["cluster"].Type)
+
+	_, err = UnmarshalPortableState([]byte(`{"version":"v0"}`))
+	assert.Error(t, err)
+	_, err = UnmarshalPortableState([]byte(`{"version":"v1","values":{"a":{"type":"json","data":"e30x"}}}`))
+	assert.Error(t, err)
+	_, err = UnmarshalPortableState([]byte(`{`))
+	assert.Error(t, err)
+
+	var kubeconfig PortableValue
+	var found bool
+	s1 := &Step{ID: "deploy_app"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		kubeconfig, found = InputValue(ctx, "kubeconfig")
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("deploy", WithSteps(s1), ImportInputs(imported))
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("secret-config"), kubeconfig.Data)
+
+	_, ok := InputValue(ctx, "kubeconfig")
+	assert.False(t, ok)
+}
@@ -0,0 +1,95 @@
+package automa
+
+import (
+	"context"
+	"sync"
+)
+
+// Prefetcher defines the method to prepare a step ahead of its turn, e.g. pre-downloading binaries or warming caches
+// Prefetch of all steps is executed concurrently as soon as the workflow starts, while earlier steps run.
+type Prefetcher interface {
+	Prefetch(ctx context.Context) error
+}
+
+// prefetchResult holds the result of the Prefetch of a step
+type prefetchResult struct {
+	done chan struct{}
+	err  error
+}
+
+// prefetcher runs the Prefetch of the steps of a workflow run concurrently and tracks their results
+type prefetcher struct {
+	results map[string]*prefetchResult
+	wg      sync.WaitGroup
+	cancel  context.CancelFunc
+}
+
+// startPrefetch starts Prefetch of every step that implements Prefetcher
+// It returns nil if none of the steps implements Prefetcher.
+func startPrefetch(ctx context.Context, steps []AtomicStep) *prefetcher {
+	p := &prefetcher{results: map[string]*prefetchResult{}}
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	for _, step := range steps {
+		pf, ok := step.(Prefetcher)
+		if !ok {
+			continue
+		}
+
+		result := &prefetchResult{done: make(chan struct{})}
+		p.results[step.GetID()] = result
+
+		p.wg.Add(1)
+		go func(stepID string, pf Prefetcher) {
+			defer p.wg.Done()
+			defer close(result.done)
+			// a panic is recovered as the error of the prefetch so that it never crashes the run
+			_, result.err = callSaga(withStepID(ctx, stepID), stepID, RunAction, func(ctx context.Context) (bool, error) {
+				return false, pf.Prefetch(ctx)
+			})
+		}(step.GetID(), pf)
+	}
+
+	if len(p.results) == 0 {
+		p.cancel()
+		return nil
+	}
+
+	return p
+}
+
+// wait blocks until the Prefetch of the step is finished and returns its error
+// It returns nil immediately if the step has no Prefetch.
+func (p *prefetcher) wait(ctx context.Context, stepID string) error {
+	result, ok := p.results[stepID]
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-result.done:
+		return result.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stop cancels any Prefetch still running and waits for them to return
+func (p *prefetcher) stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// withPrefetcher returns a copy of the context with the given prefetcher
+func withPrefetcher(ctx context.Context, p *prefetcher) context.Context {
+	return context.WithValue(ctx, ctxKeyPrefetcher, p)
+}
+
+// waitPrefetch waits for the Prefetch of the step started by the Workflow, if any
+func waitPrefetch(ctx context.Context, stepID string) error {
+	if p, ok := ctx.Value(ctxKeyPrefetcher).(*prefetcher); ok && p != nil {
+		return p.wait(ctx, stepID)
+	}
+
+	return nil
+}
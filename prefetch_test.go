@@ -0,0 +1,74 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWorkflow_Prefetch(t *testing.T) {
+	ctx := context.Background()
+
+	// the prefetch of the second step is released by the run of the first step
+	release := make(chan struct{})
+	var prefetched bool
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		close(release)
+		return false, nil
+	}, nil)
+
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterPrefetch(func(ctx context.Context) error {
+		<-release
+		prefetched = true
+		return nil
+	})
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		assert.True(t, prefetched)
+		return false, nil
+	}, nil)
+
+	s3 := &Step{ID: "step_3"}
+	s3.RegisterPrefetch(func(ctx context.Context) error {
+		return errors.New("mock prefetch error")
+	})
+	s3.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2, s3))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.True(t, prefetched)
+	assert.Equal(t, []string{"prefetch of step \"step_3\" failed: mock prefetch error"}, report.Warnings)
+}
+
+func TestPrefetcher_NoSteps(t *testing.T) {
+	assert.Nil(t, startPrefetch(context.Background(), nil))
+	assert.NoError(t, waitPrefetch(context.Background(), "step_1"))
+}
+
+func TestWorkflow_Prefetch_Panic(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterPrefetch(func(ctx context.Context) error {
+		panic("mock panic")
+	})
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.Equal(t, 1, len(report.Warnings))
+	assert.Contains(t, report.Warnings[0], "mock panic")
+	assert.Equal(t, 1, len(report.Panics))
+	assert.Equal(t, "step_1", report.Panics[0].StepID)
+}
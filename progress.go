@@ -0,0 +1,225 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"sync"
+	"time"
+)
+
+// Progress defines the progress of a long-running step, e.g. bytes downloaded or files copied
+type Progress struct {
+	StepID  string    `yaml:"step_id" json:"stepID"`
+	Done    int64     `yaml:"done" json:"done"`
+	Total   int64     `yaml:"total" json:"total"`
+	Message string    `yaml:"message,omitempty" json:"message,omitempty"`
+	Time    time.Time `yaml:"time" json:"time"`
+}
+
+// Percent returns the percentage done between 0 and 100
+// It returns 0 if the total is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+
+	percent := float64(p.Done) * 100 / float64(p.Total)
+	if percent > 100 {
+		return 100
+	}
+
+	return percent
+}
+
+// progressTracker holds the progress reported by the steps of the current run of a workflow
+type progressTracker struct {
+	mutex sync.Mutex
+	steps map[string]*Progress
+	order []string
+}
+
+// reset forgets the progress of the previous run
+func (t *progressTracker) reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.steps = map[string]*Progress{}
+	t.order = nil
+}
+
+// update applies the change to the progress of the step and returns a copy of it
+func (t *progressTracker) update(stepID string, change func(p *Progress)) Progress {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.steps == nil {
+		t.steps = map[string]*Progress{}
+	}
+
+	p, ok := t.steps[stepID]
+	if !ok {
+		p = &Progress{StepID: stepID}
+		t.steps[stepID] = p
+		t.order = append(t.order, stepID)
+	}
+
+	change(p)
+	p.Time = time.Now()
+
+	return *p
+}
+
+// list returns a copy of the progress of the steps in the order they first reported it
+func (t *progressTracker) list() []Progress {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	list := make([]Progress, 0, len(t.order))
+	for _, stepID := range t.order {
+		list = append(list, *t.steps[stepID])
+	}
+
+	return list
+}
+
+// withProgressTracker returns a copy of the context with the given progressTracker
+func withProgressTracker(ctx context.Context, t *progressTracker) context.Context {
+	return context.WithValue(ctx, ctxKeyProgress, t)
+}
+
+// ProgressWriter reports the progress of a step to the engine
+// It implements io.Writer counting the bytes written so that it can be used with io.Copy or io.TeeReader. Every
+// update is visible in Workflow.Progress and is emitted as a StepProgress event to the listeners of the run.
+// A nil ProgressWriter is a NOOP.
+type ProgressWriter struct {
+	ctx     context.Context
+	stepID  string
+	tracker *progressTracker
+}
+
+// ProgressWriterFromContext returns the ProgressWriter of the current step
+// It returns nil, i.e. a NOOP writer, if the context doesn't belong to a workflow step.
+func ProgressWriterFromContext(ctx context.Context) *ProgressWriter {
+	t, ok := ctx.Value(ctxKeyProgress).(*progressTracker)
+	stepID, hasStep := StepFromContext(ctx)
+	if !ok || !hasStep {
+		return nil
+	}
+
+	return &ProgressWriter{ctx: ctx, stepID: stepID, tracker: t}
+}
+
+// SetTotal sets the total amount of work of the step, e.g. the size of the file to download
+func (w *ProgressWriter) SetTotal(total int64) {
+	w.update(func(p *Progress) {
+		p.Total = total
+	})
+}
+
+// SetDone sets the amount of work done, e.g. when a download is resumed from an offset
+func (w *ProgressWriter) SetDone(done int64) {
+	w.update(func(p *Progress) {
+		p.Done = done
+	})
+}
+
+// Add adds n to the amount of work done
+func (w *ProgressWriter) Add(n int64) {
+	w.update(func(p *Progress) {
+		p.Done += n
+	})
+}
+
+// SetMessage sets a human-readable description of the current work, e.g. the name of the file being copied
+func (w *ProgressWriter) SetMessage(msg string) {
+	w.update(func(p *Progress) {
+		p.Message = msg
+	})
+}
+
+// Write implements io.Writer interface adding the number of bytes to the amount of work done
+func (w *ProgressWriter) Write(b []byte) (int, error) {
+	w.Add(int64(len(b)))
+	return len(b), nil
+}
+
+// update applies the change to the progress of the step and emits it
+func (w *ProgressWriter) update(change func(p *Progress)) {
+	if w == nil {
+		return
+	}
+
+	p := w.tracker.update(w.stepID, change)
+	if emitter, ok := EventEmitterFromContext(w.ctx); ok {
+		emitter.Emit(w.ctx, Event{Type: StepProgress, StepID: w.stepID, Progress: &p})
+	}
+}
+
+// Progress returns the progress reported by the steps of the current or the last run of the Workflow
+// It is safe to call it while the workflow is running, e.g. to render progress bars.
+func (wf *Workflow) Progress() []Progress {
+	return wf.progress.list()
+}
+
+// ProgressUpdate defines an update of the progress of a workflow run sent to the channel set using WithProgressChannel
+// Percent is the percentage of the steps of the workflow whose run action is finished. Progress is set for
+// StepProgress updates.
+type ProgressUpdate struct {
+	Type     EventType
+	RunID    string
+	StepID   string
+	Status   Status
+	Err      error
+	Percent  float64
+	Progress *Progress
+	Time     time.Time
+}
+
+// WithProgressChannel allows the lifecycle events of every run of the Workflow to be streamed as ProgressUpdate
+// Updates are sent synchronously so the channel is expected to be buffered or drained by another goroutine, e.g. a
+// CLI spinner. If the context of the run is done while an update is blocked, the update is dropped and reported in
+// WorkflowReport.Diagnostics. The channel is not closed by the workflow, the last update of a run has the type
+// WorkflowFinished.
+func WithProgressChannel(ch chan<- ProgressUpdate) WorkflowOption {
+	return func(wf *Workflow) {
+		var mutex sync.Mutex
+		finished := map[string]bool{}
+
+		WithEventListener(func(ctx context.Context, event Event) {
+			mutex.Lock()
+			switch event.Type {
+			case WorkflowStarted:
+				finished = map[string]bool{}
+			case StepCompleted, StepFailed:
+				for _, id := range wf.stepIDs {
+					if id == event.StepID {
+						finished[id] = true
+					}
+				}
+			}
+
+			percent := 100.0
+			if len(wf.stepIDs) > 0 {
+				percent = float64(len(finished)) * 100 / float64(len(wf.stepIDs))
+			}
+			mutex.Unlock()
+
+			update := ProgressUpdate{
+				Type:     event.Type,
+				RunID:    event.RunID,
+				StepID:   event.StepID,
+				Status:   event.Status,
+				Err:      event.Err,
+				Percent:  percent,
+				Progress: event.Progress,
+				Time:     event.Time,
+			}
+
+			select {
+			case ch <- update:
+			case <-ctx.Done():
+				ReportSinkError(ctx, "progress_channel", errors.Newf("%s update dropped: %v", event.Type, ctx.Err()))
+			}
+		})(wf)
+	}
+}
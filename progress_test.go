@@ -0,0 +1,149 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressWriter(t *testing.T) {
+	ctx := context.Background()
+
+	// NOOP outside of a workflow step
+	w := ProgressWriterFromContext(ctx)
+	assert.Nil(t, w)
+	w.SetTotal(10)
+	n, err := w.Write([]byte("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	var workflow *Workflow
+	s1 := &Step{ID: "download"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		w := ProgressWriterFromContext(ctx)
+		w.SetTotal(20)
+		w.SetDone(5)
+		w.SetMessage("downloading file.tar.gz")
+		_, err = io.Copy(io.Discard, io.TeeReader(strings.NewReader("0123456789"), w))
+
+		progress := workflow.Progress()
+		assert.Equal(t, 1, len(progress))
+		assert.Equal(t, int64(15), progress[0].Done)
+		assert.Equal(t, 75.0, progress[0].Percent())
+		return false, err
+	}, nil)
+
+	s2 := &Step{ID: "extract"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		ProgressWriterFromContext(ctx).Add(3)
+		return false, nil
+	}, nil)
+
+	var events []Progress
+	workflow = NewWorkflow("workflow_1",
+		WithSteps(s1, s2),
+		WithEventListener(func(ctx context.Context, event Event) {
+			if event.Type == StepProgress {
+				events = append(events, *event.Progress)
+			}
+		}))
+
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	progress := workflow.Progress()
+	assert.Equal(t, 2, len(progress))
+	assert.Equal(t, "download", progress[0].StepID)
+	assert.Equal(t, "downloading file.tar.gz", progress[0].Message)
+	assert.Equal(t, 75.0, progress[0].Percent())
+	assert.Equal(t, "extract", progress[1].StepID)
+	assert.Equal(t, 0.0, progress[1].Percent())
+
+	assert.Equal(t, 5, len(events))
+	assert.Equal(t, int64(20), events[0].Total)
+	assert.Equal(t, int64(3), events[4].Done)
+
+	assert.Equal(t, 100.0, Progress{Done: 30, Total: 20}.Percent())
+}
+
+func TestWorkflow_ProgressChannel(t *testing.T) {
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return true, nil
+	}, nil)
+
+	ch := make(chan ProgressUpdate)
+	var updates []ProgressUpdate
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for update := range ch {
+			updates = append(updates, update)
+			if update.Type == WorkflowFinished {
+				return
+			}
+		}
+	}()
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2), WithProgressChannel(ch))
+	report, err := workflow.Start(context.Background())
+	assert.NoError(t, err)
+	<-done
+
+	assert.Equal(t, 6, len(updates))
+	expected := []struct {
+		eventType EventType
+		stepID    string
+		percent   float64
+	}{
+		{WorkflowStarted, "", 0},
+		{StepStarted, "step_1", 0},
+		{StepCompleted, "step_1", 50},
+		{StepStarted, "step_2", 50},
+		{StepCompleted, "step_2", 100},
+		{WorkflowFinished, "", 100},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.eventType, updates[i].Type)
+		assert.Equal(t, e.stepID, updates[i].StepID)
+		assert.Equal(t, e.percent, updates[i].Percent)
+		assert.Equal(t, report.RunID, updates[i].RunID)
+	}
+	assert.Equal(t, StatusSkipped, updates[4].Status)
+	assert.Equal(t, StatusSuccess, updates[5].Status)
+}
+
+func TestWorkflow_ProgressChannel_Stalled(t *testing.T) {
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	// nobody reads the channel, the run is not blocked once its context is done
+	ch := make(chan ProgressUpdate)
+	workflow := NewWorkflow("workflow_1", WithSteps(s1), WithProgressChannel(ch))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	done := make(chan WorkflowReport)
+	go func() {
+		report, _ := workflow.Start(ctx)
+		done <- report
+	}()
+
+	select {
+	case report := <-done:
+		assert.NotEmpty(t, report.Diagnostics.SinkErrors)
+		assert.Equal(t, "progress_channel", report.Diagnostics.SinkErrors[0].Sink)
+	case <-time.After(time.Second):
+		assert.Fail(t, "run is blocked by the progress channel")
+	}
+}
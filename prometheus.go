@@ -0,0 +1,186 @@
+package automa
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds in seconds of the buckets of the step duration histogram
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// PrometheusCollector is a MetricsCollector exposing the metrics in the Prometheus text exposition format
+// It implements http.Handler so that it can be served as the scrape endpoint, e.g. http.Handle("/metrics", c). The
+// metrics are:
+//   - <namespace>_workflow_runs_total{workflow_id,status} counter
+//   - <namespace>_workflows_in_flight{workflow_id} gauge
+//   - <namespace>_step_actions_total{workflow_id,step_id,action,status} counter
+//   - <namespace>_step_duration_seconds{workflow_id,step_id,action} histogram
+type PrometheusCollector struct {
+	namespace string
+	buckets   []float64
+
+	mutex     sync.Mutex
+	runs      map[string]float64
+	inFlight  map[string]float64
+	actions   map[string]float64
+	durations map[string]*histogram
+}
+
+// histogram holds the cumulative bucket counts, the sum and the count of a histogram series
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheusCollector returns a PrometheusCollector with the namespace as metric name prefix, "automa" if empty
+// Buckets of the step duration histogram are DefaultDurationBuckets if none is provided.
+func NewPrometheusCollector(namespace string, buckets ...float64) *PrometheusCollector {
+	if namespace == "" {
+		namespace = "automa"
+	}
+
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+
+	return &PrometheusCollector{
+		namespace: namespace,
+		buckets:   sorted,
+		runs:      map[string]float64{},
+		inFlight:  map[string]float64{},
+		actions:   map[string]float64{},
+		durations: map[string]*histogram{},
+	}
+}
+
+// WorkflowStarted implements MetricsCollector interface
+func (c *PrometheusCollector) WorkflowStarted(workflowID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.inFlight[labels("workflow_id", workflowID)]++
+}
+
+// WorkflowFinished implements MetricsCollector interface
+func (c *PrometheusCollector) WorkflowFinished(workflowID string, status Status, duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.inFlight[labels("workflow_id", workflowID)]--
+	c.runs[labels("workflow_id", workflowID, "status", string(status))]++
+}
+
+// StepFinished implements MetricsCollector interface
+func (c *PrometheusCollector) StepFinished(workflowID string, stepID string, action StepActionType, status Status, duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.actions[labels("workflow_id", workflowID, "step_id", stepID, "action", string(action), "status", string(status))]++
+
+	key := labels("workflow_id", workflowID, "step_id", stepID, "action", string(action))
+	h, ok := c.durations[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.buckets))}
+		c.durations[key] = h
+	}
+
+	seconds := duration.Seconds()
+	for i, bound := range c.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (c *PrometheusCollector) WriteTo(w io.Writer) (int64, error) {
+	c.mutex.Lock()
+	var buf bytes.Buffer
+	c.writeSeries(&buf, "workflow_runs_total", "counter", "Number of finished workflow runs by status.", c.runs)
+	c.writeSeries(&buf, "workflows_in_flight", "gauge", "Number of workflow runs in progress.", c.inFlight)
+	c.writeSeries(&buf, "step_actions_total", "counter", "Number of finished step actions by status.", c.actions)
+	c.writeHistograms(&buf)
+	c.mutex.Unlock()
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// ServeHTTP implements http.Handler interface
+func (c *PrometheusCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = c.WriteTo(w)
+}
+
+// writeSeries writes the series of a counter or a gauge sorted by labels
+func (c *PrometheusCollector) writeSeries(buf *bytes.Buffer, name string, kind string, help string, series map[string]float64) {
+	name = c.namespace + "_" + name
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, key := range sortedKeys(series) {
+		fmt.Fprintf(buf, "%s{%s} %s\n", name, key, formatFloat(series[key]))
+	}
+}
+
+// writeHistograms writes the series of the step duration histogram sorted by labels
+func (c *PrometheusCollector) writeHistograms(buf *bytes.Buffer) {
+	name := c.namespace + "_step_duration_seconds"
+	fmt.Fprintf(buf, "# HELP %s Duration of step actions in seconds.\n# TYPE %s histogram\n", name, name)
+
+	keys := make([]string, 0, len(c.durations))
+	for key := range c.durations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		h := c.durations[key]
+		for i, bound := range c.buckets {
+			fmt.Fprintf(buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, key, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, key, h.count)
+		fmt.Fprintf(buf, "%s_sum{%s} %s\n", name, key, formatFloat(h.sum))
+		fmt.Fprintf(buf, "%s_count{%s} %d\n", name, key, h.count)
+	}
+}
+
+// labels returns the label pairs in the Prometheus text format, e.g. workflow_id="install",status="SUCCESS"
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(pairs[i+1])))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// labelEscaper escapes a label value as per the Prometheus text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// sortedKeys returns the keys of the series in order
+func sortedKeys(series map[string]float64) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// formatFloat formats a sample value as per the Prometheus text format
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,60 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusCollector(t *testing.T) {
+	c := NewPrometheusCollector("", 0.1, 1)
+	c.WorkflowStarted("install")
+	c.WorkflowStarted("install")
+	c.StepFinished("install", "copy \"files\"", RunAction, StatusSuccess, 50*time.Millisecond)
+	c.StepFinished("install", "copy \"files\"", RunAction, StatusSuccess, 500*time.Millisecond)
+	c.WorkflowFinished("install", StatusSuccess, time.Second)
+
+	var buf strings.Builder
+	_, err := c.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, `# HELP automa_workflow_runs_total Number of finished workflow runs by status.
+# TYPE automa_workflow_runs_total counter
+automa_workflow_runs_total{workflow_id="install",status="SUCCESS"} 1
+# HELP automa_workflows_in_flight Number of workflow runs in progress.
+# TYPE automa_workflows_in_flight gauge
+automa_workflows_in_flight{workflow_id="install"} 1
+# HELP automa_step_actions_total Number of finished step actions by status.
+# TYPE automa_step_actions_total counter
+automa_step_actions_total{workflow_id="install",step_id="copy \"files\"",action="run",status="SUCCESS"} 2
+# HELP automa_step_duration_seconds Duration of step actions in seconds.
+# TYPE automa_step_duration_seconds histogram
+automa_step_duration_seconds_bucket{workflow_id="install",step_id="copy \"files\"",action="run",le="0.1"} 1
+automa_step_duration_seconds_bucket{workflow_id="install",step_id="copy \"files\"",action="run",le="1"} 2
+automa_step_duration_seconds_bucket{workflow_id="install",step_id="copy \"files\"",action="run",le="+Inf"} 2
+automa_step_duration_seconds_sum{workflow_id="install",step_id="copy \"files\"",action="run"} 0.55
+automa_step_duration_seconds_count{workflow_id="install",step_id="copy \"files\"",action="run"} 2
+`, buf.String())
+}
+
+func TestPrometheusCollector_ServeHTTP(t *testing.T) {
+	c := NewPrometheusCollector("installer")
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	_, err := NewWorkflow("workflow_1", WithSteps(s1), WithMetrics(c)).Start(context.Background())
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Contains(t, body, `installer_workflow_runs_total{workflow_id="workflow_1",status="SUCCESS"} 1`)
+	assert.Contains(t, body, `installer_workflows_in_flight{workflow_id="workflow_1"} 0`)
+	assert.Contains(t, body, `installer_step_actions_total{workflow_id="workflow_1",step_id="step_1",action="run",status="SUCCESS"} 1`)
+	assert.Contains(t, body, `installer_step_duration_seconds_count{workflow_id="workflow_1",step_id="step_1",action="run"} 1`)
+}
@@ -0,0 +1,118 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// QuarantineRetry defines the report data model of the background retry of a quarantined step
+type QuarantineRetry struct {
+	StepID        string              `yaml:"step_id" json:"stepID"`
+	RunID         string              `yaml:"run_id" json:"runID"`
+	StartTime     time.Time           `yaml:"start_time" json:"startTime"`
+	EndTime       time.Time           `yaml:"end_time" json:"endTime"`
+	Status        Status              `yaml:"status" json:"status"`
+	FailureReason errors.EncodedError `yaml:"reason" json:"reason"`
+	Panic         *PanicInfo          `yaml:"panic,omitempty" json:"panic,omitempty"`
+}
+
+// quarantine holds the list of known-flaky steps of a workflow and retries their failures in the background
+type quarantine struct {
+	ids    map[string]bool
+	logger *zap.Logger
+
+	mutex      sync.Mutex
+	dispatcher *callbackDispatcher
+	retries    []*QuarantineRetry
+}
+
+// newQuarantine returns a quarantine for the given step IDs
+func newQuarantine(ids []string, logger *zap.Logger) *quarantine {
+	q := &quarantine{ids: map[string]bool{}, logger: logger}
+	for _, id := range ids {
+		q.ids[id] = true
+	}
+
+	return q
+}
+
+// has returns true if the step is quarantined
+func (q *quarantine) has(stepID string) bool {
+	return q.ids[stepID]
+}
+
+// retry enqueues the run action of the quarantined step to be retried once in the background
+// The context must be the detached context of the step so that the retry keeps the values of the run and the step
+// after the run is finished. A panic of the retry is recovered and reported as its failure.
+func (q *quarantine) retry(ctx context.Context, stepID string, run SagaRun) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.dispatcher == nil {
+		q.dispatcher = newCallbackDispatcher(1, len(q.ids), DropOnFull)
+	}
+
+	runID, _ := RunIdFromContext(ctx)
+
+	task := func() {
+		r := &QuarantineRetry{StepID: stepID, RunID: runID, StartTime: time.Now()}
+		skipped, err := callSaga(ctx, stepID, RunAction, run)
+		r.EndTime = time.Now()
+		switch {
+		case err != nil:
+			q.logger.Warn("retry of quarantined step failed", zap.String("step_id", stepID), zap.Error(err))
+			r.Status = StatusFailed
+			r.FailureReason = errors.EncodeError(ctx, err)
+			r.Panic = panicFromError(err)
+		case skipped:
+			r.Status = StatusSkipped
+		default:
+			r.Status = StatusSuccess
+		}
+
+		q.mutex.Lock()
+		q.retries = append(q.retries, r)
+		q.mutex.Unlock()
+	}
+
+	if !q.dispatcher.dispatch(task) {
+		q.logger.Warn("retry of quarantined step dropped since the queue is full", zap.String("step_id", stepID))
+	}
+}
+
+// results returns the results of the background retries completed so far
+func (q *quarantine) results() []*QuarantineRetry {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	retries := make([]*QuarantineRetry, len(q.retries))
+	copy(retries, q.retries)
+
+	return retries
+}
+
+// drain waits for all pending retries to complete
+func (q *quarantine) drain() {
+	q.mutex.Lock()
+	dispatcher := q.dispatcher
+	q.dispatcher = nil
+	q.mutex.Unlock()
+
+	if dispatcher != nil {
+		dispatcher.drain()
+	}
+}
+
+// withQuarantine returns a copy of the context with the given quarantine
+func withQuarantine(ctx context.Context, q *quarantine) context.Context {
+	return context.WithValue(ctx, ctxKeyQuarantine, q)
+}
+
+// quarantineFromContext returns the quarantine set in the context by the Workflow, if any
+func quarantineFromContext(ctx context.Context) *quarantine {
+	q, _ := ctx.Value(ctxKeyQuarantine).(*quarantine)
+	return q
+}
@@ -0,0 +1,74 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkflow_WithQuarantine(t *testing.T) {
+	ctx := context.Background()
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	var attempts int32
+	flaky := &Step{ID: "flaky_step"}
+	flaky.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return false, errors.New("mock flaky error")
+		}
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(fetch, flaky), WithQuarantine(flaky.GetID()))
+	assert.Nil(t, NewWorkflow("workflow_2").QuarantineRetries())
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPartial, report.Status)
+	assert.Equal(t, StepIDs{flaky.GetID()}, report.ToleratedFailures())
+
+	workflow.End(ctx)
+	retries := workflow.QuarantineRetries()
+	assert.Equal(t, 1, len(retries))
+	assert.Equal(t, flaky.GetID(), retries[0].StepID)
+	assert.Equal(t, report.RunID, retries[0].RunID)
+	assert.Equal(t, StatusSuccess, retries[0].Status)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestWorkflow_WithQuarantine_RetryContext(t *testing.T) {
+	ctx := context.Background()
+
+	type regionKey struct{}
+	var attempts int32
+	var region interface{}
+	flaky := &Step{ID: "flaky_step"}
+	flaky.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return false, errors.New("mock flaky error")
+		}
+
+		region = ctx.Value(regionKey{})
+		panic("mock panic")
+	}, nil).WithContextValue(regionKey{}, "us-east-1")
+
+	workflow := NewWorkflow("workflow_1", WithSteps(flaky), WithQuarantine(flaky.GetID()))
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	// the panic of the background retry is reported as its failure instead of crashing the process
+	workflow.End(ctx)
+	retries := workflow.QuarantineRetries()
+	assert.Equal(t, 1, len(retries))
+	assert.Equal(t, StatusFailed, retries[0].Status)
+	assert.NotNil(t, retries[0].Panic)
+	assert.Equal(t, "mock panic", retries[0].Panic.Value)
+	assert.Equal(t, "us-east-1", region)
+}
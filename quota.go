@@ -0,0 +1,108 @@
+package automa
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Quota defines the limits of executions for a workflow or a tenant
+// A zero value for a limit means that the limit is not enforced.
+type Quota struct {
+	MaxConcurrent int `yaml:"max_concurrent" json:"maxConcurrent"`
+	MaxDaily      int `yaml:"max_daily" json:"maxDaily"`
+}
+
+// QuotaExceeded is the error returned when an execution is rejected because of a Quota
+type QuotaExceeded struct {
+	Key   string
+	Limit string
+	Quota Quota
+}
+
+// Error implements error interface for QuotaExceeded
+func (e *QuotaExceeded) Error() string {
+	return fmt.Sprintf("quota exceeded for %q: %s limit reached", e.Key, e.Limit)
+}
+
+// quotaUsage tracks the executions for a quota key
+type quotaUsage struct {
+	concurrent int
+	daily      int
+	day        string
+}
+
+// QuotaManager limits concurrent and daily executions per key, where the key is a workflow ID or a tenant
+type QuotaManager struct {
+	mutex  sync.Mutex
+	quotas map[string]Quota
+	usage  map[string]*quotaUsage
+	now    func() time.Time
+}
+
+// NewQuotaManager returns an instance of QuotaManager without any quota
+func NewQuotaManager() *QuotaManager {
+	return &QuotaManager{
+		quotas: map[string]Quota{},
+		usage:  map[string]*quotaUsage{},
+		now:    time.Now,
+	}
+}
+
+// SetQuota sets the Quota for the given key
+// It returns itself so that chaining is possible when setting multiple quotas
+func (qm *QuotaManager) SetQuota(key string, quota Quota) *QuotaManager {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	qm.quotas[key] = quota
+
+	return qm
+}
+
+// Acquire reserves an execution for the given key
+// It returns a release func that must be invoked when the execution finishes, or a QuotaExceeded error if the
+// execution is not allowed. Keys without any Quota are not limited.
+func (qm *QuotaManager) Acquire(key string) (func(), error) {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	quota, ok := qm.quotas[key]
+	if !ok {
+		return func() {}, nil
+	}
+
+	usage, ok := qm.usage[key]
+	if !ok {
+		usage = &quotaUsage{}
+		qm.usage[key] = usage
+	}
+
+	today := qm.now().UTC().Format("2006-01-02")
+	if usage.day != today {
+		usage.day = today
+		usage.daily = 0
+	}
+
+	if quota.MaxConcurrent > 0 && usage.concurrent >= quota.MaxConcurrent {
+		return nil, &QuotaExceeded{Key: key, Limit: "concurrent", Quota: quota}
+	}
+
+	if quota.MaxDaily > 0 && usage.daily >= quota.MaxDaily {
+		return nil, &QuotaExceeded{Key: key, Limit: "daily", Quota: quota}
+	}
+
+	usage.concurrent++
+	usage.daily++
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			qm.mutex.Lock()
+			defer qm.mutex.Unlock()
+			usage.concurrent--
+		})
+	}
+
+	return release, nil
+}
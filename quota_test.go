@@ -0,0 +1,67 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestQuotaManager_Acquire(t *testing.T) {
+	qm := NewQuotaManager().
+		SetQuota("tenant_1", Quota{MaxConcurrent: 1}).
+		SetQuota("tenant_2", Quota{MaxDaily: 2})
+
+	release, err := qm.Acquire("unknown")
+	assert.NoError(t, err)
+	release()
+
+	release, err = qm.Acquire("tenant_1")
+	assert.NoError(t, err)
+	_, err = qm.Acquire("tenant_1")
+	var quotaErr *QuotaExceeded
+	assert.True(t, errors.As(err, &quotaErr))
+	assert.Equal(t, "concurrent", quotaErr.Limit)
+	release()
+	release() // releasing twice should not affect the usage
+	release, err = qm.Acquire("tenant_1")
+	assert.NoError(t, err)
+	release()
+
+	now := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	qm.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		release, err = qm.Acquire("tenant_2")
+		assert.NoError(t, err)
+		release()
+	}
+	_, err = qm.Acquire("tenant_2")
+	assert.True(t, errors.As(err, &quotaErr))
+	assert.Equal(t, "daily", quotaErr.Limit)
+
+	// daily quota is reset on the next day
+	now = now.Add(24 * time.Hour)
+	_, err = qm.Acquire("tenant_2")
+	assert.NoError(t, err)
+}
+
+func TestWorkflow_WithQuota(t *testing.T) {
+	ctx := context.Background()
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	qm := NewQuotaManager().SetQuota("workflow_1", Quota{MaxDaily: 1})
+	workflow := NewWorkflow("workflow_1", WithSteps(fetch), WithQuota(qm, ""))
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	_, err = workflow.Start(ctx)
+	var quotaErr *QuotaExceeded
+	assert.True(t, errors.As(err, &quotaErr))
+	assert.Equal(t, "workflow_1", quotaErr.Key)
+}
@@ -0,0 +1,141 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+)
+
+// ReportRedactor returns the value to be reported for the given key, e.g. a masked value if the key holds a secret
+type ReportRedactor func(key, value string) string
+
+const (
+	// FailureReasonRedactKey is the key the message of StepReport.FailureReason is redacted with
+	FailureReasonRedactKey = "failure_reason"
+
+	// AttemptErrorsRedactKey is the key every entry of StepReport.AttemptErrors is redacted with
+	AttemptErrorsRedactKey = "attempt_errors"
+
+	// PanicValueRedactKey is the key the Value of every PanicInfo of WorkflowReport.Panics is redacted with
+	PanicValueRedactKey = "panic"
+)
+
+// WithReportRedactor sets a ReportRedactor applied to the copies of the report handed to callbacks and the ReportSink
+// It is applied to Metadata, Outputs and the string values of Extra of every run and rollback StepReport, including
+// the values nested in maps and slices of Extra, as well as to the error details, i.e. FailureReason, AttemptErrors
+// and the values of the Panics, which are redacted with FailureReasonRedactKey, AttemptErrorsRedactKey and
+// PanicValueRedactKey respectively. The report returned to the caller and the one saved in the StateStore are not
+// redacted so that their outputs can be exported or seeded and the run can be resumed; use WorkflowReport.Redacted
+// before marshalling the returned report.
+func WithReportRedactor(redactor ReportRedactor) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.redactor = redactor
+	}
+}
+
+// Redacted returns a copy of the report redacted by the redactor, leaving the report itself as is
+func (wfr *WorkflowReport) Redacted(redactor ReportRedactor) WorkflowReport {
+	c := wfr.Clone()
+	c.Redact(redactor)
+
+	return c
+}
+
+// Redact replaces the values of every StepReport, the Outputs and the values of the Panics with the values returned
+// by the redactor, see WithReportRedactor
+func (wfr *WorkflowReport) Redact(redactor ReportRedactor) {
+	if redactor == nil {
+		return
+	}
+
+	for _, stepReport := range wfr.StepReports {
+		stepReport.Redact(redactor)
+	}
+
+	for key, value := range wfr.Outputs {
+		wfr.Outputs[key] = []byte(redactor(key, string(value)))
+	}
+
+	// panics are copied since they are shared with the PanicError returned by the steps
+	for i, info := range wfr.Panics {
+		pi := *info
+		pi.Value = redactor(PanicValueRedactKey, pi.Value)
+		wfr.Panics[i] = &pi
+	}
+}
+
+// Redact replaces the Metadata, Outputs, Extra values and error details of the StepReport with the values returned by
+// the redactor, see WithReportRedactor
+// Extra values other than strings, maps and slices, e.g. structs, are left as is. A redacted FailureReason only
+// retains the redacted message of the error.
+func (sr *StepReport) Redact(redactor ReportRedactor) {
+	if redactor == nil {
+		return
+	}
+
+	for key, value := range sr.Metadata {
+		sr.Metadata[key] = []byte(redactor(key, string(value)))
+	}
+
+	for key, value := range sr.Outputs {
+		sr.Outputs[key] = []byte(redactor(key, string(value)))
+	}
+
+	for key, value := range sr.Extra {
+		sr.Extra[key] = redactValue(redactor, key, value)
+	}
+
+	if sr.AttemptErrors != nil {
+		attemptErrors := make([]string, len(sr.AttemptErrors))
+		for i, msg := range sr.AttemptErrors {
+			attemptErrors[i] = redactor(AttemptErrorsRedactKey, msg)
+		}
+
+		sr.AttemptErrors = attemptErrors
+	}
+
+	if sr.FailureReason.Error != nil {
+		msg := errors.DecodeError(context.Background(), sr.FailureReason).Error()
+		if redacted := redactor(FailureReasonRedactKey, msg); redacted != msg {
+			sr.FailureReason = errors.EncodeError(context.Background(), errors.New(redacted))
+		}
+	}
+}
+
+// redactValue returns a copy of the value with every string redacted
+// Strings nested in slices are redacted using the key of the slice.
+func redactValue(redactor ReportRedactor, key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redactor(key, v)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = redactValue(redactor, k, item)
+		}
+
+		return m
+	case map[string]string:
+		m := make(map[string]string, len(v))
+		for k, item := range v {
+			m[k] = redactor(k, item)
+		}
+
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = redactValue(redactor, key, item)
+		}
+
+		return s
+	case []string:
+		s := make([]string, len(v))
+		for i, item := range v {
+			s[i] = redactor(key, item)
+		}
+
+		return s
+	default:
+		return value
+	}
+}
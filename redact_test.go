@@ -0,0 +1,156 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// mockSecretStep is an example of a step adding secrets to its report
+type mockSecretStep struct {
+	Step
+}
+
+func (s *mockSecretStep) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	report := NewStepReport(s.GetID(), RunAction)
+	report.Metadata["token"] = []byte("s3cr3t")
+	report.Metadata["image"] = []byte("nginx")
+	_ = report.SetExtra("db", map[string]interface{}{"password": "s3cr3t", "hosts": []interface{}{"db-1"}})
+
+	return s.RunNext(ctx, prevSuccess, report)
+}
+
+func maskSecrets(key, value string) string {
+	if key == "token" || key == "password" {
+		return "****"
+	}
+
+	return value
+}
+
+func TestWithReportRedactor(t *testing.T) {
+	ctx := context.Background()
+	var callbackReport WorkflowReport
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(&mockSecretStep{Step: Step{ID: "step_1"}}),
+		WithReportRedactor(maskSecrets),
+		WithOnCompletion(func(ctx context.Context, report WorkflowReport) {
+			callbackReport = report
+		}),
+	)
+	defer workflow.End(ctx)
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	for _, r := range []WorkflowReport{callbackReport, report.Redacted(maskSecrets)} {
+		assert.Equal(t, []byte("****"), r.StepReports[0].Metadata["token"])
+		assert.Equal(t, []byte("nginx"), r.StepReports[0].Metadata["image"])
+		assert.Equal(t, map[string]interface{}{"password": "****", "hosts": []interface{}{"db-1"}},
+			r.StepReports[0].Extra["db"])
+	}
+
+	// the report returned to the caller is not redacted
+	assert.Equal(t, []byte("s3cr3t"), report.StepReports[0].Metadata["token"])
+	assert.Equal(t, map[string]interface{}{"password": "s3cr3t", "hosts": []interface{}{"db-1"}},
+		report.StepReports[0].Extra["db"])
+}
+
+func TestWithReportRedactor_ExportOutputs(t *testing.T) {
+	ctx := context.Background()
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, PublishOutput(ctx, "token", []byte("s3cr3t"))
+	}, nil)
+
+	var callbackReport WorkflowReport
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(s1),
+		WithReportRedactor(maskSecrets),
+		WithOnCompletion(func(ctx context.Context, report WorkflowReport) {
+			callbackReport = report
+		}),
+	)
+	defer workflow.End(ctx)
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("****"), callbackReport.Outputs["token"])
+
+	state, err := ExportOutputs(report, "token")
+	assert.NoError(t, err)
+	data, err := state.Marshal()
+	assert.NoError(t, err)
+	imported, err := UnmarshalPortableState(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("s3cr3t"), imported.Values["token"].Data)
+
+	var seeded []byte
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		seeded, _ = SeedValue(ctx, "token")
+		return false, nil
+	}, nil)
+
+	_, err = NewWorkflow("workflow_2", WithSteps(s2), WithSeedState(report)).Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("s3cr3t"), seeded)
+}
+
+func TestWorkflowReport_Redact(t *testing.T) {
+	report := NewWorkflowReport("workflow_1", StepIDs{"step_1"})
+	runReport := NewStepReport("step_1", RunAction)
+	runReport.Metadata["token"] = []byte("s3cr3t")
+	report.Append(runReport, RunAction, StatusSuccess)
+
+	rollbackReport := NewStepReport("step_1", RollbackAction)
+	rollbackReport.Metadata["token"] = []byte("s3cr3t")
+	_ = rollbackReport.SetExtra("token", []string{"a", "b"})
+	_ = rollbackReport.SetExtra("count", 2)
+	report.Append(rollbackReport, RollbackAction, StatusSuccess)
+
+	report.Redact(nil)
+	assert.Equal(t, []byte("s3cr3t"), report.StepReports[0].Metadata["token"])
+
+	redacted := report.Redacted(maskSecrets)
+	assert.Equal(t, []byte("****"), redacted.StepReports[0].Metadata["token"])
+	assert.Equal(t, []byte("s3cr3t"), report.StepReports[0].Metadata["token"])
+
+	report.Redact(maskSecrets)
+	assert.Equal(t, []byte("****"), report.StepReports[0].Metadata["token"])
+	assert.Equal(t, []byte("****"), report.StepReports[1].Metadata["token"])
+	assert.Equal(t, []string{"****", "****"}, report.StepReports[1].Extra["token"])
+	assert.Equal(t, 2, report.StepReports[1].Extra["count"])
+}
+
+func TestStepReport_Redact_Details(t *testing.T) {
+	maskTokens := func(key, value string) string {
+		return strings.ReplaceAll(value, "s3cr3t", "****")
+	}
+
+	report := NewWorkflowReport("workflow_1", StepIDs{"step_1"})
+	runReport := NewStepReport("step_1", RunAction)
+	runReport.Outputs["token"] = []byte("s3cr3t")
+	runReport.AttemptErrors = []string{"curl -H 'Authorization: s3cr3t' failed"}
+	runReport.FailureReason = errors.EncodeError(context.Background(), errors.New("curl -H 'Authorization: s3cr3t' failed"))
+	report.Append(runReport, RunAction, StatusFailed)
+	report.Outputs["token"] = []byte("s3cr3t")
+	info := &PanicInfo{StepID: "step_1", Value: "invalid token s3cr3t"}
+	report.Panics = []*PanicInfo{info}
+
+	report.Redact(maskTokens)
+	assert.Equal(t, []byte("****"), report.StepReports[0].Outputs["token"])
+	assert.Equal(t, []byte("****"), report.Outputs["token"])
+	assert.Equal(t, []string{"curl -H 'Authorization: ****' failed"}, report.StepReports[0].AttemptErrors)
+	assert.Equal(t, "curl -H 'Authorization: ****' failed",
+		errors.DecodeError(context.Background(), report.StepReports[0].FailureReason).Error())
+	assert.Equal(t, "invalid token ****", report.Panics[0].Value)
+	assert.Equal(t, "invalid token s3cr3t", info.Value)
+
+	// the failure reason is kept as is if there is nothing to redact
+	runReport = NewStepReport("step_1", RunAction)
+	runReport.FailureReason = errors.EncodeError(context.Background(), errors.Wrap(ErrNotConsistent, "mock error"))
+	runReport.Redact(maskTokens)
+	assert.True(t, errors.Is(errors.DecodeError(context.Background(), runReport.FailureReason), ErrNotConsistent))
+}
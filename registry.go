@@ -3,12 +3,15 @@ package automa
 import (
 	"github.com/cockroachdb/errors"
 	"go.uber.org/zap"
+	"sync"
 )
 
 // StepRegistry is an implementation of AtomicStepRegistry interface
 type StepRegistry struct {
-	cache  map[string]AtomicStep
-	logger *zap.Logger
+	mutex    sync.RWMutex
+	cache    map[string]AtomicStep
+	builders map[string][]*registeredBuilder
+	logger   *zap.Logger
 }
 
 // NewStepRegistry returns an instance of StepRegistry that implements AtomicStepRegistry
@@ -18,7 +21,7 @@ func NewStepRegistry(logger *zap.Logger) *StepRegistry {
 		logger = zap.NewNop()
 	}
 
-	return &StepRegistry{cache: map[string]AtomicStep{}, logger: logger}
+	return &StepRegistry{cache: map[string]AtomicStep{}, builders: map[string][]*registeredBuilder{}, logger: logger}
 }
 
 // registerStep registers an AtomicStep with the registry
@@ -34,6 +37,9 @@ func (r *StepRegistry) registerStep(id string, step AtomicStep) *StepRegistry {
 
 // RegisterSteps is a helper method to register multiple AtomicSteps at a time
 func (r *StepRegistry) RegisterSteps(steps map[string]AtomicStep) AtomicStepRegistry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
 	for id, step := range steps {
 		r.registerStep(id, step)
 	}
@@ -44,6 +50,9 @@ func (r *StepRegistry) RegisterSteps(steps map[string]AtomicStep) AtomicStepRegi
 // GetStep returns an AtomicStep by the id
 // It returns error if a step cannot be found by the given ID
 func (r *StepRegistry) GetStep(id string) AtomicStep {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
 	if step, ok := r.cache[id]; ok {
 		return step
 	}
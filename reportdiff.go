@@ -0,0 +1,244 @@
+package automa
+
+import (
+	"time"
+)
+
+// StepStatusChange defines the change of the status of a step action between two reports
+// OldStatus or NewStatus is empty if the action is missing from the corresponding report.
+type StepStatusChange struct {
+	StepID    string         `yaml:"step_id" json:"stepID"`
+	Action    StepActionType `yaml:"action" json:"action"`
+	OldStatus Status         `yaml:"old_status" json:"oldStatus"`
+	NewStatus Status         `yaml:"new_status" json:"newStatus"`
+}
+
+// ReportDiff defines the differences between the reports of two runs of a workflow
+type ReportDiff struct {
+	OldRunID     string             `yaml:"old_run_id" json:"oldRunID"`
+	NewRunID     string             `yaml:"new_run_id" json:"newRunID"`
+	OldStatus    Status             `yaml:"old_status" json:"oldStatus"`
+	NewStatus    Status             `yaml:"new_status" json:"newStatus"`
+	AddedSteps   StepIDs            `yaml:"added_steps,omitempty" json:"addedSteps,omitempty"`
+	RemovedSteps StepIDs            `yaml:"removed_steps,omitempty" json:"removedSteps,omitempty"`
+	ChangedSteps []StepStatusChange `yaml:"changed_steps,omitempty" json:"changedSteps,omitempty"`
+}
+
+// IsEmpty returns true if no step action changed between the reports
+func (d ReportDiff) IsEmpty() bool {
+	return len(d.AddedSteps) == 0 && len(d.RemovedSteps) == 0 && len(d.ChangedSteps) == 0
+}
+
+// stepActionKey identifies an action of a step in a report
+type stepActionKey struct {
+	stepID string
+	action StepActionType
+}
+
+// lastStatuses returns the status of the last report of every step action in the order of their first report
+func (wfr *WorkflowReport) lastStatuses(actions ...StepActionType) ([]stepActionKey, map[stepActionKey]Status) {
+	var keys []stepActionKey
+	statuses := map[stepActionKey]Status{}
+	for _, stepReport := range wfr.StepReports {
+		found := false
+		for _, action := range actions {
+			found = found || stepReport.Action == action
+		}
+
+		if !found {
+			continue
+		}
+
+		key := stepActionKey{stepID: stepReport.StepID, action: stepReport.Action}
+		if _, ok := statuses[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		statuses[key] = stepReport.Status
+	}
+
+	return keys, statuses
+}
+
+// Diff returns the changes of the status of every step action from this report to the other report
+// Step actions reported more than once, e.g. by a resumed run, are compared using their last report. If one of the
+// reports is the result of Workflow.Plan, only the RunAction reports are compared since the planned rollbacks are
+// hypothetical, and StatusPlanned is considered the same as StatusSuccess. This allows comparing a dry-run report
+// against the report of the real run.
+func (wfr *WorkflowReport) Diff(other *WorkflowReport) ReportDiff {
+	d := ReportDiff{
+		OldRunID:  wfr.RunID,
+		NewRunID:  other.RunID,
+		OldStatus: wfr.Status,
+		NewStatus: other.Status,
+	}
+
+	actions := []StepActionType{RunAction, RollbackAction}
+	if wfr.Status == StatusPlanned || other.Status == StatusPlanned {
+		actions = actions[:1]
+	}
+
+	oldKeys, oldStatuses := wfr.lastStatuses(actions...)
+	newKeys, newStatuses := other.lastStatuses(actions...)
+
+	oldSteps := map[string]bool{}
+	for _, key := range oldKeys {
+		oldSteps[key.stepID] = true
+	}
+
+	newSteps := map[string]bool{}
+	for _, key := range newKeys {
+		if !newSteps[key.stepID] && !oldSteps[key.stepID] {
+			d.AddedSteps = append(d.AddedSteps, key.stepID)
+		}
+
+		newSteps[key.stepID] = true
+	}
+
+	for _, key := range oldKeys {
+		if !newSteps[key.stepID] {
+			if !containsStepID(d.RemovedSteps, key.stepID) {
+				d.RemovedSteps = append(d.RemovedSteps, key.stepID)
+			}
+
+			continue
+		}
+
+		if oldStatus, newStatus := oldStatuses[key], newStatuses[key]; !sameOutcome(oldStatus, newStatus) {
+			d.ChangedSteps = append(d.ChangedSteps, StepStatusChange{
+				StepID:    key.stepID,
+				Action:    key.action,
+				OldStatus: oldStatus,
+				NewStatus: newStatus,
+			})
+		}
+	}
+
+	// actions of existing steps that are only in the new report, e.g. rollbacks of a failed run
+	for _, key := range newKeys {
+		if _, ok := oldStatuses[key]; !ok && oldSteps[key.stepID] {
+			d.ChangedSteps = append(d.ChangedSteps, StepStatusChange{
+				StepID:    key.stepID,
+				Action:    key.action,
+				NewStatus: newStatuses[key],
+			})
+		}
+	}
+
+	return d
+}
+
+// sameOutcome returns true if the statuses are equal or one is the planned outcome of the other
+func sameOutcome(old Status, new Status) bool {
+	return old == new ||
+		(old == StatusPlanned && new == StatusSuccess) ||
+		(old == StatusSuccess && new == StatusPlanned)
+}
+
+// containsStepID returns true if ids contains id
+func containsStepID(ids StepIDs, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StepAggregate defines the statuses of an action of a step across the reports of repeated runs
+type StepAggregate struct {
+	StepID string         `yaml:"step_id" json:"stepID"`
+	Action StepActionType `yaml:"action" json:"action"`
+
+	// Runs is the number of reports containing the step action
+	Runs int `yaml:"runs" json:"runs"`
+
+	// Statuses contains the number of reports by status of the step action, using its last report in every run
+	Statuses map[Status]int `yaml:"statuses" json:"statuses"`
+
+	// LastStatus is the status of the step action in the last report containing it
+	LastStatus Status `yaml:"last_status" json:"lastStatus"`
+
+	// TotalDuration is the sum of the durations of all reports of the step action
+	TotalDuration time.Duration `yaml:"total_duration" json:"totalDuration"`
+
+	// Flaky is set if the step action both succeeded and failed across the reports
+	Flaky bool `yaml:"flaky" json:"flaky"`
+}
+
+// ReportAggregate defines the merged reports of repeated runs of a workflow
+type ReportAggregate struct {
+	WorkflowID string `yaml:"workflow_id" json:"workflowID"`
+
+	// Runs is the number of reports that were aggregated
+	Runs int `yaml:"runs" json:"runs"`
+
+	// Statuses contains the number of reports by workflow status
+	Statuses map[Status]int `yaml:"statuses" json:"statuses"`
+
+	// Steps contains the aggregate of every step action in the order of their first report
+	Steps []*StepAggregate `yaml:"steps" json:"steps"`
+
+	// Costs contains the total of the costs of the runs
+	Costs Costs `yaml:"costs,omitempty" json:"costs,omitempty"`
+}
+
+// AggregateReports merges the reports of repeated runs of a workflow
+// Nil reports are ignored. The WorkflowID is the one of the first report.
+func AggregateReports(reports ...*WorkflowReport) ReportAggregate {
+	agg := ReportAggregate{Statuses: map[Status]int{}}
+	steps := map[stepActionKey]*StepAggregate{}
+	for _, report := range reports {
+		if report == nil {
+			continue
+		}
+
+		if agg.Runs == 0 {
+			agg.WorkflowID = report.WorkflowID
+		}
+
+		agg.Runs++
+		agg.Statuses[report.Status]++
+		if len(report.Costs) > 0 {
+			if agg.Costs == nil {
+				agg.Costs = Costs{}
+			}
+
+			agg.Costs.Add(report.Costs)
+		}
+
+		durations := map[stepActionKey]time.Duration{}
+		for _, stepReport := range report.StepReports {
+			key := stepActionKey{stepID: stepReport.StepID, action: stepReport.Action}
+			durations[key] += stepReport.EndTime.Sub(stepReport.StartTime)
+		}
+
+		keys, statuses := report.lastStatuses(RunAction, RollbackAction)
+		for _, key := range keys {
+			step, ok := steps[key]
+			if !ok {
+				step = &StepAggregate{StepID: key.stepID, Action: key.action, Statuses: map[Status]int{}}
+				steps[key] = step
+				agg.Steps = append(agg.Steps, step)
+			}
+
+			status := statuses[key]
+			step.Runs++
+			step.Statuses[status]++
+			step.LastStatus = status
+			step.TotalDuration += durations[key]
+
+			failed := 0
+			for s, count := range step.Statuses {
+				if isFailure(s) {
+					failed += count
+				}
+			}
+
+			step.Flaky = failed > 0 && step.Statuses[StatusSuccess] > 0
+		}
+	}
+
+	return agg
+}
@@ -0,0 +1,102 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newStatusReport(status Status, steps map[string]Status, order ...string) *WorkflowReport {
+	report := NewWorkflowReport("workflow_1", order)
+	report.Status = status
+	for _, id := range order {
+		report.Append(NewStepReport(id, RunAction), RunAction, steps[id])
+	}
+
+	return report
+}
+
+func TestWorkflowReport_Diff(t *testing.T) {
+	old := newStatusReport(StatusSuccess, map[string]Status{
+		"step_1": StatusSuccess,
+		"step_2": StatusSuccess,
+		"step_3": StatusSkipped,
+	}, "step_1", "step_2", "step_3")
+	new := newStatusReport(StatusFailed, map[string]Status{
+		"step_1": StatusSuccess,
+		"step_2": StatusFailed,
+		"step_4": StatusSuccess,
+	}, "step_1", "step_4", "step_2")
+	new.Append(NewStepReport("step_4", RollbackAction), RollbackAction, StatusSuccess)
+
+	d := old.Diff(new)
+	assert.False(t, d.IsEmpty())
+	assert.Equal(t, StatusSuccess, d.OldStatus)
+	assert.Equal(t, StatusFailed, d.NewStatus)
+	assert.Equal(t, StepIDs{"step_4"}, d.AddedSteps)
+	assert.Equal(t, StepIDs{"step_3"}, d.RemovedSteps)
+	assert.Equal(t, []StepStatusChange{
+		{StepID: "step_2", Action: RunAction, OldStatus: StatusSuccess, NewStatus: StatusFailed},
+	}, d.ChangedSteps)
+
+	assert.True(t, old.Diff(old).IsEmpty())
+}
+
+func TestWorkflowReport_Diff_DryRun(t *testing.T) {
+	ctx := context.Background()
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (bool, error) { return false, nil }, nil)
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (bool, error) {
+		return false, errors.New("failed")
+	}, func(ctx context.Context) (bool, error) { return false, nil })
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	defer workflow.End(ctx)
+
+	plan, err := workflow.Plan(ctx)
+	assert.NoError(t, err)
+
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+
+	d := plan.Diff(&report)
+	assert.Empty(t, d.AddedSteps)
+	assert.Empty(t, d.RemovedSteps)
+	assert.Equal(t, []StepStatusChange{
+		{StepID: "step_2", Action: RunAction, OldStatus: StatusPlanned, NewStatus: StatusFailed},
+	}, d.ChangedSteps)
+}
+
+func TestAggregateReports(t *testing.T) {
+	r1 := newStatusReport(StatusSuccess, map[string]Status{
+		"step_1": StatusSuccess,
+		"step_2": StatusSuccess,
+	}, "step_1", "step_2")
+	r2 := newStatusReport(StatusFailed, map[string]Status{
+		"step_1": StatusSuccess,
+		"step_2": StatusFailed,
+	}, "step_1", "step_2")
+	r2.Append(NewStepReport("step_1", RollbackAction), RollbackAction, StatusSuccess)
+
+	agg := AggregateReports(r1, nil, r2)
+	assert.Equal(t, "workflow_1", agg.WorkflowID)
+	assert.Equal(t, 2, agg.Runs)
+	assert.Equal(t, map[Status]int{StatusSuccess: 1, StatusFailed: 1}, agg.Statuses)
+	assert.Equal(t, 3, len(agg.Steps))
+
+	assert.Equal(t, "step_1", agg.Steps[0].StepID)
+	assert.Equal(t, 2, agg.Steps[0].Runs)
+	assert.False(t, agg.Steps[0].Flaky)
+
+	assert.Equal(t, "step_2", agg.Steps[1].StepID)
+	assert.Equal(t, map[Status]int{StatusSuccess: 1, StatusFailed: 1}, agg.Steps[1].Statuses)
+	assert.Equal(t, StatusFailed, agg.Steps[1].LastStatus)
+	assert.True(t, agg.Steps[1].Flaky)
+
+	assert.Equal(t, RollbackAction, agg.Steps[2].Action)
+	assert.Equal(t, 1, agg.Steps[2].Runs)
+
+	assert.Equal(t, 0, AggregateReports().Runs)
+}
@@ -1,7 +1,13 @@
 package automa
 
 import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
 	"github.com/cockroachdb/errors"
+	"io"
+	"sync"
 	"time"
 )
 
@@ -17,22 +23,308 @@ const (
 // WorkflowReport defines a map of StepReport with key as the step ID
 type WorkflowReport struct {
 	WorkflowID   string        `yaml:"workflow_id" json:"workflowID"`
+	RunID        string        `yaml:"run_id" json:"runID"`
+	ManifestHash string        `yaml:"manifest_hash" json:"manifestHash"`
 	StartTime    time.Time     `yaml:"start_time" json:"startTime"`
 	EndTime      time.Time     `yaml:"end_time" json:"endTime"`
 	Status       Status        `yaml:"status" json:"status"`
 	StepSequence StepIDs       `yaml:"step_sequence" json:"stepSequence"`
 	StepReports  []*StepReport `yaml:"step_reports" json:"stepReports"`
+
+	// Outputs contains the outputs published by the steps of a successful run
+	// It is populated at the end of the run from StepReport.Outputs of every successful RunAction in step order.
+	Outputs map[string][]byte `yaml:"outputs" json:"outputs"`
+
+	// ManifestDiff contains the changes of the workflow definition since the previous run, if any
+	ManifestDiff *ManifestDiff `yaml:"manifest_diff,omitempty" json:"manifestDiff,omitempty"`
+
+	// Costs contains the total of the costs reported by the steps of the run, see AddCost
+	Costs Costs `yaml:"costs,omitempty" json:"costs,omitempty"`
+
+	// Groups contains the summary of every group of steps in the order of their first execution, see Step.WithGroup
+	Groups []*GroupSummary `yaml:"groups,omitempty" json:"groups,omitempty"`
+
+	// Phases contains the summary of every phase in the order of their first execution, see WithPhase
+	Phases []*GroupSummary `yaml:"phases,omitempty" json:"phases,omitempty"`
+
+	// ResourceCleanups contains the reports of the cleanup of the resources tracked during the run, see TrackResource
+	ResourceCleanups []*ResourceCleanup `yaml:"resource_cleanups,omitempty" json:"resourceCleanups,omitempty"`
+
+	// Panics contains the panics raised by the steps during the run, they are reported as failures of the steps
+	Panics []*PanicInfo `yaml:"panics,omitempty" json:"panics,omitempty"`
+
+	// Warnings contains the unique warnings raised during the run by the engine or the steps, see AddWarning
+	Warnings []string `yaml:"warnings" json:"warnings"`
+
+	// Diagnostics contains the failures during the run that did not affect its outcome, e.g. observability sinks
+	Diagnostics Diagnostics `yaml:"diagnostics" json:"diagnostics"`
+
+	// CallbackFailures contains the failures of the callbacks executed synchronously at the end of the run
+	CallbackFailures []*CallbackFailure `yaml:"callback_failures" json:"callbackFailures"`
 }
 
 // StepReport defines the report data model for each AtomicStep execution
 type StepReport struct {
 	StepID        string              `yaml:"step_id" json:"stepID"`
+	DisplayName   string              `yaml:"display_name,omitempty" json:"displayName,omitempty"`
 	Action        StepActionType      `yaml:"action" json:"action"`
 	StartTime     time.Time           `yaml:"start_time" json:"startTime"`
 	EndTime       time.Time           `yaml:"end_time" json:"endTime"`
 	Status        Status              `yaml:"status" json:"status"`
 	FailureReason errors.EncodedError `yaml:"reason" json:"reason"`
 	Metadata      map[string][]byte   `yaml:"metadata" json:"metadata"`
+	Severity      Severity            `yaml:"severity,omitempty" json:"severity,omitempty"`
+	Group         string              `yaml:"group,omitempty" json:"group,omitempty"`
+	Phase         string              `yaml:"phase,omitempty" json:"phase,omitempty"`
+
+	// Attempts is the number of times the run action was attempted as per the RetryPolicy of the step
+	// AttemptErrors contains the errors of the failed attempts that were retried, the last error is in FailureReason.
+	Attempts      int      `yaml:"attempts,omitempty" json:"attempts,omitempty"`
+	AttemptErrors []string `yaml:"attempt_errors,omitempty" json:"attemptErrors,omitempty"`
+
+	// ClockSkew is the offset of the clock that reported StartTime and EndTime, they were shifted by it to the clock
+	// of the workflow, see NormalizeClock and WithClockSkewTolerance
+	ClockSkew time.Duration `yaml:"clock_skew,omitempty" json:"clockSkew,omitempty"`
+
+	// StateSize is the size in bytes of the serialized Outputs, Metadata and Extra, see WithStateSizeLimits
+	StateSize int `yaml:"state_size,omitempty" json:"stateSize,omitempty"`
+
+	// Outputs contains the results of the step that are to be exposed in WorkflowReport.Outputs
+	// e.g. path of a generated file or the version of an installed tool
+	Outputs map[string][]byte `yaml:"outputs" json:"outputs"`
+
+	// Costs contains the costs incurred by the action of the step, see AddCost
+	Costs Costs `yaml:"costs,omitempty" json:"costs,omitempty"`
+
+	// Extra contains typed domain specific data of the step, e.g. installed version or number of migrations
+	// Values are to be set using SetExtra so that the report remains serializable.
+	Extra map[string]interface{} `yaml:"extra,omitempty" json:"extra,omitempty"`
+}
+
+// SetExtra sets a typed custom value in the StepReport
+// It returns error if the key is empty or the value cannot be encoded as JSON and gob, see SetExtra to set a value from
+// SagaRun and SagaUndo.
+func (sr *StepReport) SetExtra(key string, value interface{}) error {
+	if err := validateExtra(key, value); err != nil {
+		return err
+	}
+
+	if sr.Extra == nil {
+		sr.Extra = map[string]interface{}{}
+	}
+
+	sr.Extra[key] = value
+
+	return nil
+}
+
+// SetExtra sets a typed custom value in the report of the action of the step being executed
+// It is meant to be called from SagaRun and SagaUndo, the value is added to StepReport.Extra once the action completes.
+// It returns error if the context doesn't belong to a workflow step or the value is not serializable, see
+// StepReport.SetExtra.
+func SetExtra(ctx context.Context, key string, value interface{}) error {
+	if err := validateExtra(key, value); err != nil {
+		return err
+	}
+
+	e, ok := ctx.Value(ctxKeyStepExtras).(*stepExtras)
+	if !ok || e == nil {
+		return errors.Newf("extra %q cannot be set outside of a workflow step", key)
+	}
+
+	e.set(key, value)
+
+	return nil
+}
+
+func init() {
+	// generic values decoded from JSON or YAML are valid extra values
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// validateExtra returns error if the key is empty or the value cannot be encoded as JSON and gob
+// Reports are serialized as JSON or YAML, while gob is used by the consumers that persist them as binary.
+func validateExtra(key string, value interface{}) error {
+	if key == "" {
+		return errors.New("extra key cannot be empty")
+	}
+
+	if _, err := json.Marshal(value); err != nil {
+		return errors.Wrapf(err, "extra value for key %q is not serializable", key)
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	if err := gob.NewEncoder(io.Discard).Encode(value); err != nil {
+		return errors.Wrapf(err, "extra value for key %q is not serializable", key)
+	}
+
+	return nil
+}
+
+// stepExtras collects the extra values set by an action of a step that may be set concurrently
+type stepExtras struct {
+	mutex  sync.Mutex
+	values map[string]interface{}
+}
+
+// set sets the extra value
+func (e *stepExtras) set(key string, value interface{}) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.values == nil {
+		e.values = map[string]interface{}{}
+	}
+
+	e.values[key] = value
+}
+
+// addTo adds the collected extra values to the report of the step
+func (e *stepExtras) addTo(report *StepReport) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for key, value := range e.values {
+		if report.Extra == nil {
+			report.Extra = map[string]interface{}{}
+		}
+
+		report.Extra[key] = value
+	}
+}
+
+// withStepExtras returns a copy of the context with the given stepExtras
+func withStepExtras(ctx context.Context, e *stepExtras) context.Context {
+	return context.WithValue(ctx, ctxKeyStepExtras, e)
+}
+
+// Clone returns a deep copy of the StepReport
+// Values in Extra are copied shallowly since they are expected to be immutable once set.
+func (sr *StepReport) Clone() *StepReport {
+	if sr == nil {
+		return nil
+	}
+
+	c := *sr
+	c.Metadata = cloneBytesMap(sr.Metadata)
+	c.Outputs = cloneBytesMap(sr.Outputs)
+	c.Costs = sr.Costs.Clone()
+	if sr.AttemptErrors != nil {
+		c.AttemptErrors = append([]string{}, sr.AttemptErrors...)
+	}
+
+	if sr.Extra != nil {
+		c.Extra = make(map[string]interface{}, len(sr.Extra))
+		for key, val := range sr.Extra {
+			c.Extra[key] = val
+		}
+	}
+
+	return &c
+}
+
+// Clone returns a deep copy of the WorkflowReport
+// WorkflowReport is passed by value but its StepReports are shared pointers, therefore consumers that keep or mutate
+// a report while the workflow may still update it, e.g. callbacks, must use a clone.
+func (wfr *WorkflowReport) Clone() WorkflowReport {
+	c := *wfr
+	c.Outputs = cloneBytesMap(wfr.Outputs)
+	c.Costs = wfr.Costs.Clone()
+
+	if wfr.StepSequence != nil {
+		c.StepSequence = append(StepIDs{}, wfr.StepSequence...)
+	}
+
+	if wfr.StepReports != nil {
+		c.StepReports = make([]*StepReport, len(wfr.StepReports))
+		for i, stepReport := range wfr.StepReports {
+			c.StepReports[i] = stepReport.Clone()
+		}
+	}
+
+	c.Groups = cloneSummaries(wfr.Groups)
+	c.Phases = cloneSummaries(wfr.Phases)
+
+	if wfr.ResourceCleanups != nil {
+		c.ResourceCleanups = make([]*ResourceCleanup, len(wfr.ResourceCleanups))
+		for i, cleanup := range wfr.ResourceCleanups {
+			rc := *cleanup
+			c.ResourceCleanups[i] = &rc
+		}
+	}
+
+	if wfr.Panics != nil {
+		c.Panics = make([]*PanicInfo, len(wfr.Panics))
+		for i, info := range wfr.Panics {
+			pi := *info
+			c.Panics[i] = &pi
+		}
+	}
+
+	if wfr.Warnings != nil {
+		c.Warnings = append([]string{}, wfr.Warnings...)
+	}
+
+	if wfr.Diagnostics.SinkErrors != nil {
+		c.Diagnostics.SinkErrors = make([]*SinkError, len(wfr.Diagnostics.SinkErrors))
+		for i, sinkErr := range wfr.Diagnostics.SinkErrors {
+			se := *sinkErr
+			c.Diagnostics.SinkErrors[i] = &se
+		}
+	}
+
+	if wfr.Diagnostics.LeakedGoroutines != nil {
+		c.Diagnostics.LeakedGoroutines = make([]*GoroutineLeak, len(wfr.Diagnostics.LeakedGoroutines))
+		for i, leak := range wfr.Diagnostics.LeakedGoroutines {
+			gl := *leak
+			c.Diagnostics.LeakedGoroutines[i] = &gl
+		}
+	}
+
+	if wfr.CallbackFailures != nil {
+		c.CallbackFailures = make([]*CallbackFailure, len(wfr.CallbackFailures))
+		for i, failure := range wfr.CallbackFailures {
+			cf := *failure
+			c.CallbackFailures[i] = &cf
+		}
+	}
+
+	return c
+}
+
+// cloneSummaries returns a deep copy of the list of GroupSummary
+func cloneSummaries(summaries []*GroupSummary) []*GroupSummary {
+	if summaries == nil {
+		return nil
+	}
+
+	c := make([]*GroupSummary, len(summaries))
+	for i, summary := range summaries {
+		s := *summary
+		s.StepIDs = append(StepIDs{}, summary.StepIDs...)
+		s.Costs = summary.Costs.Clone()
+		c[i] = &s
+	}
+
+	return c
+}
+
+// cloneBytesMap returns a deep copy of the map
+func cloneBytesMap(m map[string][]byte) map[string][]byte {
+	if m == nil {
+		return nil
+	}
+
+	c := make(map[string][]byte, len(m))
+	for key, val := range m {
+		c[key] = append([]byte(nil), val...)
+	}
+
+	return c
 }
 
 // Append appends the current report to the previous report
@@ -48,6 +340,212 @@ func (wfr *WorkflowReport) Append(stepReport *StepReport, action StepActionType,
 	wfr.StepReports = append(wfr.StepReports, stepReport)
 }
 
+// collectOutputs populates Outputs from the outputs of the successful RunAction of every step
+// If multiple steps publish the same key, the value from the later step takes precedence.
+func (wfr *WorkflowReport) collectOutputs() {
+	wfr.Outputs = map[string][]byte{}
+	for _, stepReport := range wfr.StepReports {
+		if stepReport.Action != RunAction || stepReport.Status != StatusSuccess {
+			continue
+		}
+
+		for key, val := range stepReport.Outputs {
+			wfr.Outputs[key] = val
+		}
+	}
+}
+
+// PublishOutput publishes the output of the step being executed
+// It is meant to be called from SagaRun, the value is added to StepReport.Outputs once SagaRun succeeds and therefore to
+// WorkflowReport.Outputs. It returns error if the context doesn't belong to the SagaRun of a step.
+func PublishOutput(ctx context.Context, key string, val []byte) error {
+	sio, ok := ctx.Value(ctxKeyStepIO).(*stepIO)
+	if !ok || sio == nil {
+		return errors.Newf("output %q cannot be set outside of the SagaRun of a step", key)
+	}
+
+	sio.set(key, append([]byte(nil), val...))
+
+	return nil
+}
+
+// hasFailedRun returns true if the RunAction of any step has StatusFailed or StatusTimedOut
+func (wfr *WorkflowReport) hasFailedRun() bool {
+	for _, stepReport := range wfr.StepReports {
+		if stepReport.Action == RunAction && isFailure(stepReport.Status) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compensated returns true if the run of the step failed and the step was already rolled back, see CompensatedRun
+func (wfr *WorkflowReport) compensated(stepID string) bool {
+	failed, rolledBack := false, false
+	for _, stepReport := range wfr.StepReports {
+		if stepReport.StepID != stepID {
+			continue
+		}
+
+		if stepReport.Action == RunAction && isFailure(stepReport.Status) {
+			failed = true
+		} else if stepReport.Action == RollbackAction {
+			rolledBack = true
+		}
+	}
+
+	return failed && rolledBack
+}
+
+// memoized returns true if the run of the occurrence of the step being rolled back reused the result of an earlier
+// execution, see Step.WithMemoize
+// Occurrences are rolled back in reverse order, therefore the occurrence is the last run not rolled back yet.
+func (wfr *WorkflowReport) memoized(stepID string) bool {
+	var runs []*StepReport
+	rollbacks := 0
+	for _, stepReport := range wfr.StepReports {
+		if stepReport.StepID != stepID {
+			continue
+		}
+
+		if stepReport.Action == RunAction {
+			runs = append(runs, stepReport)
+		} else if stepReport.Action == RollbackAction {
+			rollbacks++
+		}
+	}
+
+	i := len(runs) - 1 - rollbacks
+	if i < 0 {
+		return false
+	}
+
+	return string(runs[i].Metadata[MemoizedMetadataKey]) == "true"
+}
+
+// HardFailures returns the IDs of the steps whose RunAction failed with SeverityCritical
+// Steps without any Severity in the report are considered as SeverityCritical.
+func (wfr *WorkflowReport) HardFailures() StepIDs {
+	var ids StepIDs
+	for _, stepReport := range wfr.StepReports {
+		if stepReport.Action == RunAction && isFailure(stepReport.Status) &&
+			(stepReport.Severity == "" || stepReport.Severity == SeverityCritical) {
+			ids = append(ids, stepReport.StepID)
+		}
+	}
+
+	return ids
+}
+
+// ToleratedFailures returns the IDs of the steps whose RunAction failed with SeverityWarning or SeverityInfo
+func (wfr *WorkflowReport) ToleratedFailures() StepIDs {
+	var ids StepIDs
+	for _, stepReport := range wfr.StepReports {
+		if stepReport.Action == RunAction && isFailure(stepReport.Status) &&
+			stepReport.Severity != "" && stepReport.Severity != SeverityCritical {
+			ids = append(ids, stepReport.StepID)
+		}
+	}
+
+	return ids
+}
+
+// GroupSummary defines the aggregated status and duration of the step reports of a group
+// Status is StatusFailed if any step action of the group failed, StatusSuccess if any succeeded and StatusSkipped
+// otherwise.
+type GroupSummary struct {
+	Group     string        `yaml:"group" json:"group"`
+	Status    Status        `yaml:"status" json:"status"`
+	StartTime time.Time     `yaml:"start_time" json:"startTime"`
+	EndTime   time.Time     `yaml:"end_time" json:"endTime"`
+	Duration  time.Duration `yaml:"duration" json:"duration"`
+	StepIDs   StepIDs       `yaml:"step_ids" json:"stepIDs"`
+
+	// Costs contains the total of the costs of the step reports of the group
+	Costs Costs `yaml:"costs,omitempty" json:"costs,omitempty"`
+}
+
+// summarizeGroups populates Groups from the step reports having a group
+func (wfr *WorkflowReport) summarizeGroups() {
+	wfr.Groups = wfr.summarize(func(stepReport *StepReport) string {
+		return stepReport.Group
+	})
+}
+
+// summarizePhases populates Phases from the step reports having a phase
+func (wfr *WorkflowReport) summarizePhases() {
+	wfr.Phases = wfr.summarize(func(stepReport *StepReport) string {
+		return stepReport.Phase
+	})
+}
+
+// summarize returns the summaries of the step reports by the given label in the order of their first execution
+// Step reports with an empty label are ignored. A summary is StatusFailed if any step failed, StatusCancelled if any
+// step was cancelled otherwise, StatusSuccess if any step succeeded otherwise and StatusSkipped if none did.
+func (wfr *WorkflowReport) summarize(label func(stepReport *StepReport) string) []*GroupSummary {
+	var list []*GroupSummary
+	summaries := map[string]*GroupSummary{}
+	for _, stepReport := range wfr.StepReports {
+		name := label(stepReport)
+		if name == "" {
+			continue
+		}
+
+		summary, ok := summaries[name]
+		if !ok {
+			summary = &GroupSummary{
+				Group:     name,
+				Status:    StatusSkipped,
+				StartTime: stepReport.StartTime,
+				EndTime:   stepReport.EndTime,
+			}
+			summaries[name] = summary
+			list = append(list, summary)
+		}
+
+		if stepReport.StartTime.Before(summary.StartTime) {
+			summary.StartTime = stepReport.StartTime
+		}
+
+		if stepReport.EndTime.After(summary.EndTime) {
+			summary.EndTime = stepReport.EndTime
+		}
+
+		summary.Duration = summary.EndTime.Sub(summary.StartTime)
+
+		if len(stepReport.Costs) > 0 {
+			if summary.Costs == nil {
+				summary.Costs = Costs{}
+			}
+
+			summary.Costs.Add(stepReport.Costs)
+		}
+
+		if isFailure(stepReport.Status) {
+			summary.Status = StatusFailed
+		} else if stepReport.Status == StatusCancelled && summary.Status != StatusFailed {
+			summary.Status = StatusCancelled
+		} else if stepReport.Status == StatusSuccess && summary.Status == StatusSkipped {
+			summary.Status = StatusSuccess
+		}
+
+		found := false
+		for _, id := range summary.StepIDs {
+			if id == stepReport.StepID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			summary.StepIDs = append(summary.StepIDs, stepReport.StepID)
+		}
+	}
+
+	return list
+}
+
 // NewWorkflowReport returns an instance of WorkflowReport
 func NewWorkflowReport(id string, steps StepIDs) *WorkflowReport {
 	return &WorkflowReport{
@@ -57,6 +555,7 @@ func NewWorkflowReport(id string, steps StepIDs) *WorkflowReport {
 		Status:       StatusUndefined,
 		StepSequence: steps,
 		StepReports:  []*StepReport{},
+		Outputs:      map[string][]byte{},
 	}
 }
 
@@ -69,7 +568,77 @@ func NewStepReport(id string, action StepActionType) *StepReport {
 		Status:        StatusUndefined,
 		FailureReason: errors.EncodedError{},
 		Metadata:      map[string][]byte{},
+		Outputs:       map[string][]byte{},
 	}
 
 	return r
 }
+
+// OutcomeSpec defines the expected outcome of a workflow run
+// It is used with WorkflowReport.Conforms in order to detect runs that quietly skipped or failed critical steps.
+type OutcomeSpec struct {
+	// MustSucceed is the list of steps whose RunAction must have StatusSuccess
+	MustSucceed StepIDs `yaml:"must_succeed" json:"mustSucceed"`
+
+	// AllowedSkips is the list of steps whose RunAction is allowed to have StatusSkipped
+	// Any other skipped step is reported as a violation.
+	AllowedSkips StepIDs `yaml:"allowed_skips" json:"allowedSkips"`
+}
+
+// Violation defines a deviation of a WorkflowReport from an OutcomeSpec
+type Violation struct {
+	StepID string `yaml:"step_id" json:"stepID"`
+	Reason string `yaml:"reason" json:"reason"`
+}
+
+// String returns a human-readable description of the Violation
+func (v Violation) String() string {
+	return fmt.Sprintf("step %q: %s", v.StepID, v.Reason)
+}
+
+// Conforms compares the report against the given OutcomeSpec and returns the list of violations
+// An empty list means that the report conforms to the spec. Every run StepReport is checked, including the members of
+// parallel groups and the steps of phases, and a step of the spec without any run StepReport is a violation as well.
+func (wfr *WorkflowReport) Conforms(spec OutcomeSpec) []Violation {
+	runStatus := map[string]Status{}
+	var runSequence StepIDs
+	for _, stepReport := range wfr.StepReports {
+		if stepReport.Action == RunAction {
+			if _, ok := runStatus[stepReport.StepID]; !ok {
+				runSequence = append(runSequence, stepReport.StepID)
+			}
+			runStatus[stepReport.StepID] = stepReport.Status
+		}
+	}
+
+	violations := []Violation{}
+	for _, id := range spec.MustSucceed {
+		status, ok := runStatus[id]
+		if !ok {
+			violations = append(violations, Violation{StepID: id, Reason: "step was not executed"})
+		} else if status != StatusSuccess {
+			violations = append(violations, Violation{StepID: id, Reason: fmt.Sprintf("expected status %s, found %s", StatusSuccess, status)})
+		}
+	}
+
+	known := map[string]bool{}
+	for _, id := range wfr.StepSequence {
+		known[id] = true
+	}
+
+	allowedSkips := map[string]bool{}
+	for _, id := range spec.AllowedSkips {
+		allowedSkips[id] = true
+		if _, ok := runStatus[id]; !ok && !known[id] {
+			violations = append(violations, Violation{StepID: id, Reason: "step is not part of the run"})
+		}
+	}
+
+	for _, id := range runSequence {
+		if runStatus[id] == StatusSkipped && !allowedSkips[id] {
+			violations = append(violations, Violation{StepID: id, Reason: "step was skipped"})
+		}
+	}
+
+	return violations
+}
@@ -1,9 +1,13 @@
 package automa
 
 import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 	"testing"
+	"time"
 )
 
 func TestWorkflowReport_Append(t *testing.T) {
@@ -33,3 +37,241 @@ func TestReportYAML(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, out)
 }
+
+func TestWorkflowReport_collectOutputs(t *testing.T) {
+	workflowReport := NewWorkflowReport("test", nil)
+
+	stepReport1 := NewStepReport("step-1", RunAction)
+	stepReport1.Outputs["kubeconfig"] = []byte("/tmp/kubeconfig")
+	stepReport1.Outputs["version"] = []byte("v1.0.0")
+	workflowReport.Append(stepReport1, RunAction, StatusSuccess)
+
+	stepReport2 := NewStepReport("step-2", RunAction)
+	stepReport2.Outputs["version"] = []byte("v1.1.0")
+	workflowReport.Append(stepReport2, RunAction, StatusSuccess)
+
+	stepReport3 := NewStepReport("step-3", RunAction)
+	stepReport3.Outputs["ignored"] = []byte("skipped")
+	workflowReport.Append(stepReport3, RunAction, StatusSkipped)
+
+	workflowReport.collectOutputs()
+	assert.Equal(t, 2, len(workflowReport.Outputs))
+	assert.Equal(t, []byte("/tmp/kubeconfig"), workflowReport.Outputs["kubeconfig"])
+	assert.Equal(t, []byte("v1.1.0"), workflowReport.Outputs["version"])
+}
+
+func TestPublishOutput(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "create_cluster"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		assert.NoError(t, PublishOutput(ctx, "kubeconfig", []byte("/tmp/kubeconfig")))
+		return false, PublishOutput(ctx, "version", []byte("v1.0.0"))
+	}, nil)
+
+	s2 := &Step{ID: "upgrade_cluster"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, PublishOutput(ctx, "version", []byte("v1.1.0"))
+	}, nil)
+
+	s3 := &Step{ID: "skipped_step"}
+	s3.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		assert.NoError(t, PublishOutput(ctx, "ignored", []byte("skipped")))
+		return true, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2, s3))
+	defer workflow.End(ctx)
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"kubeconfig": []byte("/tmp/kubeconfig"),
+		"version":    []byte("v1.1.0"),
+	}, report.Outputs)
+	assert.Equal(t, []byte("v1.0.0"), report.StepReports[0].Outputs["version"])
+
+	// outputs cannot be published outside of a step
+	assert.Error(t, PublishOutput(ctx, "version", []byte("v1.0.0")))
+}
+
+func TestWorkflowReport_Conforms(t *testing.T) {
+	workflowReport := NewWorkflowReport("test", StepIDs{"step-1", "step-2", "step-3"})
+	workflowReport.Append(NewStepReport("step-1", RunAction), RunAction, StatusSuccess)
+	workflowReport.Append(NewStepReport("step-2", RunAction), RunAction, StatusSkipped)
+	workflowReport.Append(NewStepReport("step-3", RunAction), RunAction, StatusSkipped)
+
+	violations := workflowReport.Conforms(OutcomeSpec{
+		MustSucceed:  StepIDs{"step-1"},
+		AllowedSkips: StepIDs{"step-2", "step-3"},
+	})
+	assert.Empty(t, violations)
+
+	violations = workflowReport.Conforms(OutcomeSpec{
+		MustSucceed:  StepIDs{"step-1", "step-3", "step-4"},
+		AllowedSkips: StepIDs{"step-2"},
+	})
+	assert.Equal(t, 3, len(violations))
+	assert.Equal(t, "step-3", violations[0].StepID)
+	assert.Equal(t, "step-4", violations[1].StepID)
+	assert.Equal(t, "step-3", violations[2].StepID)
+	assert.Equal(t, "step \"step-4\": step was not executed", violations[1].String())
+
+	// members of parallel groups are not part of the step sequence but are checked as well
+	groupReport := NewWorkflowReport("test", StepIDs{"group-1"})
+	member1 := NewStepReport("member-1", RunAction)
+	member1.Group = "group-1"
+	groupReport.Append(member1, RunAction, StatusSkipped)
+	member2 := NewStepReport("member-2", RunAction)
+	member2.Group = "group-1"
+	groupReport.Append(member2, RunAction, StatusFailed)
+
+	violations = groupReport.Conforms(OutcomeSpec{
+		MustSucceed:  StepIDs{"member-2"},
+		AllowedSkips: StepIDs{"member-3"},
+	})
+	assert.Equal(t, []Violation{
+		{StepID: "member-2", Reason: fmt.Sprintf("expected status %s, found %s", StatusSuccess, StatusFailed)},
+		{StepID: "member-3", Reason: "step is not part of the run"},
+		{StepID: "member-1", Reason: "step was skipped"},
+	}, violations)
+}
+
+func TestStepReport_SetExtra(t *testing.T) {
+	stepReport := NewStepReport("step-1", RunAction)
+	assert.NoError(t, stepReport.SetExtra("version", "v1.2.0"))
+	assert.NoError(t, stepReport.SetExtra("migrations", 3))
+	assert.Error(t, stepReport.SetExtra("", 1))
+	assert.Error(t, stepReport.SetExtra("invalid", make(chan int)))
+	assert.Error(t, stepReport.SetExtra("not_gob", struct{ version string }{version: "v1.2.0"}))
+	assert.Equal(t, 2, len(stepReport.Extra))
+	assert.Equal(t, 3, stepReport.Extra["migrations"])
+
+	stepReport = &StepReport{}
+	assert.NoError(t, stepReport.SetExtra("version", "v1.2.0"))
+	assert.Equal(t, "v1.2.0", stepReport.Extra["version"])
+}
+
+func TestSetExtra(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "migrate_db"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		assert.Error(t, SetExtra(ctx, "invalid", make(chan int)))
+		return false, SetExtra(ctx, "migrations", 3)
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return false, SetExtra(ctx, "reverted", 3)
+	})
+
+	s2 := &Step{ID: "fail"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	defer workflow.End(ctx)
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, map[string]interface{}{"migrations": 3}, report.StepReports[0].Extra)
+	rollbackReport := report.StepReports[len(report.StepReports)-1]
+	assert.Equal(t, RollbackAction, rollbackReport.Action)
+	assert.Equal(t, map[string]interface{}{"reverted": 3}, rollbackReport.Extra)
+
+	// extra values cannot be set outside of a step
+	assert.Error(t, SetExtra(ctx, "migrations", 3))
+}
+
+func TestWorkflowReport_summarizeGroups(t *testing.T) {
+	start := time.Now()
+	newReport := func(id string, group string, action StepActionType, status Status, offset time.Duration) *StepReport {
+		r := NewStepReport(id, action)
+		r.Group = group
+		r.Status = status
+		r.StartTime = start.Add(offset)
+		r.EndTime = start.Add(offset + time.Second)
+		return r
+	}
+
+	report := NewWorkflowReport("test", nil)
+	report.StepReports = []*StepReport{
+		newReport("create_vpc", "networking", RunAction, StatusSuccess, 0),
+		newReport("create_subnet", "networking", RunAction, StatusSuccess, time.Second),
+		newReport("check_disk", "", RunAction, StatusSuccess, 2*time.Second),
+		newReport("create_db", "storage", RunAction, StatusFailed, 3*time.Second),
+		newReport("create_bucket", "storage", RunAction, StatusSkipped, 4*time.Second),
+		newReport("create_subnet", "networking", RollbackAction, StatusSuccess, 5*time.Second),
+		newReport("create_vpc", "networking", RollbackAction, StatusSuccess, 6*time.Second),
+	}
+
+	report.summarizeGroups()
+	assert.Equal(t, 2, len(report.Groups))
+
+	networking := report.Groups[0]
+	assert.Equal(t, "networking", networking.Group)
+	assert.Equal(t, StatusSuccess, networking.Status)
+	assert.Equal(t, StepIDs{"create_vpc", "create_subnet"}, networking.StepIDs)
+	assert.Equal(t, start, networking.StartTime)
+	assert.Equal(t, 7*time.Second, networking.Duration)
+
+	storage := report.Groups[1]
+	assert.Equal(t, "storage", storage.Group)
+	assert.Equal(t, StatusFailed, storage.Status)
+	assert.Equal(t, 2*time.Second, storage.Duration)
+
+	// a cancelled step is not summarized as a success
+	report.StepReports = []*StepReport{
+		newReport("create_vpc", "networking", RunAction, StatusSuccess, 0),
+		newReport("create_subnet", "networking", RunAction, StatusCancelled, time.Second),
+		newReport("create_db", "storage", RunAction, StatusFailed, 2*time.Second),
+		newReport("create_bucket", "storage", RunAction, StatusCancelled, 3*time.Second),
+		newReport("create_vpc", "networking", RollbackAction, StatusSuccess, 4*time.Second),
+	}
+	report.StepReports[1].Phase = "install"
+	report.StepReports[2].Phase = "install"
+
+	report.summarizeGroups()
+	assert.Equal(t, StatusCancelled, report.Groups[0].Status)
+	assert.Equal(t, StatusFailed, report.Groups[1].Status)
+
+	report.summarizePhases()
+	assert.Equal(t, 1, len(report.Phases))
+	assert.Equal(t, StatusFailed, report.Phases[0].Status)
+	report.StepReports[2].Phase = ""
+	report.summarizePhases()
+	assert.Equal(t, StatusCancelled, report.Phases[0].Status)
+}
+
+func TestWorkflowReport_Clone(t *testing.T) {
+	report := NewWorkflowReport("test", StepIDs{"step_1"})
+	stepReport := NewStepReport("step_1", RunAction)
+	stepReport.Metadata["key"] = []byte("value")
+	stepReport.AttemptErrors = []string{"mock error"}
+	assert.NoError(t, stepReport.SetExtra("version", "1.0"))
+	report.StepReports = append(report.StepReports, stepReport)
+	report.Outputs["out"] = []byte("value")
+	report.Warnings = []string{"warning"}
+	report.Groups = []*GroupSummary{{Group: "networking", StepIDs: StepIDs{"step_1"}}}
+
+	clone := report.Clone()
+	assert.Equal(t, *report, clone)
+
+	clone.StepReports[0].Status = StatusFailed
+	clone.StepReports[0].Metadata["key"][0] = 'V'
+	clone.StepReports[0].AttemptErrors[0] = "changed"
+	clone.StepReports[0].Extra["version"] = "2.0"
+	clone.Outputs["out"] = []byte("changed")
+	clone.Warnings[0] = "changed"
+	clone.Groups[0].StepIDs[0] = "changed"
+	clone.StepSequence[0] = "changed"
+
+	assert.Equal(t, StatusUndefined, stepReport.Status)
+	assert.Equal(t, []byte("value"), stepReport.Metadata["key"])
+	assert.Equal(t, "mock error", stepReport.AttemptErrors[0])
+	assert.Equal(t, "1.0", stepReport.Extra["version"])
+	assert.Equal(t, []byte("value"), report.Outputs["out"])
+	assert.Equal(t, "warning", report.Warnings[0])
+	assert.Equal(t, "step_1", report.Groups[0].StepIDs[0])
+	assert.Equal(t, "step_1", report.StepSequence[0])
+
+	var nilReport *StepReport
+	assert.Nil(t, nilReport.Clone())
+}
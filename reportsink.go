@@ -0,0 +1,286 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReportSink delivers the reports of the workflow runs to an external system, e.g. an HTTP endpoint or a Kafka topic
+// Deliver must eventually call either Ack or Nack of the delivery, possibly asynchronously from another goroutine,
+// e.g. when the broker acknowledges the message. A delivery that is neither acked nor nacked within the AckTimeout of
+// the ReportSinkOptions is considered as nacked.
+type ReportSink interface {
+	Deliver(ctx context.Context, d *ReportDelivery)
+}
+
+// ReportDelivery is a delivery attempt of a report to a ReportSink
+type ReportDelivery struct {
+	Report  WorkflowReport
+	Attempt int
+
+	once   sync.Once
+	result chan error
+}
+
+// newReportDelivery returns a ReportDelivery waiting for its Ack or Nack
+func newReportDelivery(report WorkflowReport, attempt int) *ReportDelivery {
+	return &ReportDelivery{Report: report, Attempt: attempt, result: make(chan error, 1)}
+}
+
+// Ack acknowledges the delivery of the report
+// Only the first call of Ack or Nack is taken into account.
+func (d *ReportDelivery) Ack() {
+	d.once.Do(func() {
+		d.result <- nil
+	})
+}
+
+// Nack rejects the delivery of the report so that it is retried as per the MaxAttempts of the ReportSinkOptions
+// Only the first call of Ack or Nack is taken into account.
+func (d *ReportDelivery) Nack(err error) {
+	if err == nil {
+		err = errors.New("report delivery rejected")
+	}
+
+	d.once.Do(func() {
+		d.result <- err
+	})
+}
+
+// ReportSinkOptions defines the buffering and the retries of the deliveries to a ReportSink
+// BufferSize, MaxAttempts and AckTimeout are set as 1, 1 and 30s respectively if a non-positive value is provided.
+type ReportSinkOptions struct {
+	// BufferSize is the maximum number of reports waiting to be delivered
+	BufferSize int
+
+	// Policy defines the behaviour when the buffer is full at the end of a run, see BackpressurePolicy
+	Policy BackpressurePolicy
+
+	// BlockTimeout is the maximum duration the end of a run is blocked by a full buffer with BlockOnFull policy
+	// The report is dropped once it elapses. Zero means that the run is blocked until its context is done.
+	BlockTimeout time.Duration
+
+	// MaxAttempts is the maximum number of deliveries of a report that is nacked before it is dropped
+	MaxAttempts int
+
+	// AckTimeout is the maximum duration to wait for the Ack or Nack of a delivery
+	AckTimeout time.Duration
+
+	// Backoff returns the delay before the next delivery of a nacked report, nil retries immediately
+	Backoff Backoff
+}
+
+// ReportSinkStats defines the counters of the deliveries to the ReportSink of a Workflow
+type ReportSinkStats struct {
+	// Delivered is the number of reports acked by the sink
+	Delivered uint64 `yaml:"delivered" json:"delivered"`
+
+	// Dropped is the number of reports dropped since the buffer was full or the context of End was done before they
+	// were delivered
+	Dropped uint64 `yaml:"dropped" json:"dropped"`
+
+	// Failed is the number of reports dropped after MaxAttempts deliveries were nacked or timed out
+	Failed uint64 `yaml:"failed" json:"failed"`
+
+	// Nacked is the number of deliveries that were nacked or timed out, including the ones that were retried
+	Nacked uint64 `yaml:"nacked" json:"nacked"`
+}
+
+// WithReportSink allows the report of every run of the Workflow to be delivered to the sink
+// Reports are delivered in order by a background worker through a bounded buffer, so that a slow sink never blocks
+// the steps. If the buffer is full at the end of a run, the run is either blocked or the report is dropped as per
+// the Policy of the options, a dropped report being recorded in WorkflowReport.Diagnostics. Pending reports are
+// delivered by End until its context is done.
+func WithReportSink(sink ReportSink, opts ReportSinkOptions) WorkflowOption {
+	return func(wf *Workflow) {
+		if opts.BufferSize <= 0 {
+			opts.BufferSize = 1
+		}
+
+		if opts.MaxAttempts <= 0 {
+			opts.MaxAttempts = 1
+		}
+
+		if opts.AckTimeout <= 0 {
+			opts.AckTimeout = 30 * time.Second
+		}
+
+		wf.reportSink = sink
+		wf.reportSinkOptions = opts
+		wf.reportSinkStats = &ReportSinkStats{}
+	}
+}
+
+// ReportSinkStats returns the counters of the deliveries to the ReportSink of the Workflow
+func (wf *Workflow) ReportSinkStats() ReportSinkStats {
+	if wf.reportSinkStats == nil {
+		return ReportSinkStats{}
+	}
+
+	return ReportSinkStats{
+		Delivered: atomic.LoadUint64(&wf.reportSinkStats.Delivered),
+		Dropped:   atomic.LoadUint64(&wf.reportSinkStats.Dropped),
+		Failed:    atomic.LoadUint64(&wf.reportSinkStats.Failed),
+		Nacked:    atomic.LoadUint64(&wf.reportSinkStats.Nacked),
+	}
+}
+
+// reportSinkBuffer delivers reports to a ReportSink from a bounded buffer using a single worker
+type reportSinkBuffer struct {
+	sink  ReportSink
+	opts  ReportSinkOptions
+	stats *ReportSinkStats
+	queue chan WorkflowReport
+	abort chan struct{}
+	wg    sync.WaitGroup
+}
+
+// newReportSinkBuffer returns a reportSinkBuffer with its worker started
+func newReportSinkBuffer(sink ReportSink, opts ReportSinkOptions, stats *ReportSinkStats) *reportSinkBuffer {
+	b := &reportSinkBuffer{
+		sink:  sink,
+		opts:  opts,
+		stats: stats,
+		queue: make(chan WorkflowReport, opts.BufferSize),
+		abort: make(chan struct{}),
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for report := range b.queue {
+			if b.aborted() {
+				atomic.AddUint64(&b.stats.Dropped, 1)
+				continue
+			}
+
+			b.deliver(report)
+		}
+	}()
+
+	return b
+}
+
+// enqueue adds the report to the buffer as per the BackpressurePolicy of the options
+// It returns an error if the report was dropped.
+func (b *reportSinkBuffer) enqueue(ctx context.Context, report WorkflowReport) error {
+	if b.opts.Policy == DropOnFull {
+		select {
+		case b.queue <- report:
+			return nil
+		default:
+			atomic.AddUint64(&b.stats.Dropped, 1)
+			return errors.Newf("report of run %q dropped since the buffer is full", report.RunID)
+		}
+	}
+
+	var timeout <-chan time.Time
+	if b.opts.BlockTimeout > 0 {
+		timer := time.NewTimer(b.opts.BlockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case b.queue <- report:
+		return nil
+	case <-timeout:
+		atomic.AddUint64(&b.stats.Dropped, 1)
+		return errors.Newf("report of run %q dropped since the buffer is full after %s", report.RunID, b.opts.BlockTimeout)
+	case <-ctx.Done():
+		atomic.AddUint64(&b.stats.Dropped, 1)
+		return errors.Wrapf(ctx.Err(), "report of run %q dropped since the buffer is full", report.RunID)
+	}
+}
+
+// deliver delivers the report to the sink until it is acked or MaxAttempts deliveries failed
+// The report is dropped if the buffer is aborted before it is acked.
+func (b *reportSinkBuffer) deliver(report WorkflowReport) {
+	for attempt := 1; attempt <= b.opts.MaxAttempts; attempt++ {
+		err := b.deliverOnce(report, attempt)
+		if err == nil {
+			atomic.AddUint64(&b.stats.Delivered, 1)
+			return
+		}
+
+		if b.aborted() {
+			atomic.AddUint64(&b.stats.Dropped, 1)
+			return
+		}
+
+		atomic.AddUint64(&b.stats.Nacked, 1)
+		if attempt < b.opts.MaxAttempts && b.opts.Backoff != nil {
+			timer := time.NewTimer(b.opts.Backoff(attempt))
+			select {
+			case <-b.abort:
+				timer.Stop()
+				atomic.AddUint64(&b.stats.Dropped, 1)
+				return
+			case <-timer.C:
+			}
+		}
+	}
+
+	atomic.AddUint64(&b.stats.Failed, 1)
+}
+
+// deliverOnce delivers the report to the sink and waits for its Ack or Nack
+func (b *reportSinkBuffer) deliverOnce(report WorkflowReport, attempt int) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.opts.AckTimeout)
+	defer cancel()
+
+	d := newReportDelivery(report, attempt)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				d.Nack(errors.Newf("report sink panicked: %v", r))
+			}
+		}()
+
+		b.sink.Deliver(ctx, d)
+	}()
+
+	select {
+	case err = <-d.result:
+		return err
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "delivery of report of run %q was not acked", report.RunID)
+	case <-b.abort:
+		return errors.Newf("delivery of report of run %q was aborted", report.RunID)
+	}
+}
+
+// aborted returns true if the buffer was aborted by close
+func (b *reportSinkBuffer) aborted() bool {
+	select {
+	case <-b.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+// close delivers the pending reports and stops the worker
+// If the context is done first, the delivery in progress is abandoned and the pending reports are dropped.
+func (b *reportSinkBuffer) close(ctx context.Context) {
+	close(b.queue)
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	// the worker no longer waits for the sink once aborted, so that it drops the remaining reports right away
+	close(b.abort)
+	<-done
+}
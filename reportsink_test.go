@@ -0,0 +1,168 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockReportSink acks or nacks the deliveries as per its nack func and records the delivered reports
+type mockReportSink struct {
+	mutex    sync.Mutex
+	block    chan struct{}
+	nack     func(d *ReportDelivery) bool
+	attempts int
+	reports  []WorkflowReport
+}
+
+func (s *mockReportSink) Deliver(ctx context.Context, d *ReportDelivery) {
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attempts++
+
+	if s.nack != nil && s.nack(d) {
+		d.Nack(nil)
+		return
+	}
+
+	s.reports = append(s.reports, d.Report)
+	d.Ack()
+}
+
+func TestWithReportSink(t *testing.T) {
+	ctx := context.Background()
+	sink := &mockReportSink{}
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(&mockSecretStep{Step: Step{ID: "step_1"}}),
+		WithReportRedactor(maskSecrets),
+		WithReportSink(sink, ReportSinkOptions{BufferSize: 2}),
+	)
+
+	for i := 0; i < 3; i++ {
+		_, err := workflow.Start(ctx)
+		assert.NoError(t, err)
+	}
+	workflow.End(ctx)
+
+	assert.Equal(t, ReportSinkStats{Delivered: 3}, workflow.ReportSinkStats())
+	assert.Equal(t, 3, len(sink.reports))
+	assert.Equal(t, StatusSuccess, sink.reports[0].Status)
+	assert.Equal(t, []byte("****"), sink.reports[0].StepReports[0].Metadata["token"])
+}
+
+func TestWithReportSink_Retries(t *testing.T) {
+	ctx := context.Background()
+	sink := &mockReportSink{nack: func(d *ReportDelivery) bool {
+		return d.Attempt < 2
+	}}
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(&mockSecretStep{Step: Step{ID: "step_1"}}),
+		WithReportSink(sink, ReportSinkOptions{MaxAttempts: 2, Backoff: ConstantBackoff(time.Millisecond)}),
+	)
+
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	workflow.End(ctx)
+	assert.Equal(t, ReportSinkStats{Delivered: 1, Nacked: 1}, workflow.ReportSinkStats())
+	assert.Equal(t, 2, sink.attempts)
+
+	// a report nacked MaxAttempts times is dropped
+	sink = &mockReportSink{nack: func(d *ReportDelivery) bool { return true }}
+	workflow = NewWorkflow("workflow_1",
+		WithSteps(&mockSecretStep{Step: Step{ID: "step_1"}}),
+		WithReportSink(sink, ReportSinkOptions{MaxAttempts: 3}),
+	)
+
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	workflow.End(ctx)
+	assert.Equal(t, ReportSinkStats{Failed: 1, Nacked: 3}, workflow.ReportSinkStats())
+	assert.Equal(t, 3, sink.attempts)
+}
+
+func TestWithReportSink_AckTimeout(t *testing.T) {
+	ctx := context.Background()
+	block := make(chan struct{})
+	defer close(block)
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(&mockSecretStep{Step: Step{ID: "step_1"}}),
+		WithReportSink(&mockReportSink{block: block}, ReportSinkOptions{AckTimeout: 10 * time.Millisecond}),
+	)
+
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	workflow.End(ctx)
+	assert.Equal(t, ReportSinkStats{Failed: 1, Nacked: 1}, workflow.ReportSinkStats())
+}
+
+func TestWithReportSink_Backpressure(t *testing.T) {
+	ctx := context.Background()
+
+	// the worker is blocked by the first report and the second one fills the buffer
+	block := make(chan struct{})
+	sink := &mockReportSink{block: block}
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(&mockSecretStep{Step: Step{ID: "step_1"}}),
+		WithReportSink(sink, ReportSinkOptions{Policy: DropOnFull}),
+	)
+
+	var report WorkflowReport
+	var err error
+	assert.Eventually(t, func() bool {
+		report, err = workflow.Start(ctx)
+		return len(report.Diagnostics.SinkErrors) > 0
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "report_sink", report.Diagnostics.SinkErrors[0].Sink)
+	assert.Equal(t, uint64(1), workflow.ReportSinkStats().Dropped)
+	close(block)
+	workflow.End(ctx)
+	assert.Equal(t, ReportSinkStats{Delivered: 2, Dropped: 1}, workflow.ReportSinkStats())
+
+	// a blocked run drops the report once BlockTimeout elapses
+	block = make(chan struct{})
+	workflow = NewWorkflow("workflow_1",
+		WithSteps(&mockSecretStep{Step: Step{ID: "step_1"}}),
+		WithReportSink(&mockReportSink{block: block}, ReportSinkOptions{BlockTimeout: 10 * time.Millisecond}),
+	)
+
+	assert.Eventually(t, func() bool {
+		report, err = workflow.Start(ctx)
+		return len(report.Diagnostics.SinkErrors) > 0
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), workflow.ReportSinkStats().Dropped)
+	close(block)
+	workflow.End(ctx)
+	assert.Equal(t, ReportSinkStats{Delivered: 2, Dropped: 1}, workflow.ReportSinkStats())
+}
+
+func TestWithReportSink_EndContext(t *testing.T) {
+	ctx := context.Background()
+
+	// the worker is blocked by the first report and the second one waits in the buffer
+	block := make(chan struct{})
+	defer close(block)
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(&mockSecretStep{Step: Step{ID: "step_1"}}),
+		WithReportSink(&mockReportSink{block: block}, ReportSinkOptions{BufferSize: 2, MaxAttempts: 3}),
+	)
+
+	for i := 0; i < 2; i++ {
+		_, err := workflow.Start(ctx)
+		assert.NoError(t, err)
+	}
+
+	endCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	workflow.End(endCtx)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, ReportSinkStats{Dropped: 2}, workflow.ReportSinkStats())
+}
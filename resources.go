@@ -0,0 +1,109 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"sync"
+)
+
+// Resource describes a temporary resource created by a step, e.g. a temp directory or a scratch container
+// Cleanup must be idempotent since the step's own rollback may have removed the resource already.
+type Resource struct {
+	Kind    string
+	Name    string
+	Cleanup func(ctx context.Context) error
+}
+
+// ResourceCleanup defines the report of the cleanup of a Resource at the end of a run
+type ResourceCleanup struct {
+	StepID        string              `yaml:"step_id" json:"stepID"`
+	Kind          string              `yaml:"kind" json:"kind"`
+	Name          string              `yaml:"name" json:"name"`
+	Status        Status              `yaml:"status" json:"status"`
+	FailureReason errors.EncodedError `yaml:"reason" json:"reason"`
+}
+
+// trackedResource is a Resource along with the step that created it
+type trackedResource struct {
+	stepID   string
+	resource Resource
+}
+
+// resources tracks the temporary resources created during a workflow run
+type resources struct {
+	mutex   sync.Mutex
+	tracked []trackedResource
+}
+
+// add tracks the resource
+func (r *resources) add(stepID string, resource Resource) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.tracked = append(r.tracked, trackedResource{stepID: stepID, resource: resource})
+}
+
+// cleanup cleans up all the tracked resources in reverse order and returns their reports
+// A failure or a panic of a cleanup doesn't prevent the cleanup of the other resources.
+func (r *resources) cleanup(ctx context.Context) []*ResourceCleanup {
+	r.mutex.Lock()
+	tracked := r.tracked
+	r.tracked = nil
+	r.mutex.Unlock()
+
+	var reports []*ResourceCleanup
+	for i := len(tracked) - 1; i >= 0; i-- {
+		t := tracked[i]
+		report := &ResourceCleanup{
+			StepID: t.stepID,
+			Kind:   t.resource.Kind,
+			Name:   t.resource.Name,
+			Status: StatusSuccess,
+		}
+
+		if err := safeCleanup(ctx, t.resource); err != nil {
+			report.Status = StatusFailed
+			report.FailureReason = errors.EncodeError(ctx, err)
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+// safeCleanup invokes the cleanup of the resource converting a panic into an error
+func safeCleanup(ctx context.Context, resource Resource) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Newf("cleanup of %s %q panicked: %v", resource.Kind, resource.Name, r)
+		}
+	}()
+
+	return resource.Cleanup(ctx)
+}
+
+// withResources returns a copy of the context with the given resources tracker
+func withResources(ctx context.Context, r *resources) context.Context {
+	return context.WithValue(ctx, ctxKeyResources, r)
+}
+
+// TrackResource registers a temporary resource created by the current step for cleanup at the end of the run
+// The engine cleans up the tracked resources in reverse order when the run finishes, whether it succeeded or not and
+// even if the rollback of the step is skipped or fails. The result of every cleanup is reported in
+// WorkflowReport.ResourceCleanups. It returns error if the context doesn't belong to a workflow run.
+func TrackResource(ctx context.Context, resource Resource) error {
+	if resource.Cleanup == nil {
+		return errors.Newf("cleanup of %s %q cannot be nil", resource.Kind, resource.Name)
+	}
+
+	r, ok := ctx.Value(ctxKeyResources).(*resources)
+	if !ok {
+		return errors.Newf("%s %q cannot be tracked outside of a workflow run", resource.Kind, resource.Name)
+	}
+
+	stepID, _ := StepFromContext(ctx)
+	r.add(stepID, resource)
+
+	return nil
+}
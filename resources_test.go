@@ -0,0 +1,63 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTrackResource(t *testing.T) {
+	ctx := context.Background()
+
+	var cleaned []string
+	cleanup := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			cleaned = append(cleaned, name)
+			return err
+		}
+	}
+
+	download := &Step{ID: "download_binaries"}
+	download.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		assert.NoError(t, TrackResource(ctx, Resource{Kind: "dir", Name: "/tmp/download", Cleanup: cleanup("/tmp/download", nil)}))
+		assert.NoError(t, TrackResource(ctx, Resource{Kind: "file", Name: "/tmp/archive.tar", Cleanup: cleanup("/tmp/archive.tar", errors.New("mock error"))}))
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("rollback error")
+	})
+
+	install := &Step{ID: "install_binaries"}
+	install.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		assert.NoError(t, TrackResource(ctx, Resource{Kind: "container", Name: "scratch", Cleanup: func(ctx context.Context) error {
+			panic("mock panic")
+		}}))
+		return false, errors.New("install error")
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(download, install))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"/tmp/archive.tar", "/tmp/download"}, cleaned)
+	assert.Equal(t, 3, len(report.ResourceCleanups))
+
+	assert.Equal(t, "install_binaries", report.ResourceCleanups[0].StepID)
+	assert.Equal(t, StatusFailed, report.ResourceCleanups[0].Status)
+	assert.Equal(t, StatusFailed, report.ResourceCleanups[1].Status)
+	assert.Equal(t, "/tmp/download", report.ResourceCleanups[2].Name)
+	assert.Equal(t, StatusSuccess, report.ResourceCleanups[2].Status)
+	assert.Contains(t, report.Warnings, `cleanup of file "/tmp/archive.tar" tracked by step "download_binaries" failed`)
+
+	// resources are not tracked again in the next run
+	cleaned = nil
+	install.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+	report, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/tmp/archive.tar", "/tmp/download"}, cleaned)
+	assert.Equal(t, 2, len(report.ResourceCleanups))
+
+	assert.Error(t, TrackResource(ctx, Resource{Kind: "dir", Name: "/tmp/x", Cleanup: cleanup("/tmp/x", nil)}))
+	assert.Error(t, TrackResource(ctx, Resource{Kind: "dir", Name: "/tmp/x"}))
+}
@@ -0,0 +1,153 @@
+package automa
+
+import (
+	"context"
+	"time"
+)
+
+// Backoff returns the delay before the next attempt given the number of failed attempts so far
+type Backoff func(failedAttempts int) time.Duration
+
+// ConstantBackoff returns a Backoff with the same delay before every attempt
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(failedAttempts int) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff returns a Backoff doubling the delay after every failed attempt up to max
+func ExponentialBackoff(initial time.Duration, max time.Duration) Backoff {
+	return func(failedAttempts int) time.Duration {
+		delay := initial
+		for i := 1; i < failedAttempts; i++ {
+			delay *= 2
+			if delay >= max {
+				return max
+			}
+		}
+
+		return delay
+	}
+}
+
+// RetryPolicy defines how many times the run action of a step is attempted before it is marked as failed
+// A MaxAttempts less than 2 means that the run action is not retried. A nil Backoff retries immediately.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     Backoff
+}
+
+// delay returns the delay before the next attempt
+func (p *RetryPolicy) delay(failedAttempts int) time.Duration {
+	if p.Backoff == nil {
+		return 0
+	}
+
+	return p.Backoff(failedAttempts)
+}
+
+// WithRetryPolicy allows the run action of every step of the Workflow to be retried on failure
+// Steps may override it using Step.WithRetryPolicy.
+func WithRetryPolicy(maxAttempts int, backoff Backoff) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.retryPolicy = &RetryPolicy{MaxAttempts: maxAttempts, Backoff: backoff}
+	}
+}
+
+// withRetryPolicy returns a copy of the context with the given RetryPolicy
+func withRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	return context.WithValue(ctx, ctxKeyRetryPolicy, policy)
+}
+
+// retryPolicyFromContext returns the RetryPolicy set in the context by the Workflow, if any
+func retryPolicyFromContext(ctx context.Context) *RetryPolicy {
+	if policy, ok := ctx.Value(ctxKeyRetryPolicy).(*RetryPolicy); ok {
+		return policy
+	}
+
+	return nil
+}
+
+// WithRetryPolicy sets the RetryPolicy of the run action of the step, overriding the policy of the workflow
+// The number of attempts and the error of every failed attempt are recorded in the StepReport.
+func (s *Step) WithRetryPolicy(maxAttempts int, backoff Backoff) *Step {
+	s.retryPolicy = &RetryPolicy{MaxAttempts: maxAttempts, Backoff: backoff}
+
+	return s
+}
+
+// getRetryPolicy returns the RetryPolicy of the step if set, or else the RetryPolicy of the workflow
+func (s *Step) getRetryPolicy(ctx context.Context) *RetryPolicy {
+	if s.retryPolicy != nil {
+		return s.retryPolicy
+	}
+
+	return retryPolicyFromContext(ctx)
+}
+
+// nilReportRetry holds the number of attempts of the action of a step that reported a nil report
+type nilReportRetry struct {
+	stepID   string
+	action   StepActionType
+	attempts int
+}
+
+// retryNilReport returns the context for the next attempt of the action of the step after a nil report
+// It waits for the delay of the RetryPolicy of the step before returning. It returns false if the attempts are
+// exhausted, the step is not part of a workflow or the context is done while waiting.
+func (s *Step) retryNilReport(ctx context.Context, action StepActionType) (context.Context, bool) {
+	policy := s.getRetryPolicy(ctx)
+	if s.self == nil || policy == nil {
+		return ctx, false
+	}
+
+	attempts := 1
+	if r, ok := ctx.Value(ctxKeyNilReportRetry).(nilReportRetry); ok && r.stepID == s.GetID() && r.action == action {
+		attempts = r.attempts
+	}
+
+	if attempts >= policy.MaxAttempts {
+		return ctx, false
+	}
+
+	timer := time.NewTimer(policy.delay(attempts))
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx, false
+	case <-timer.C:
+	}
+
+	return context.WithValue(ctx, ctxKeyNilReportRetry, nilReportRetry{stepID: s.GetID(), action: action, attempts: attempts + 1}), true
+}
+
+// runWithRetry invokes SagaRun as per the RetryPolicy of the step and records the attempts in the report
+// It stops retrying if the context is done while waiting for the next attempt.
+func (s *Step) runWithRetry(ctx context.Context, report *StepReport) (bool, error) {
+	maxAttempts := 1
+	policy := s.getRetryPolicy(ctx)
+	if policy != nil && policy.MaxAttempts > 1 {
+		maxAttempts = policy.MaxAttempts
+	}
+
+	for {
+		report.Attempts++
+		runCtx, cancel := s.runContext(ctx)
+		skipped, err := callSaga(s.stepContext(runCtx), s.GetID(), RunAction, s.run)
+		cancel()
+		err = withCancelCause(ctx, err)
+		if err == nil || report.Attempts >= maxAttempts {
+			return skipped, err
+		}
+
+		report.AttemptErrors = append(report.AttemptErrors, err.Error())
+
+		timer := time.NewTimer(policy.delay(report.Attempts))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, withCancelCause(ctx, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
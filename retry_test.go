@@ -0,0 +1,76 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	constant := ConstantBackoff(time.Second)
+	assert.Equal(t, time.Second, constant(1))
+	assert.Equal(t, time.Second, constant(5))
+
+	exponential := ExponentialBackoff(time.Second, 5*time.Second)
+	assert.Equal(t, time.Second, exponential(1))
+	assert.Equal(t, 2*time.Second, exponential(2))
+	assert.Equal(t, 4*time.Second, exponential(3))
+	assert.Equal(t, 5*time.Second, exponential(4))
+	assert.Equal(t, 5*time.Second, exponential(10))
+}
+
+func TestStep_WithRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	newFlakyStep := func(id string, failures int) (*Step, *int) {
+		attempts := 0
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			attempts++
+			if attempts <= failures {
+				return false, errors.Newf("attempt %d failed", attempts)
+			}
+			return false, nil
+		}, nil)
+		return s, &attempts
+	}
+
+	// workflow policy
+	s1, attempts := newFlakyStep("pull_image", 2)
+	workflow := NewWorkflow("workflow_1", WithSteps(s1), WithRetryPolicy(3, ConstantBackoff(time.Millisecond)))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, *attempts)
+	assert.Equal(t, 3, report.StepReports[0].Attempts)
+	assert.Equal(t, []string{"attempt 1 failed", "attempt 2 failed"}, report.StepReports[0].AttemptErrors)
+
+	// step policy overrides the workflow policy
+	s2, attempts := newFlakyStep("pull_image", 2)
+	s2.WithRetryPolicy(2, nil)
+	workflow = NewWorkflow("workflow_1", WithSteps(s2), WithRetryPolicy(3, nil))
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 2, *attempts)
+	assert.Equal(t, 2, report.StepReports[0].Attempts)
+	assert.Equal(t, []string{"attempt 1 failed"}, report.StepReports[0].AttemptErrors)
+	assert.Equal(t, 2, workflow.Manifest().Steps[0].MaxAttempts)
+
+	// no retry by default
+	s3, attempts := newFlakyStep("pull_image", 1)
+	workflow = NewWorkflow("workflow_1", WithSteps(s3))
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, *attempts)
+	assert.Equal(t, 1, report.StepReports[0].Attempts)
+
+	// retries stop when the context is done
+	s4, attempts := newFlakyStep("pull_image", 5)
+	workflow = NewWorkflow("workflow_1", WithSteps(s4), WithRetryPolicy(5, ConstantBackoff(time.Hour)))
+	cancelCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = workflow.Start(cancelCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, *attempts)
+}
@@ -0,0 +1,102 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RunLockTimeout is the error returned when a run cannot acquire the lock of its workflow ID within the timeout
+type RunLockTimeout struct {
+	Key     string
+	Timeout time.Duration
+}
+
+// Error implements error interface for RunLockTimeout
+func (e *RunLockTimeout) Error() string {
+	return fmt.Sprintf("run lock for %q not acquired within %s", e.Key, e.Timeout)
+}
+
+// RunLocker serializes the runs of workflows sharing the same key, i.e. the same logical workflow ID
+type RunLocker struct {
+	mutex sync.Mutex
+	locks map[string]*runLock
+}
+
+// runLock is the lock of a key, it is removed from the RunLocker once it has neither a holder nor a waiter
+type runLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// NewRunLocker returns an instance of RunLocker
+func NewRunLocker() *RunLocker {
+	return &RunLocker{locks: map[string]*runLock{}}
+}
+
+// ref returns the lock of the key and registers the caller as a holder or a waiter of the lock
+func (rl *RunLocker) ref(key string) *runLock {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	l, ok := rl.locks[key]
+	if !ok {
+		l = &runLock{ch: make(chan struct{}, 1)}
+		rl.locks[key] = l
+	}
+	l.refs++
+
+	return l
+}
+
+// unref unregisters a holder or a waiter of the lock of the key and removes the lock once it is unused
+func (rl *RunLocker) unref(key string, l *runLock) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	l.refs--
+	if l.refs == 0 {
+		delete(rl.locks, key)
+	}
+}
+
+// Acquire blocks until the lock of the key is acquired
+// It returns a release func that must be invoked when the run finishes. It returns a RunLockTimeout error if the lock
+// is not acquired within the timeout, or the context error if the context is done before that. A zero timeout means
+// that Acquire fails immediately if the lock is held by another run.
+func (rl *RunLocker) Acquire(ctx context.Context, key string, timeout time.Duration) (func(), error) {
+	l := rl.ref(key)
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			<-l.ch
+			rl.unref(key, l)
+		})
+	}
+
+	select {
+	case l.ch <- struct{}{}:
+		return release, nil
+	default:
+		if timeout <= 0 {
+			rl.unref(key, l)
+			return nil, &RunLockTimeout{Key: key, Timeout: timeout}
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case l.ch <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		rl.unref(key, l)
+		return nil, &RunLockTimeout{Key: key, Timeout: timeout}
+	case <-ctx.Done():
+		rl.unref(key, l)
+		return nil, ctx.Err()
+	}
+}
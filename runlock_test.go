@@ -0,0 +1,124 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRunLocker_Acquire(t *testing.T) {
+	ctx := context.Background()
+	locker := NewRunLocker()
+
+	release, err := locker.Acquire(ctx, "workflow_1", 0)
+	assert.NoError(t, err)
+
+	// different keys are not serialized
+	releaseOther, err := locker.Acquire(ctx, "workflow_2", 0)
+	assert.NoError(t, err)
+	releaseOther()
+
+	_, err = locker.Acquire(ctx, "workflow_1", 0)
+	var timeoutErr *RunLockTimeout
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.Equal(t, "workflow_1", timeoutErr.Key)
+
+	_, err = locker.Acquire(ctx, "workflow_1", 10*time.Millisecond)
+	assert.True(t, errors.As(err, &timeoutErr))
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = locker.Acquire(cancelledCtx, "workflow_1", time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+		release() // release is idempotent
+	}()
+
+	releaseNext, err := locker.Acquire(ctx, "workflow_1", time.Second)
+	assert.NoError(t, err)
+	releaseNext()
+
+	// locks are removed once they are neither held nor awaited
+	assert.Empty(t, locker.locks)
+}
+
+func TestWorkflow_WithRunLock(t *testing.T) {
+	ctx := context.Background()
+	locker := NewRunLocker()
+
+	started := make(chan struct{})
+	proceed := make(chan struct{})
+	slow := &Step{ID: "slow_step"}
+	slow.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		close(started)
+		<-proceed
+		return false, nil
+	}, nil)
+
+	executed := false
+	fast := &Step{ID: "fast_step"}
+	fast.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		executed = true
+		return false, nil
+	}, nil)
+
+	wf1 := NewWorkflow("deploy", WithSteps(slow), WithRunLock(locker, "", time.Second))
+	wf2 := NewWorkflow("deploy", WithSteps(fast), WithRunLock(locker, "", 10*time.Millisecond))
+
+	done := make(chan error)
+	go func() {
+		_, err := wf1.Start(ctx)
+		done <- err
+	}()
+	<-started
+
+	_, err := wf2.Start(ctx)
+	var timeoutErr *RunLockTimeout
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.False(t, executed)
+
+	close(proceed)
+	assert.NoError(t, <-done)
+
+	_, err = wf2.Start(ctx)
+	assert.NoError(t, err)
+	assert.True(t, executed)
+}
+
+func TestWorkflow_WithRunLock_Undo(t *testing.T) {
+	ctx := context.Background()
+	locker := NewRunLocker()
+
+	undone := false
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		undone = true
+		return false, nil
+	})
+
+	workflow := NewWorkflow("deploy", WithSteps(s1), WithUndoWindow(time.Minute),
+		WithRunLock(locker, "", 10*time.Millisecond))
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	// the undo is serialized with the runs holding the lock and the undo window remains open on timeout
+	release, err := locker.Acquire(ctx, "deploy", 0)
+	assert.NoError(t, err)
+	_, err = workflow.Undo(ctx)
+	var timeoutErr *RunLockTimeout
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.False(t, undone)
+
+	release()
+	report, err := workflow.Undo(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusUndone, report.Status)
+	assert.True(t, undone)
+}
@@ -0,0 +1,170 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// RollbackScheduler defines the methods to defer the rollback of a step to a later time
+// e.g. deleting trial resources after 24 hours instead of immediately on failure
+type RollbackScheduler interface {
+	// Schedule schedules the undo function of the step to be executed at the due time and returns the schedule ID
+	Schedule(stepID string, due time.Time, undo SagaUndo) (string, error)
+}
+
+// ScheduledRollback defines the tracking data model of a rollback scheduled by a RollbackScheduler
+type ScheduledRollback struct {
+	ID            string              `yaml:"id" json:"id"`
+	StepID        string              `yaml:"step_id" json:"stepID"`
+	DueTime       time.Time           `yaml:"due_time" json:"dueTime"`
+	Status        Status              `yaml:"status" json:"status"`
+	FailureReason errors.EncodedError `yaml:"reason" json:"reason"`
+	Panic         *PanicInfo          `yaml:"panic,omitempty" json:"panic,omitempty"`
+}
+
+// scheduledEntry holds a ScheduledRollback along with its undo function and timer
+type scheduledEntry struct {
+	rollback ScheduledRollback
+	undo     SagaUndo
+	timer    *time.Timer
+}
+
+// InMemRollbackScheduler is an in-memory implementation of RollbackScheduler using timers
+// Scheduled rollbacks are lost if the process exits before they are due.
+type InMemRollbackScheduler struct {
+	mutex   sync.Mutex
+	entries map[string]*scheduledEntry
+	logger  *zap.Logger
+}
+
+// NewInMemRollbackScheduler returns an instance of InMemRollbackScheduler
+// if logger is nil, it initializes itself with a NoOp logger
+func NewInMemRollbackScheduler(logger *zap.Logger) *InMemRollbackScheduler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &InMemRollbackScheduler{entries: map[string]*scheduledEntry{}, logger: logger}
+}
+
+// Schedule implements RollbackScheduler interface
+func (rs *InMemRollbackScheduler) Schedule(stepID string, due time.Time, undo SagaUndo) (string, error) {
+	if undo == nil {
+		return "", errors.Newf("undo function is required to schedule rollback of step %q", stepID)
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	id := newRunID()
+	entry := &scheduledEntry{
+		rollback: ScheduledRollback{
+			ID:      id,
+			StepID:  stepID,
+			DueTime: due,
+			Status:  StatusScheduled,
+		},
+		undo: undo,
+	}
+	entry.timer = time.AfterFunc(time.Until(due), func() {
+		_ = rs.execute(context.Background(), id)
+	})
+	rs.entries[id] = entry
+
+	return id, nil
+}
+
+// Get returns the ScheduledRollback for the given schedule ID
+func (rs *InMemRollbackScheduler) Get(id string) (ScheduledRollback, bool) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	if entry, ok := rs.entries[id]; ok {
+		return entry.rollback, true
+	}
+
+	return ScheduledRollback{}, false
+}
+
+// Pending returns the rollbacks that have not been executed or cancelled yet
+func (rs *InMemRollbackScheduler) Pending() []ScheduledRollback {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	var pending []ScheduledRollback
+	for _, entry := range rs.entries {
+		if entry.rollback.Status == StatusScheduled {
+			pending = append(pending, entry.rollback)
+		}
+	}
+
+	return pending
+}
+
+// RunNow executes the scheduled rollback immediately instead of waiting until its due time
+func (rs *InMemRollbackScheduler) RunNow(ctx context.Context, id string) error {
+	return rs.execute(ctx, id)
+}
+
+// Cancel cancels the scheduled rollback so that it is never executed
+func (rs *InMemRollbackScheduler) Cancel(id string) error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	entry, ok := rs.entries[id]
+	if !ok {
+		return errors.Newf("scheduled rollback %q not found", id)
+	}
+
+	if entry.rollback.Status != StatusScheduled {
+		return errors.Newf("scheduled rollback %q cannot be cancelled since its status is %s", id, entry.rollback.Status)
+	}
+
+	entry.timer.Stop()
+	entry.rollback.Status = StatusCancelled
+
+	return nil
+}
+
+// execute runs the undo function of the scheduled rollback if it is still pending
+func (rs *InMemRollbackScheduler) execute(ctx context.Context, id string) error {
+	rs.mutex.Lock()
+	entry, ok := rs.entries[id]
+	if !ok {
+		rs.mutex.Unlock()
+		return errors.Newf("scheduled rollback %q not found", id)
+	}
+
+	if entry.rollback.Status != StatusScheduled {
+		rs.mutex.Unlock()
+		return errors.Newf("scheduled rollback %q cannot be executed since its status is %s", id, entry.rollback.Status)
+	}
+
+	entry.timer.Stop()
+	entry.rollback.Status = StatusUndefined
+	rs.mutex.Unlock()
+
+	// the rollback runs on a timer goroutine, a panic must not crash the process
+	skipped, err := callSaga(withStepID(ctx, entry.rollback.StepID), entry.rollback.StepID, RollbackAction, entry.undo)
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	switch {
+	case err != nil:
+		rs.logger.Error("scheduled rollback failed",
+			zap.String("id", id), zap.String("step_id", entry.rollback.StepID), zap.Error(err))
+		entry.rollback.Status = StatusFailed
+		entry.rollback.FailureReason = errors.EncodeError(ctx, err)
+		entry.rollback.Panic = panicFromError(err)
+	case skipped:
+		entry.rollback.Status = StatusSkipped
+	default:
+		entry.rollback.Status = StatusSuccess
+	}
+
+	return err
+}
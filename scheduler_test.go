@@ -0,0 +1,124 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInMemRollbackScheduler(t *testing.T) {
+	ctx := context.Background()
+	scheduler := NewInMemRollbackScheduler(nil)
+
+	var count int32
+	undo := func(ctx context.Context) (skipped bool, err error) {
+		atomic.AddInt32(&count, 1)
+		return false, nil
+	}
+
+	_, err := scheduler.Schedule("step_1", time.Now(), nil)
+	assert.Error(t, err)
+
+	id1, err := scheduler.Schedule("step_1", time.Now().Add(time.Hour), undo)
+	assert.NoError(t, err)
+	id2, err := scheduler.Schedule("step_2", time.Now().Add(time.Hour), undo)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(scheduler.Pending()))
+
+	// manual override
+	assert.NoError(t, scheduler.RunNow(ctx, id1))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	rollback, ok := scheduler.Get(id1)
+	assert.True(t, ok)
+	assert.Equal(t, StatusSuccess, rollback.Status)
+	assert.Error(t, scheduler.RunNow(ctx, id1))
+
+	assert.NoError(t, scheduler.Cancel(id2))
+	assert.Error(t, scheduler.Cancel(id2))
+	assert.Error(t, scheduler.Cancel("INVALID"))
+	assert.Error(t, scheduler.RunNow(ctx, "INVALID"))
+	assert.Empty(t, scheduler.Pending())
+
+	_, ok = scheduler.Get("INVALID")
+	assert.False(t, ok)
+
+	// due rollback is executed by the timer
+	id3, err := scheduler.Schedule("step_3", time.Now(), func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		rollback, _ := scheduler.Get(id3)
+		return rollback.Status == StatusFailed
+	}, time.Second, time.Millisecond)
+}
+
+func TestStep_ScheduleRollback(t *testing.T) {
+	ctx := context.Background()
+	scheduler := NewInMemRollbackScheduler(nil)
+
+	s1 := &mockSuccessStep{
+		Step:  Step{ID: "create_trial_resources"},
+		cache: map[string][]byte{},
+	}
+	s1.RegisterSaga(s1.run, s1.rollback).ScheduleRollback(24*time.Hour, scheduler)
+
+	s2 := &mockRestartContainersStep{
+		Step:  Step{ID: "restart_containers"},
+		cache: map[string][]byte{},
+	}
+	s2.RegisterSaga(s2.run, s2.rollback)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 4, len(report.StepReports))
+	assert.Equal(t, StatusScheduled, report.StepReports[3].Status)
+
+	pending := scheduler.Pending()
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, s1.GetID(), pending[0].StepID)
+	assert.Equal(t, []byte(pending[0].ID), report.StepReports[3].Metadata["scheduled_rollback_id"])
+	assert.NoError(t, scheduler.RunNow(ctx, pending[0].ID))
+}
+
+func TestStep_ScheduleRollback_StepContext(t *testing.T) {
+	ctx := context.Background()
+	scheduler := NewInMemRollbackScheduler(nil)
+
+	type regionKey struct{}
+	var region interface{}
+	var runID string
+	s1 := &Step{ID: "create_trial_resources"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		region = ctx.Value(regionKey{})
+		runID, _ = RunIdFromContext(ctx)
+		panic("mock panic")
+	}).WithContextValue(regionKey{}, "us-east-1").ScheduleRollback(time.Millisecond, scheduler)
+
+	s2 := &Step{ID: "fail"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+
+	// the panic of the rollback executed by the timer is reported as its failure
+	id := string(report.StepReports[len(report.StepReports)-1].Metadata["scheduled_rollback_id"])
+	assert.Eventually(t, func() bool {
+		rollback, _ := scheduler.Get(id)
+		return rollback.Status == StatusFailed
+	}, time.Second, time.Millisecond)
+	rollback, _ := scheduler.Get(id)
+	assert.NotNil(t, rollback.Panic)
+	assert.Equal(t, "mock panic", rollback.Panic.Value)
+	assert.Equal(t, "us-east-1", region)
+	assert.Equal(t, report.RunID, runID)
+}
@@ -0,0 +1,60 @@
+package automa
+
+import (
+	"context"
+)
+
+// seedState holds the outputs of a previous successful run used to warm-start the runs of a workflow
+type seedState struct {
+	workflowID string
+	runID      string
+	status     Status
+	outputs    map[string][]byte
+}
+
+// WithSeedState allows the runs of the Workflow to be warm-started with the outputs of a previous run
+// It enables incremental pipelines, e.g. a step may reuse the cluster created by yesterday's run instead of creating a
+// new one. Steps read the seeded values using SeedValue. Start returns error if the report is not of a successful run.
+func WithSeedState(report WorkflowReport) WorkflowOption {
+	return func(wf *Workflow) {
+		seed := &seedState{
+			workflowID: report.WorkflowID,
+			runID:      report.RunID,
+			status:     report.Status,
+			outputs:    map[string][]byte{},
+		}
+
+		for key, val := range report.Outputs {
+			seed.outputs[key] = val
+		}
+
+		wf.seed = seed
+	}
+}
+
+// withSeedState returns a copy of the context with the given seed state
+func withSeedState(ctx context.Context, seed *seedState) context.Context {
+	return context.WithValue(ctx, ctxKeySeedState, seed)
+}
+
+// SeedValue returns the output of the previous run the current workflow run was seeded with, see WithSeedState
+func SeedValue(ctx context.Context, key string) ([]byte, bool) {
+	seed, ok := ctx.Value(ctxKeySeedState).(*seedState)
+	if !ok {
+		return nil, false
+	}
+
+	val, ok := seed.outputs[key]
+
+	return val, ok
+}
+
+// SeedRunIdFromContext returns the ID of the previous run the current workflow run was seeded with
+func SeedRunIdFromContext(ctx context.Context) (string, bool) {
+	seed, ok := ctx.Value(ctxKeySeedState).(*seedState)
+	if !ok {
+		return "", false
+	}
+
+	return seed.runID, true
+}
@@ -0,0 +1,49 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWorkflow_WithSeedState(t *testing.T) {
+	ctx := context.Background()
+
+	prev := NewWorkflowReport("create_cluster", nil)
+	prev.RunID = "run_1"
+	prev.Status = StatusSuccess
+	prev.Outputs["cluster_id"] = []byte("cluster-42")
+
+	var clusterID []byte
+	var seedRunID string
+	var found bool
+	s1 := &Step{ID: "ensure_cluster"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		clusterID, found = SeedValue(ctx, "cluster_id")
+		seedRunID, _ = SeedRunIdFromContext(ctx)
+		_, ok := SeedValue(ctx, "unknown")
+		assert.False(t, ok)
+		return found, nil
+	}, nil)
+
+	workflow := NewWorkflow("create_cluster", WithSteps(s1), WithSeedState(*prev))
+
+	// changes to the seed report after the workflow is built are not visible
+	prev.Outputs["cluster_id"] = []byte("cluster-43")
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("cluster-42"), clusterID)
+	assert.Equal(t, "run_1", seedRunID)
+	assert.Equal(t, StatusSkipped, report.StepReports[0].Status)
+
+	_, ok := SeedValue(ctx, "cluster_id")
+	assert.False(t, ok)
+
+	// only successful runs can be used as seed
+	prev.Status = StatusFailed
+	workflow = NewWorkflow("create_cluster", WithSteps(s1), WithSeedState(*prev))
+	_, err = workflow.Start(ctx)
+	assert.Error(t, err)
+}
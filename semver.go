@@ -0,0 +1,120 @@
+package automa
+
+import (
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"strconv"
+	"strings"
+)
+
+// semver is a semantic version MAJOR.MINOR.PATCH
+// Pre-release and build metadata are not supported.
+type semver struct {
+	major int
+	minor int
+	patch int
+}
+
+// parseSemver parses a version such as "v1.2.3" or "1.2.3"
+// Missing minor and patch numbers are parsed as 0 so that "1.0" is the same as "1.0.0".
+func parseSemver(s string) (semver, error) {
+	var v semver
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".")
+	if len(parts) > 3 {
+		return v, errors.Newf("invalid version %q", s)
+	}
+
+	numbers := []*int{&v.major, &v.minor, &v.patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, errors.Newf("invalid version %q", s)
+		}
+
+		*numbers[i] = n
+	}
+
+	return v, nil
+}
+
+// String returns the version prefixed by "v"
+func (v semver) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+// compare returns -1, 0 or 1 if the version is respectively lower than, equal to or greater than the other version
+func (v semver) compare(other semver) int {
+	for _, d := range []int{v.major - other.major, v.minor - other.minor, v.patch - other.patch} {
+		if d < 0 {
+			return -1
+		}
+
+		if d > 0 {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// versionConstraint defines a condition on a version, e.g. ">=1.0"
+type versionConstraint struct {
+	op      string
+	version semver
+}
+
+// constraintOps are the supported operators, longer operators first so that they are matched first
+var constraintOps = []string{">=", "<=", ">", "<", "=", "^", "~"}
+
+// parseConstraints parses comma separated constraints, e.g. ">=1.0, <2.0"
+// A version without an operator must match exactly. "^1.2.3" matches any version compatible with 1.2.3 as per
+// semantic versioning, i.e. ">=1.2.3, <2.0.0", and "~1.2.3" matches any patch of 1.2, i.e. ">=1.2.3, <1.3.0".
+func parseConstraints(s string) ([]versionConstraint, error) {
+	var constraints []versionConstraint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		c := versionConstraint{op: "="}
+		for _, op := range constraintOps {
+			if strings.HasPrefix(part, op) {
+				c.op = op
+				part = strings.TrimPrefix(part, op)
+				break
+			}
+		}
+
+		v, err := parseSemver(part)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid version constraint %q", s)
+		}
+
+		c.version = v
+		constraints = append(constraints, c)
+	}
+
+	return constraints, nil
+}
+
+// matches returns true if the version satisfies the constraint
+func (c versionConstraint) matches(v semver) bool {
+	cmp := v.compare(c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "^":
+		if c.version.major == 0 {
+			return cmp >= 0 && v.major == 0 && v.minor == c.version.minor
+		}
+
+		return cmp >= 0 && v.major == c.version.major
+	case "~":
+		return cmp >= 0 && v.major == c.version.major && v.minor == c.version.minor
+	default:
+		return cmp == 0
+	}
+}
@@ -0,0 +1,63 @@
+package automa
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	v, err := parseSemver("v1.2.3")
+	assert.NoError(t, err)
+	assert.Equal(t, semver{1, 2, 3}, v)
+	assert.Equal(t, "v1.2.3", v.String())
+
+	v, err = parseSemver("1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, semver{1, 0, 0}, v)
+
+	for _, s := range []string{"", "v1.x", "1.2.3.4", "-1", "1.2.3-rc1"} {
+		_, err = parseSemver(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestVersionConstraint_matches(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		matches    bool
+	}{
+		{"1.2.0", "v1.2.0", true},
+		{"=1.2", "v1.2.1", false},
+		{">=1.0", "v1.2.0", true},
+		{">=1.0", "v0.9.0", false},
+		{">1.2.0", "v1.2.0", false},
+		{"<2", "v1.9.9", true},
+		{"<=1.2", "v1.2.0", true},
+		{">=1.0, <2.0", "v2.0.0", false},
+		{"^1.2.3", "v1.9.0", true},
+		{"^1.2.3", "v2.0.0", false},
+		{"^0.2.3", "v0.3.0", false},
+		{"^0.2.3", "v0.2.5", true},
+		{"~1.2.3", "v1.2.9", true},
+		{"~1.2.3", "v1.3.0", false},
+	}
+
+	for _, test := range tests {
+		constraints, err := parseConstraints(test.constraint)
+		assert.NoError(t, err)
+
+		v, err := parseSemver(test.version)
+		assert.NoError(t, err)
+
+		matched := true
+		for _, c := range constraints {
+			matched = matched && c.matches(v)
+		}
+
+		assert.Equal(t, test.matches, matched, "%s %s", test.constraint, test.version)
+	}
+
+	_, err := parseConstraints(">=x")
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package automa
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/cockroachdb/errors"
+	"gopkg.in/yaml.v3"
+	"io"
+	"reflect"
+)
+
+// StepBuilder returns a new step with the given ID and the parameters declared in a WorkflowSpec
+type StepBuilder func(id string, params map[string]string) (AtomicStep, error)
+
+// StepSpec declares a step of a WorkflowSpec
+// Uses references the StepBuilder registered with the StepRegistry building the step, see StepRegistry.Of.
+type StepSpec struct {
+	ID     string            `yaml:"id" json:"id"`
+	Uses   string            `yaml:"uses" json:"uses"`
+	Params map[string]string `yaml:"params,omitempty" json:"params,omitempty"`
+}
+
+// WorkflowSpec declares a workflow as a sequence of steps built by the registered StepBuilder
+type WorkflowSpec struct {
+	ID            string        `yaml:"id" json:"id"`
+	ExecutionMode ExecutionMode `yaml:"execution_mode,omitempty" json:"executionMode,omitempty"`
+	RollbackMode  RollbackMode  `yaml:"rollback_mode,omitempty" json:"rollbackMode,omitempty"`
+	Steps         []StepSpec    `yaml:"steps" json:"steps"`
+}
+
+// LoadWorkflowSpec reads a WorkflowSpec from a YAML or JSON document
+// A document starting with '{' is decoded as JSON using the JSON keys of WorkflowSpec, otherwise it is decoded as
+// YAML. Unknown fields are rejected so that typos do not go unnoticed.
+func LoadWorkflowSpec(r io.Reader) (*WorkflowSpec, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read workflow spec")
+	}
+
+	spec := &WorkflowSpec{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.DisallowUnknownFields()
+		err = d.Decode(spec)
+	} else {
+		d := yaml.NewDecoder(bytes.NewReader(data))
+		d.KnownFields(true)
+		err = d.Decode(spec)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode workflow spec")
+	}
+
+	if err = spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// Validate returns an error if the WorkflowSpec is incomplete or inconsistent
+func (spec *WorkflowSpec) Validate() error {
+	if spec.ID == "" {
+		return errors.New("workflow spec has no id")
+	}
+
+	switch spec.ExecutionMode {
+	case "", StopOnError, CompensateAndContinue:
+	default:
+		return errors.Newf("workflow spec %q has an invalid execution mode %q", spec.ID, spec.ExecutionMode)
+	}
+
+	switch spec.RollbackMode {
+	case "", ContinueOnRollbackError, StopOnRollbackError:
+	default:
+		return errors.Newf("workflow spec %q has an invalid rollback mode %q", spec.ID, spec.RollbackMode)
+	}
+
+	if len(spec.Steps) == 0 {
+		return errors.Newf("workflow spec %q has no steps", spec.ID)
+	}
+
+	ids := map[string]bool{}
+	for i, step := range spec.Steps {
+		if step.ID == "" {
+			return errors.Newf("step %d of workflow spec %q has no id", i, spec.ID)
+		}
+
+		if step.Uses == "" {
+			return errors.Newf("step %q of workflow spec %q has no builder", step.ID, spec.ID)
+		}
+
+		if ids[step.ID] {
+			return errors.Newf("step %q of workflow spec %q is declared more than once", step.ID, spec.ID)
+		}
+
+		ids[step.ID] = true
+	}
+
+	return nil
+}
+
+// BuildWorkflowFromSpec builds a Workflow from the WorkflowSpec using the registered StepBuilder
+// It returns a ValidationError if ValidateSpec reports any violation. The options are applied after the settings of the
+// spec, so that they may add to or override them.
+func (r *StepRegistry) BuildWorkflowFromSpec(spec *WorkflowSpec, opts ...WorkflowOption) (*Workflow, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	if violations := r.ValidateSpec(spec); len(violations) > 0 {
+		return nil, &ValidationError{WorkflowID: spec.ID, Violations: violations}
+	}
+
+	steps, err := r.BuildSteps(spec.Steps...)
+	if err != nil {
+		return nil, err
+	}
+
+	wfOpts := []WorkflowOption{WithSteps(steps...), WithLogger(r.logger)}
+	if spec.ExecutionMode != "" {
+		wfOpts = append(wfOpts, WithExecutionMode(spec.ExecutionMode))
+	}
+	if spec.RollbackMode != "" {
+		wfOpts = append(wfOpts, WithRollbackMode(spec.RollbackMode))
+	}
+
+	return NewWorkflow(spec.ID, append(wfOpts, opts...)...), nil
+}
+
+// BuildSteps builds the steps using the registered StepBuilder referenced by every StepSpec
+// Every builder receives a copy of the parameters of its StepSpec.
+func (r *StepRegistry) BuildSteps(specs ...StepSpec) ([]AtomicStep, error) {
+	var steps []AtomicStep
+	for _, stepSpec := range specs {
+		builder, err := r.Of(stepSpec.Uses)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid step builder of step %q", stepSpec.ID)
+		}
+
+		params := map[string]string{}
+		for key, value := range stepSpec.Params {
+			params[key] = value
+		}
+
+		step, err := builder(stepSpec.ID, params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to build step %q using %q", stepSpec.ID, stepSpec.Uses)
+		}
+
+		if isNilStep(step) {
+			return nil, errors.Newf("step builder %q returned a nil step for step %q", stepSpec.Uses, stepSpec.ID)
+		}
+
+		if step.GetID() != stepSpec.ID {
+			return nil, errors.Newf("step builder %q did not return a step with id %q", stepSpec.Uses, stepSpec.ID)
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// isNilStep returns true if the step is nil or a typed nil, e.g. a nil *Step returned as an AtomicStep
+func isNilStep(step AtomicStep) bool {
+	if step == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(step)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
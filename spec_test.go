@@ -0,0 +1,119 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// mockParamStep is an example of a step built from the parameters of a WorkflowSpec
+type mockParamStep struct {
+	Step
+	params map[string]string
+}
+
+func newMockParamStep(id string, params map[string]string) (AtomicStep, error) {
+	if params["image"] == "" {
+		return nil, errors.New("image is required")
+	}
+
+	s := &mockParamStep{Step: Step{ID: id}, params: params}
+	s.RegisterSaga(func(ctx context.Context) (bool, error) {
+		return false, nil
+	}, nil)
+
+	return s, nil
+}
+
+const yamlSpec = `
+id: workflow_1
+execution_mode: compensate_and_continue
+steps:
+  - id: pull
+    uses: container
+    params:
+      image: nginx
+  - id: start
+    uses: container
+    params:
+      image: nginx
+      port: "8080"
+`
+
+const jsonSpec = `{
+  "id": "workflow_1",
+  "rollbackMode": "stop_on_rollback_error",
+  "steps": [{"id": "pull", "uses": "container", "params": {"image": "nginx"}}]
+}`
+
+func TestLoadWorkflowSpec(t *testing.T) {
+	spec, err := LoadWorkflowSpec(strings.NewReader(yamlSpec))
+	assert.NoError(t, err)
+	assert.Equal(t, "workflow_1", spec.ID)
+	assert.Equal(t, CompensateAndContinue, spec.ExecutionMode)
+	assert.Equal(t, 2, len(spec.Steps))
+	assert.Equal(t, "8080", spec.Steps[1].Params["port"])
+
+	spec, err = LoadWorkflowSpec(strings.NewReader(jsonSpec))
+	assert.NoError(t, err)
+	assert.Equal(t, StopOnRollbackError, spec.RollbackMode)
+	assert.Equal(t, "container", spec.Steps[0].Uses)
+
+	invalid := []string{
+		"",
+		"id: workflow_1\nsteps: []",
+		"id: workflow_1\nunknown: true\nsteps:\n  - {id: pull, uses: container}",
+		"id: workflow_1\nsteps:\n  - {id: pull}",
+		"id: workflow_1\nsteps:\n  - {id: pull, uses: container}\n  - {id: pull, uses: container}",
+		"id: workflow_1\nexecution_mode: retry\nsteps:\n  - {id: pull, uses: container}",
+		`{"id": "workflow_1", "execution_mode": "stop_on_error", "steps": []}`,
+	}
+	for _, doc := range invalid {
+		_, err = LoadWorkflowSpec(strings.NewReader(doc))
+		assert.Error(t, err, doc)
+	}
+}
+
+func TestStepRegistry_BuildWorkflowFromSpec(t *testing.T) {
+	ctx := context.Background()
+	registry := NewStepRegistry(nil).RegisterStepBuilder("container", newMockParamStep)
+
+	spec, err := LoadWorkflowSpec(strings.NewReader(yamlSpec))
+	assert.NoError(t, err)
+
+	workflow, err := registry.BuildWorkflowFromSpec(spec, WithQuarantine("start"))
+	assert.NoError(t, err)
+	defer workflow.End(ctx)
+
+	m := workflow.Manifest()
+	assert.Equal(t, CompensateAndContinue, m.ExecutionMode)
+	assert.Equal(t, []string{"start"}, m.Quarantine)
+	assert.Equal(t, 2, len(m.Steps))
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "pull", report.StepReports[0].StepID)
+
+	// parameters are copied so that the spec cannot change the built steps
+	spec.Steps[1].Params["port"] = "9090"
+	assert.Equal(t, "8080", workflow.steps[1].(*mockParamStep).params["port"])
+
+	spec.Steps[0].Uses = "unknown"
+	_, err = registry.BuildWorkflowFromSpec(spec)
+	assert.Error(t, err)
+
+	spec.Steps[0].Uses = "container"
+	spec.Steps[0].Params = nil
+	_, err = registry.BuildWorkflowFromSpec(spec)
+	assert.Error(t, err)
+
+	// a builder returning a typed nil step is reported instead of panicking
+	registry.RegisterStepBuilder("nil", func(id string, params map[string]string) (AtomicStep, error) {
+		var s *Step
+		return s, nil
+	})
+	_, err = registry.BuildSteps(StepSpec{ID: "pull", Uses: "nil"})
+	assert.EqualError(t, err, `step builder "nil" returned a nil step for step "pull"`)
+}
@@ -0,0 +1,102 @@
+package automa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// StateSizeLimits defines the thresholds in bytes of the serialized state of a workflow run
+// The state of a step is its Outputs, Metadata and Extra, while the state of the run is the whole WorkflowReport.
+// Exceeding a warning threshold adds a warning to the report, while exceeding a max threshold fails the step.
+// A threshold less than 1 is not enforced.
+type StateSizeLimits struct {
+	StepWarn   int
+	StepMax    int
+	ReportWarn int
+	ReportMax  int
+}
+
+// StateSizeExceeded is the error returned when the state of a step or the report exceeds its max size
+type StateSizeExceeded struct {
+	StepID string
+	Report bool
+	Size   int
+	Limit  int
+}
+
+// Error implements error interface for StateSizeExceeded
+func (e *StateSizeExceeded) Error() string {
+	if e.Report {
+		return fmt.Sprintf("report size %d bytes after step %q exceeds the limit of %d bytes", e.Size, e.StepID, e.Limit)
+	}
+
+	return fmt.Sprintf("state size %d bytes of step %q exceeds the limit of %d bytes", e.Size, e.StepID, e.Limit)
+}
+
+// WithStateSizeLimits enables the tracking of the size of the state of every step and of the report during a run
+// The size of the state of a step is set in StepReport.StateSize.
+func WithStateSizeLimits(limits StateSizeLimits) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.stateSizeLimits = &limits
+	}
+}
+
+// withStateSizeLimits returns a copy of the context with the given StateSizeLimits
+func withStateSizeLimits(ctx context.Context, limits *StateSizeLimits) context.Context {
+	return context.WithValue(ctx, ctxKeyStateSizeLimits, limits)
+}
+
+// stepState is the serialized state of a step
+type stepState struct {
+	Outputs  map[string][]byte      `json:"outputs"`
+	Metadata map[string][]byte      `json:"metadata"`
+	Extra    map[string]interface{} `json:"extra"`
+}
+
+// checkStateSize measures the state of the step and the report including it as per the StateSizeLimits, if any
+// It sets StepReport.StateSize, adds warnings for the exceeded warning thresholds and returns StateSizeExceeded
+// error if a max threshold is exceeded.
+func checkStateSize(ctx context.Context, wfr *WorkflowReport, report *StepReport) error {
+	limits, ok := ctx.Value(ctxKeyStateSizeLimits).(*StateSizeLimits)
+	if !ok || limits == nil {
+		return nil
+	}
+
+	state, err := json.Marshal(stepState{Outputs: report.Outputs, Metadata: report.Metadata, Extra: report.Extra})
+	if err != nil {
+		AddWarning(ctx, "state size of step %q cannot be measured: %v", report.StepID, err)
+		return nil
+	}
+
+	report.StateSize = len(state)
+	if limits.StepMax > 0 && report.StateSize > limits.StepMax {
+		return &StateSizeExceeded{StepID: report.StepID, Size: report.StateSize, Limit: limits.StepMax}
+	}
+
+	if limits.StepWarn > 0 && report.StateSize > limits.StepWarn {
+		AddWarning(ctx, "state size %d bytes of step %q exceeds %d bytes", report.StateSize, report.StepID, limits.StepWarn)
+	}
+
+	if limits.ReportWarn < 1 && limits.ReportMax < 1 {
+		return nil
+	}
+
+	serialized, err := json.Marshal(wfr)
+	if err != nil {
+		AddWarning(ctx, "report size after step %q cannot be measured: %v", report.StepID, err)
+		return nil
+	}
+
+	size := len(serialized) + len(state)
+	if limits.ReportMax > 0 && size > limits.ReportMax {
+		return &StateSizeExceeded{StepID: report.StepID, Report: true, Size: size, Limit: limits.ReportMax}
+	}
+
+	if limits.ReportWarn > 0 && size > limits.ReportWarn {
+		// the report keeps growing, so the message doesn't contain the step in order to be reported once per run
+		AddWarning(ctx, "report size exceeds %d bytes", limits.ReportWarn)
+	}
+
+	return nil
+}
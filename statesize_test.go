@@ -0,0 +1,71 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type mockOutputStep struct {
+	Step
+	size int
+}
+
+func (s *mockOutputStep) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	report := NewStepReport(s.GetID(), RunAction)
+	report.Outputs["data"] = []byte(strings.Repeat("x", s.size))
+	return s.RunNext(ctx, prevSuccess, report)
+}
+
+func TestWorkflow_StateSizeLimits(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &mockOutputStep{Step: Step{ID: "small"}, size: 10}
+	s2 := &mockOutputStep{Step: Step{ID: "large"}, size: 200}
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(s1, s2),
+		WithStateSizeLimits(StateSizeLimits{StepWarn: 100, ReportWarn: 300}))
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.Greater(t, report.StepReports[0].StateSize, 10)
+	assert.Greater(t, report.StepReports[1].StateSize, 200)
+	assert.Equal(t, 2, len(report.Warnings))
+	assert.Contains(t, report.Warnings, `state size 318 bytes of step "large" exceeds 100 bytes`)
+	assert.Contains(t, report.Warnings, "report size exceeds 300 bytes")
+
+	// hard limit of a step
+	s3 := &mockOutputStep{Step: Step{ID: "small"}, size: 10}
+	s4 := &mockOutputStep{Step: Step{ID: "large"}, size: 200}
+	workflow = NewWorkflow("workflow_1",
+		WithSteps(s3, s4),
+		WithStateSizeLimits(StateSizeLimits{StepMax: 100}))
+
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	var exceeded *StateSizeExceeded
+	assert.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, "large", exceeded.StepID)
+	assert.False(t, exceeded.Report)
+	assert.Equal(t, StatusFailed, report.Status)
+
+	// hard limit of the report
+	s5 := &mockOutputStep{Step: Step{ID: "small"}, size: 10}
+	s6 := &mockOutputStep{Step: Step{ID: "large"}, size: 200}
+	workflow = NewWorkflow("workflow_1",
+		WithSteps(s5, s6),
+		WithStateSizeLimits(StateSizeLimits{ReportMax: 800}))
+
+	_, err = workflow.Start(ctx)
+	assert.ErrorAs(t, err, &exceeded)
+	assert.True(t, exceeded.Report)
+	assert.Contains(t, exceeded.Error(), `after step "large"`)
+
+	// not tracked without limits
+	s7 := &mockOutputStep{Step: Step{ID: "small"}, size: 10}
+	report, err = NewWorkflow("workflow_1", WithSteps(s7)).Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.StepReports[0].StateSize)
+}
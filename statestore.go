@@ -0,0 +1,263 @@
+package automa
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// ErrStateNotFound is returned by a StateStore when there is no state for the run
+var ErrStateNotFound = errors.New("state not found")
+
+// StateStore defines the methods to persist the state of workflow runs keyed by run ID
+// The state of a run is a Checkpoint that is saved before every step and at the end of the run, so that external
+// tools can inspect in-flight runs and resume them using Workflow.Resume.
+type StateStore interface {
+	// Save saves the state replacing the previous state of the same run
+	Save(ctx context.Context, cp *Checkpoint) error
+
+	// Load returns the state of the run or ErrStateNotFound
+	Load(ctx context.Context, runID string) (*Checkpoint, error)
+
+	// Delete deletes the state of the run, it is a NOOP if there is no state for the run
+	Delete(ctx context.Context, runID string) error
+}
+
+// WithStateStore allows the Workflow to persist the state of its runs in the store
+// Failures of the store never fail the run, they are reported in WorkflowReport.Diagnostics instead.
+func WithStateStore(store StateStore) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.stateStore = store
+	}
+}
+
+// stateRecorder saves the state of a workflow run in a StateStore
+type stateRecorder struct {
+	store   StateStore
+	stepIDs StepIDs
+}
+
+// save saves the state of the run as per the given report
+// The status is StatusUndefined while the run is in-flight.
+func (r *stateRecorder) save(ctx context.Context, report WorkflowReport) {
+	cp := newCheckpoint(report, r.stepIDs)
+	if err := r.store.Save(ctx, cp); err != nil {
+		ReportSinkError(ctx, "state_store", errors.Wrapf(err, "failed to save state of run %q", report.RunID))
+	}
+}
+
+// withStateRecorder returns a copy of the context with the given stateRecorder
+func withStateRecorder(ctx context.Context, r *stateRecorder) context.Context {
+	return context.WithValue(ctx, ctxKeyStateRecorder, r)
+}
+
+// saveState saves the state of the run using the stateRecorder of the context, if any
+func saveState(ctx context.Context, report WorkflowReport) {
+	if r, ok := ctx.Value(ctxKeyStateRecorder).(*stateRecorder); ok && r != nil {
+		r.save(ctx, report)
+	}
+}
+
+// runIDPattern restricts run IDs used as file names
+var runIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// tableNamePattern restricts table names used in SQL statements
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// FileStateStore is a StateStore saving the state of every run as a JSON file in a directory
+type FileStateStore struct {
+	dir string
+}
+
+// NewFileStateStore returns an instance of FileStateStore using the given directory
+// The directory is created if it doesn't exist.
+func NewFileStateStore(dir string) (*FileStateStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create state directory %q", dir)
+	}
+
+	return &FileStateStore{dir: dir}, nil
+}
+
+// path returns the path of the state file of the run
+func (s *FileStateStore) path(runID string) (string, error) {
+	if !runIDPattern.MatchString(runID) {
+		return "", errors.Newf("invalid run ID %q", runID)
+	}
+
+	return filepath.Join(s.dir, runID+".json"), nil
+}
+
+// Save implements StateStore interface
+// The file is replaced atomically so that readers never observe a partially written state.
+func (s *FileStateStore) Save(ctx context.Context, cp *Checkpoint) error {
+	path, err := s.path(cp.RunID)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal state of run %q", cp.RunID)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, cp.RunID+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create state file of run %q", cp.RunID)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to write state file of run %q", cp.RunID)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrapf(err, "failed to write state file of run %q", cp.RunID)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load implements StateStore interface
+func (s *FileStateStore) Load(ctx context.Context, runID string) (*Checkpoint, error) {
+	path, err := s.path(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrStateNotFound
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to read state file of run %q", runID)
+	}
+
+	cp := &Checkpoint{}
+	if err = json.Unmarshal(b, cp); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse state file of run %q", runID)
+	}
+
+	return cp, nil
+}
+
+// Delete implements StateStore interface
+func (s *FileStateStore) Delete(ctx context.Context, runID string) error {
+	path, err := s.path(runID)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to delete state file of run %q", runID)
+	}
+
+	return nil
+}
+
+// SQLStateStore is a StateStore saving the state of every run as a JSON document in a database/sql table
+// The table must have the columns run_id (primary key), workflow_id, status, state and updated_at, see CreateTable.
+type SQLStateStore struct {
+	db                 *sql.DB
+	table              string
+	dollarPlaceholders bool
+}
+
+// NewSQLStateStore returns an instance of SQLStateStore using the given table
+// dollarPlaceholders must be set for drivers using $1 style placeholders, e.g. PostgreSQL, instead of ?.
+func NewSQLStateStore(db *sql.DB, table string, dollarPlaceholders bool) (*SQLStateStore, error) {
+	if !tableNamePattern.MatchString(table) {
+		return nil, errors.Newf("invalid table name %q", table)
+	}
+
+	return &SQLStateStore{db: db, table: table, dollarPlaceholders: dollarPlaceholders}, nil
+}
+
+// placeholder returns the placeholder of the nth argument of a statement
+func (s *SQLStateStore) placeholder(n int) string {
+	if s.dollarPlaceholders {
+		return fmt.Sprintf("$%d", n)
+	}
+
+	return "?"
+}
+
+// CreateTable creates the table of the store if it doesn't exist
+func (s *SQLStateStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (run_id VARCHAR(64) PRIMARY KEY, workflow_id VARCHAR(255) NOT NULL, "+
+			"status VARCHAR(32) NOT NULL, state TEXT NOT NULL, updated_at TIMESTAMP NOT NULL)", s.table))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create table %q", s.table)
+	}
+
+	return nil
+}
+
+// Save implements StateStore interface
+// It replaces the row of the run in a transaction since upserts are not portable across databases.
+func (s *SQLStateStore) Save(ctx context.Context, cp *Checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal state of run %q", cp.RunID)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save state of run %q", cp.RunID)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE run_id = %s", s.table, s.placeholder(1)),
+		cp.RunID); err != nil {
+		return errors.Wrapf(err, "failed to save state of run %q", cp.RunID)
+	}
+
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (run_id, workflow_id, status, state, updated_at) VALUES (%s, %s, %s, %s, %s)", s.table,
+		s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4), s.placeholder(5)),
+		cp.RunID, cp.WorkflowID, string(cp.Status), string(b), time.Now().UTC()); err != nil {
+		return errors.Wrapf(err, "failed to save state of run %q", cp.RunID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrapf(err, "failed to save state of run %q", cp.RunID)
+	}
+
+	return nil
+}
+
+// Load implements StateStore interface
+func (s *SQLStateStore) Load(ctx context.Context, runID string) (*Checkpoint, error) {
+	var state string
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT state FROM %s WHERE run_id = %s", s.table, s.placeholder(1)),
+		runID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStateNotFound
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state of run %q", runID)
+	}
+
+	cp := &Checkpoint{}
+	if err = json.Unmarshal([]byte(state), cp); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse state of run %q", runID)
+	}
+
+	return cp, nil
+}
+
+// Delete implements StateStore interface
+func (s *SQLStateStore) Delete(ctx context.Context, runID string) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE run_id = %s", s.table, s.placeholder(1)),
+		runID); err != nil {
+		return errors.Wrapf(err, "failed to delete state of run %q", runID)
+	}
+
+	return nil
+}
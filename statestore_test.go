@@ -0,0 +1,165 @@
+package automa
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockSQLDriver is a minimal database/sql driver supporting the statements of SQLStateStore
+type mockSQLDriver struct {
+	mutex sync.Mutex
+	rows  map[string]string
+}
+
+type mockSQLConn struct {
+	d *mockSQLDriver
+}
+
+type mockSQLStmt struct {
+	d     *mockSQLDriver
+	query string
+}
+
+type mockSQLRows struct {
+	values []string
+}
+
+func (d *mockSQLDriver) Open(name string) (driver.Conn, error) { return &mockSQLConn{d: d}, nil }
+func (c *mockSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &mockSQLStmt{d: c.d, query: query}, nil
+}
+func (c *mockSQLConn) Close() error              { return nil }
+func (c *mockSQLConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *mockSQLConn) Commit() error             { return nil }
+func (c *mockSQLConn) Rollback() error           { return nil }
+func (s *mockSQLStmt) Close() error              { return nil }
+func (s *mockSQLStmt) NumInput() int             { return -1 }
+
+func (s *mockSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+	case strings.HasPrefix(s.query, "DELETE"):
+		delete(s.d.rows, args[0].(string))
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.d.rows[args[0].(string)] = args[3].(string)
+	default:
+		return nil, errors.Newf("unsupported query %q", s.query)
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *mockSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+
+	rows := &mockSQLRows{}
+	if state, ok := s.d.rows[args[0].(string)]; ok {
+		rows.values = append(rows.values, state)
+	}
+
+	return rows, nil
+}
+
+func (r *mockSQLRows) Columns() []string { return []string{"state"} }
+func (r *mockSQLRows) Close() error      { return nil }
+func (r *mockSQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+
+	return nil
+}
+
+func init() {
+	sql.Register("automa_mock", &mockSQLDriver{rows: map[string]string{}})
+}
+
+func testStateStore(t *testing.T, store StateStore) {
+	ctx := context.Background()
+
+	_, err := store.Load(ctx, "run_1")
+	assert.ErrorIs(t, err, ErrStateNotFound)
+
+	cp := &Checkpoint{WorkflowID: "workflow_1", RunID: "run_1", NextStep: "step_1"}
+	assert.NoError(t, store.Save(ctx, cp))
+	cp.NextStep = "step_2"
+	assert.NoError(t, store.Save(ctx, cp))
+
+	loaded, err := store.Load(ctx, "run_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "step_2", loaded.NextStep)
+
+	assert.NoError(t, store.Delete(ctx, "run_1"))
+	assert.NoError(t, store.Delete(ctx, "run_1"))
+	_, err = store.Load(ctx, "run_1")
+	assert.ErrorIs(t, err, ErrStateNotFound)
+}
+
+func TestFileStateStore(t *testing.T) {
+	store, err := NewFileStateStore(t.TempDir())
+	assert.NoError(t, err)
+	testStateStore(t, store)
+
+	assert.Error(t, store.Save(context.Background(), &Checkpoint{RunID: "../run_1"}))
+}
+
+func TestSQLStateStore(t *testing.T) {
+	db, err := sql.Open("automa_mock", "")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = NewSQLStateStore(db, "states; DROP TABLE x", false)
+	assert.Error(t, err)
+
+	store, err := NewSQLStateStore(db, "automa_states", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "$2", store.placeholder(2))
+	assert.NoError(t, store.CreateTable(context.Background()))
+	testStateStore(t, store)
+}
+
+func TestWorkflow_WithStateStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStateStore(t.TempDir())
+	assert.NoError(t, err)
+
+	var inFlight *Checkpoint
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		runID, _ := RunIdFromContext(ctx)
+		inFlight, err = store.Load(ctx, runID)
+		return false, err
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2), WithStateStore(store))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "step_2", inFlight.NextStep)
+	assert.Equal(t, StatusUndefined, inFlight.Status)
+	assert.Equal(t, 1, len(inFlight.StepReports))
+
+	final, err := store.Load(ctx, report.RunID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, final.Status)
+	assert.Equal(t, "", final.NextStep)
+	assert.Equal(t, 2, len(final.StepReports))
+}
@@ -7,5 +7,12 @@ const (
 	StatusSuccess   Status = "SUCCESS"
 	StatusFailed    Status = "FAILED"
 	StatusSkipped   Status = "SKIPPED"
+	StatusUndone    Status = "UNDONE"
+	StatusScheduled Status = "SCHEDULED"
+	StatusCancelled Status = "CANCELLED"
+	StatusPartial   Status = "PARTIAL"
+	StatusTimedOut  Status = "TIMED_OUT"
+	StatusPaused    Status = "PAUSED"
+	StatusPlanned   Status = "PLANNED"
 	StatusUndefined Status = "UNDEFINED"
 )
@@ -3,6 +3,7 @@ package automa
 import (
 	"context"
 	"github.com/cockroachdb/errors"
+	"time"
 )
 
 // SagaRun is a func definition to contain the run logic
@@ -17,6 +18,12 @@ type SagaRun func(ctx context.Context) (skipped bool, err error)
 // err return value denotes any error during execution (if any)
 type SagaUndo func(ctx context.Context) (skipped bool, err error)
 
+// stepContextValue is a key-value pair to be injected in the context of a step
+type stepContextValue struct {
+	key   interface{}
+	value interface{}
+}
+
 // Step is the kernel for AtomicStep implementation containing SagaRun and SagaUndo function
 // It is to be used as inheritance by composition pattern by actual Step implementations
 // If the saga methods are not registered, then Step will skip those operations during invocation of Run and Rollback
@@ -29,6 +36,63 @@ type Step struct {
 	// holder of saga methods to be executed during Run and Rollback method of the AtomicStep
 	run      SagaRun
 	rollback SagaUndo
+
+	// severity of the failure of the run action, see Severity
+	severity Severity
+
+	// label to group the step with related steps in the report, see WorkflowReport.Groups
+	group string
+
+	// scheduling priority of the step as a member of a ParallelGroup, see WithPriority
+	priority int
+
+	// static configuration of the step injected in the context of SagaRun and SagaUndo, see WithContextValue
+	contextValues []stepContextValue
+
+	// if set, this overrides the RetryPolicy of the workflow for this step
+	retryPolicy *RetryPolicy
+
+	// maximum duration of every attempt of the run action, see WithTimeout
+	timeout time.Duration
+
+	// maximum number of goroutines started using Go at the same time, see WithGoroutineBudget
+	goroutineBudget int
+
+	// hint that the step makes destructive changes and the reason for not having a rollback, see Workflow.Validate
+	destructive             bool
+	noRollbackJustification string
+
+	// typed outputs produced and inputs required by the step, see DeclareOutput and RequireInput
+	outputs []StepIO
+	inputs  []StepIO
+
+	// read re-checked after a successful run until the writes are visible, see WithConsistencyCheck
+	consistencyCheck   ConsistencyCheck
+	consistencyOptions ConsistencyOptions
+
+	// if set, a successful run of the step is reused when the step is executed again in the same run
+	memoize bool
+
+	// user facing texts of the step, see StepPresenter
+	displayName string
+	description string
+
+	// optional logic to describe the actions of the step without side effects, see Planner
+	plan PlanFunc
+
+	// optional logic to prepare the step ahead of its turn, see Prefetcher
+	prefetch func(ctx context.Context) error
+
+	// if set, these override the modes of the workflow for this step
+	executionMode ExecutionMode
+	rollbackMode  RollbackMode
+
+	// if set, rollback is deferred by rollbackDelay using the scheduler instead of being executed immediately
+	rollbackDelay     time.Duration
+	rollbackScheduler RollbackScheduler
+
+	// the AtomicStep embedding the Step in the workflow, used to invoke its actions again, see RetryOnNilReport
+	self AtomicStep
 }
 
 // RegisterSaga register saga logic for run and undo in order to leverage the default controller logic for Run and Rollback
@@ -41,6 +105,134 @@ func (s *Step) RegisterSaga(run SagaRun, undo SagaUndo) *Step {
 	return s
 }
 
+// WithSeverity sets the Severity of the failure of the step
+// Failures with SeverityWarning or SeverityInfo are tolerated, i.e. the step is marked as failed in the report but
+// the workflow continues with the next step. By default, a step has SeverityCritical.
+func (s *Step) WithSeverity(severity Severity) *Step {
+	s.severity = severity
+
+	return s
+}
+
+// GetSeverity returns the Severity of the step
+func (s *Step) GetSeverity() Severity {
+	if s.severity == "" {
+		return SeverityCritical
+	}
+
+	return s.severity
+}
+
+// WithPriority sets the scheduling priority of the step as a member of a ParallelGroup
+// Under bounded concurrency, see ParallelGroup.WithMaxConcurrency, members with a higher priority are started first and
+// members with the same priority are started in the order of the members. By default, a step has priority 0.
+func (s *Step) WithPriority(priority int) *Step {
+	s.priority = priority
+
+	return s
+}
+
+// GetPriority returns the scheduling priority of the step, see WithPriority
+func (s *Step) GetPriority() int {
+	return s.priority
+}
+
+// WithExecutionMode overrides the ExecutionMode of the workflow for the step
+func (s *Step) WithExecutionMode(mode ExecutionMode) *Step {
+	s.executionMode = mode
+
+	return s
+}
+
+// WithRollbackMode overrides the RollbackMode of the workflow for the step
+func (s *Step) WithRollbackMode(mode RollbackMode) *Step {
+	s.rollbackMode = mode
+
+	return s
+}
+
+// WithGroup sets the label of the group of the step, e.g. "networking"
+// The reports of the step are labelled with the group so that WorkflowReport.Groups summarizes related steps together.
+func (s *Step) WithGroup(group string) *Step {
+	s.group = group
+
+	return s
+}
+
+// WithContextValue injects a static value for the given key in the context passed to SagaRun and SagaUndo
+// It is meant for per-step configuration like endpoints or flags. Values are injected in the order they are added,
+// so that a later value overrides an earlier value for the same key.
+func (s *Step) WithContextValue(key interface{}, value interface{}) *Step {
+	s.contextValues = append(s.contextValues, stepContextValue{key: key, value: value})
+
+	return s
+}
+
+// WithMemoize enables memoization of the step within a workflow run
+// If the step has already been executed successfully in the same run, e.g. when it appears in multiple nested
+// workflows started using the context of the run, the report of the first execution is reused instead of executing it
+// again. The reused report has the Metadata key MemoizedMetadataKey set as "true". The rollback of a memoized
+// occurrence is skipped since the side effects are only compensated by the occurrence that was executed.
+func (s *Step) WithMemoize(memoize bool) *Step {
+	s.memoize = memoize
+
+	return s
+}
+
+// RegisterPrefetch registers the logic to prepare the step ahead of its turn, e.g. pre-downloading binaries
+// The default Run controller logic of Step waits for the prefetch to finish before invoking SagaRun. A failure of the
+// prefetch is reported as a warning since SagaRun is expected to perform the work itself if required.
+func (s *Step) RegisterPrefetch(prefetch func(ctx context.Context) error) *Step {
+	s.prefetch = prefetch
+
+	return s
+}
+
+// Prefetch implements Prefetcher interface
+// It is a NOOP if no prefetch logic is registered using RegisterPrefetch.
+func (s *Step) Prefetch(ctx context.Context) error {
+	if s.prefetch == nil {
+		return nil
+	}
+
+	return s.prefetch(ctx)
+}
+
+// AddUndo registers an additional compensating logic for the step
+// Undo functions are accumulated and executed in reverse order of registration, i.e. the one registered last is
+// executed first. This allows a step with several side effects to register one undo function per side effect.
+// All undo functions are executed even if some of them fail, and the rollback is reported as skipped only if all of
+// them are skipped.
+func (s *Step) AddUndo(undo SagaUndo) *Step {
+	if undo == nil {
+		return s
+	}
+
+	prev := s.rollback
+	if prev == nil {
+		s.rollback = undo
+		return s
+	}
+
+	s.rollback = func(ctx context.Context) (bool, error) {
+		skipped, err := undo(ctx)
+		prevSkipped, prevErr := prev(ctx)
+		return skipped && prevSkipped, errors.CombineErrors(err, prevErr)
+	}
+
+	return s
+}
+
+// ScheduleRollback defers the registered SagaUndo by the given delay using the RollbackScheduler
+// On failure, the rollback of the step is reported as StatusScheduled with the schedule ID in the report Metadata
+// under the key "scheduled_rollback_id", and the rollback of previous steps proceeds immediately.
+func (s *Step) ScheduleRollback(delay time.Duration, scheduler RollbackScheduler) *Step {
+	s.rollbackDelay = delay
+	s.rollbackScheduler = scheduler
+
+	return s
+}
+
 // GetID returns the step ID
 func (s *Step) GetID() string {
 	return s.ID
@@ -70,14 +262,69 @@ func (s *Step) GetPrev() Backward {
 // This is a wrapper function to help simplify AtomicStep implementations
 // Note that user may implement Run method in order to change the control logic as required.
 func (s *Step) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	if pauseRequested(ctx) {
+		return prevSuccess.workflowReport, ErrWorkflowPaused
+	}
+
+	if ctx.Err() != nil {
+		return s.CancelledRun(ctx, prevSuccess)
+	}
+
+	saveState(ctx, prevSuccess.workflowReport)
+
 	report := NewStepReport(s.GetID(), RunAction)
+	trackStep(ctx, s.GetID(), RunAction)
+	emitEvent(ctx, StepStarted, s.GetID(), "", nil)
+	report.Group = s.group
 
 	if s.run == nil {
 		return s.SkippedRun(ctx, prevSuccess, report)
 	}
 
-	skipped, err := s.run(ctx)
+	report.Severity = s.GetSeverity()
+
+	memo := runMemoFromContext(ctx)
+	if s.memoize && memo != nil {
+		if memoized := memo.get(s.GetID()); memoized != nil {
+			return s.RunNext(ctx, prevSuccess, memoized)
+		}
+	}
+
+	if err := waitPrefetch(ctx, s.GetID()); err != nil {
+		AddWarning(ctx, "prefetch of step %q failed: %v", s.GetID(), err)
+	}
+
+	watch := startDumpWatch(ctx)
+	costs, extras := &costMeter{}, &stepExtras{}
+	sio := newStepIO(ctx, prevSuccess.workflowReport)
+	skipped, err := s.runWithRetry(withStepExtras(withStepIO(withCostMeter(ctx, costs), sio), extras), report)
+	if err == nil && !skipped {
+		err = s.waitForConsistency(ctx, report)
+	}
+	costs.addTo(report)
+	extras.addTo(report)
+	if err == nil && !skipped {
+		sio.addTo(report)
+	}
+	watch.stop(report, err)
+	if err != nil && ctx.Err() != nil {
+		return s.interruptedRun(ctx, prevSuccess, report)
+	}
+
 	if err != nil {
+		if q := quarantineFromContext(ctx); q != nil && q.has(s.GetID()) {
+			report.Severity = SeverityWarning
+			q.retry(s.stepContext(detachedContext{ctx}), s.GetID(), s.run)
+		}
+
+		if report.Severity != SeverityCritical {
+			return s.ToleratedRun(ctx, prevSuccess, err, report)
+		}
+
+		if s.getExecutionMode(ctx) == CompensateAndContinue {
+			return s.CompensatedRun(ctx, prevSuccess, err, report)
+		}
+
 		return s.Rollback(ctx, NewFailedRun(ctx, prevSuccess, err, report))
 	}
 
@@ -85,6 +332,10 @@ func (s *Step) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, e
 		return s.SkippedRun(ctx, prevSuccess, report)
 	}
 
+	if s.memoize && memo != nil {
+		memo.put(s.GetID(), report)
+	}
+
 	return s.RunNext(ctx, prevSuccess, report)
 }
 
@@ -92,13 +343,44 @@ func (s *Step) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, e
 // This is a wrapper function to help simplify AtomicStep implementations
 // Note that user may implement Rollback method in order to change the control logic as required.
 func (s *Step) Rollback(ctx context.Context, prevFailure *Failure) (WorkflowReport, error) {
+	// a compensated step was rolled back when its run failed, it must not be rolled back twice
+	if prevFailure.workflowReport.compensated(s.GetID()) {
+		if s.Prev != nil {
+			return rollbackStep(ctx, s.Prev, prevFailure)
+		}
+
+		return prevFailure.workflowReport, nil
+	}
+
 	report := NewStepReport(s.GetID(), RollbackAction)
+	trackStep(ctx, s.GetID(), RollbackAction)
+	emitEvent(ctx, RollbackStarted, s.GetID(), "", nil)
+	report.Group = s.group
+
+	if prevFailure.workflowReport.memoized(s.GetID()) {
+		return s.SkippedRollback(ctx, prevFailure, report)
+	}
 
 	if s.rollback == nil {
+		AddWarning(ctx, "step %q has no rollback", s.GetID())
 		return s.SkippedRollback(ctx, prevFailure, report)
 	}
 
-	skipped, err := s.rollback(ctx)
+	if s.rollbackScheduler != nil {
+		id, err := s.rollbackScheduler.Schedule(s.GetID(), time.Now().Add(s.rollbackDelay), s.scheduledUndo(ctx))
+		if err != nil {
+			return s.FailedRollback(ctx, prevFailure, err, report)
+		}
+
+		report.Metadata["scheduled_rollback_id"] = []byte(id)
+		return s.ScheduledRollback(ctx, prevFailure, report)
+	}
+
+	costs, extras := &costMeter{}, &stepExtras{}
+	skipped, err := callSaga(withStepExtras(withCostMeter(s.stepContext(ctx), costs), extras), s.GetID(), RollbackAction, s.rollback)
+	costs.addTo(report)
+	extras.addTo(report)
+	err = withCancelCause(ctx, err)
 	if err != nil {
 		return s.FailedRollback(ctx, prevFailure, err, report)
 	}
@@ -110,15 +392,134 @@ func (s *Step) Rollback(ctx context.Context, prevFailure *Failure) (WorkflowRepo
 	return s.RollbackPrev(ctx, prevFailure, report)
 }
 
+// ToleratedRun is a helper method to report that current step has failed without affecting the workflow and trigger
+// next step's execution
+// It marks the current step RunAction as StatusFailed, or StatusTimedOut, and no rollback is executed.
+func (s *Step) ToleratedRun(ctx context.Context, prevSuccess *Success, err error, report *StepReport) (WorkflowReport, error) {
+	if report == nil {
+		report, _ = s.nilReport(ctx, RunAction)
+		report.Severity = s.GetSeverity()
+	}
+
+	report.FailureReason = errors.EncodeError(ctx, err)
+	prevSuccess.workflowReport.Append(report, RunAction, failureStatus(err))
+	emitEvent(ctx, StepFailed, s.GetID(), report.Status, err)
+
+	next := &Success{workflowReport: prevSuccess.workflowReport}
+	if s.Next != nil {
+		return runStep(ctx, s.Next, next)
+	}
+
+	return next.workflowReport, nil
+}
+
+// CompensatedRun is a helper method to report that current step has failed, roll back only the current step and trigger
+// next step's execution
+// It marks the current step RunAction as StatusFailed, or StatusTimedOut, and its RollbackAction as per the result of
+// its rollback. If a later step fails, Rollback skips the compensated step so that it is not rolled back twice.
+func (s *Step) CompensatedRun(ctx context.Context, prevSuccess *Success, err error, report *StepReport) (WorkflowReport, error) {
+	if report == nil {
+		report, _ = s.nilReport(ctx, RunAction)
+	}
+
+	report.FailureReason = errors.EncodeError(ctx, err)
+	prevSuccess.workflowReport.Append(report, RunAction, failureStatus(err))
+	emitEvent(ctx, StepFailed, s.GetID(), report.Status, err)
+
+	rollbackReport := NewStepReport(s.GetID(), RollbackAction)
+	rollbackReport.Group = s.group
+	status := StatusSkipped
+	emitEvent(ctx, RollbackStarted, s.GetID(), "", nil)
+	var rollbackErr error
+	if s.rollback != nil {
+		var skipped bool
+		costs, extras := &costMeter{}, &stepExtras{}
+		skipped, rollbackErr = callSaga(withStepExtras(withCostMeter(s.stepContext(ctx), costs), extras), s.GetID(), RollbackAction, s.rollback)
+		costs.addTo(rollbackReport)
+		extras.addTo(rollbackReport)
+		rollbackErr = withCancelCause(ctx, rollbackErr)
+		if rollbackErr != nil {
+			status = StatusFailed
+			rollbackReport.FailureReason = errors.EncodeError(ctx, rollbackErr)
+		} else if !skipped {
+			status = StatusSuccess
+		}
+	}
+	prevSuccess.workflowReport.Append(rollbackReport, RollbackAction, status)
+	if rollbackErr != nil {
+		emitEvent(ctx, RollbackFailed, s.GetID(), status, rollbackErr)
+	} else {
+		emitEvent(ctx, RollbackCompleted, s.GetID(), status, nil)
+	}
+
+	next := &Success{workflowReport: prevSuccess.workflowReport}
+	if s.Next != nil {
+		return runStep(ctx, s.Next, next)
+	}
+
+	return next.workflowReport, nil
+}
+
+// CancelledRun is a helper method to stop the workflow when its context is cancelled before the run of the step
+// It marks the run of the step as StatusCancelled, or StatusTimedOut if the deadline of the context is exceeded, and
+// rolls back the previous steps as per the CancelBehavior. The rollback uses a context that keeps the values of the run
+// but is not cancelled so that it can complete gracefully.
+func (s *Step) CancelledRun(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	report := NewStepReport(s.GetID(), RunAction)
+	report.Group = s.group
+	failure := s.newCancelledRun(ctx, prevSuccess, report)
+
+	if cancelBehaviorFromContext(ctx) == StopOnCancel || s.Prev == nil {
+		return failure.workflowReport, failure.error
+	}
+
+	return rollbackStep(detachedContext{ctx}, s.Prev, failure)
+}
+
+// interruptedRun stops the workflow when its context is cancelled while the run action of the step is running
+// Unlike CancelledRun, the step itself is rolled back too since its run action may have been partially executed.
+func (s *Step) interruptedRun(ctx context.Context, prevSuccess *Success, report *StepReport) (WorkflowReport, error) {
+	failure := s.newCancelledRun(ctx, prevSuccess, report)
+
+	if cancelBehaviorFromContext(ctx) == StopOnCancel {
+		return failure.workflowReport, failure.error
+	}
+
+	return s.Rollback(detachedContext{ctx}, failure)
+}
+
+// newCancelledRun marks the run of the step as StatusCancelled, or StatusTimedOut if the deadline of the context is
+// exceeded, and returns the Failure event with the context error
+func (s *Step) newCancelledRun(ctx context.Context, prevSuccess *Success, report *StepReport) *Failure {
+	err := &StepError{StepID: s.GetID(), Action: RunAction, Err: withCancelCause(ctx, ctx.Err())}
+	status := cancelledStatus(ctx)
+	report.FailureReason = errors.EncodeError(ctx, err.Err)
+	prevSuccess.workflowReport.Append(report, RunAction, status)
+	emitEvent(ctx, StepFailed, s.GetID(), status, err)
+
+	return &Failure{error: err, workflowReport: prevSuccess.workflowReport}
+}
+
 // SkippedRun is a helper method to report that current step has been skipped and trigger next step's execution
 // It marks the current step as StatusSkipped
 func (s *Step) SkippedRun(ctx context.Context, prevSuccess *Success, report *StepReport) (WorkflowReport, error) {
 	if report == nil {
-		report = NewStepReport(s.GetID(), RunAction)
+		var policy NilReportPolicy
+		report, policy = s.nilReport(ctx, RunAction)
+		switch policy {
+		case RetryOnNilReport:
+			if retryCtx, ok := s.retryNilReport(ctx, RunAction); ok {
+				return s.self.Run(retryCtx, prevSuccess)
+			}
+			fallthrough
+		case FailOnNilReport:
+			return s.Rollback(ctx, NewFailedRun(ctx, prevSuccess, s.nilReportError(RunAction), report))
+		}
 	}
 
+	emitEvent(ctx, StepCompleted, s.GetID(), StatusSkipped, nil)
 	if s.Next != nil {
-		return s.Next.Run(ctx, NewSkippedRun(prevSuccess, report))
+		return runStep(ctx, s.Next, NewSkippedRun(prevSuccess, report))
 	}
 
 	prevSuccess.workflowReport.Append(report, RunAction, StatusSkipped)
@@ -130,11 +531,22 @@ func (s *Step) SkippedRun(ctx context.Context, prevSuccess *Success, report *Ste
 // It marks the current step as StatusSkipped
 func (s *Step) SkippedRollback(ctx context.Context, prevFailure *Failure, report *StepReport) (WorkflowReport, error) {
 	if report == nil {
-		report = NewStepReport(s.GetID(), RollbackAction)
+		var policy NilReportPolicy
+		report, policy = s.nilReport(ctx, RollbackAction)
+		switch policy {
+		case RetryOnNilReport:
+			if retryCtx, ok := s.retryNilReport(ctx, RollbackAction); ok {
+				return s.self.Rollback(retryCtx, prevFailure)
+			}
+			fallthrough
+		case FailOnNilReport:
+			return s.FailedRollback(ctx, prevFailure, s.nilReportError(RollbackAction), report)
+		}
 	}
 
+	emitEvent(ctx, RollbackCompleted, s.GetID(), StatusSkipped, nil)
 	if s.Prev != nil {
-		return s.Prev.Rollback(ctx, NewSkippedRollback(prevFailure, report))
+		return rollbackStep(ctx, s.Prev, NewSkippedRollback(prevFailure, report))
 	}
 
 	prevFailure.workflowReport.Append(report, RollbackAction, StatusSkipped)
@@ -142,17 +554,53 @@ func (s *Step) SkippedRollback(ctx context.Context, prevFailure *Failure, report
 	return prevFailure.workflowReport, nil
 }
 
+// ScheduledRollback is a helper method to report that current step's rollback has been scheduled for later and trigger
+// previous step's rollback
+// It marks the current step RollbackAction as StatusScheduled
+func (s *Step) ScheduledRollback(ctx context.Context, prevFailure *Failure, report *StepReport) (WorkflowReport, error) {
+	if report == nil {
+		var policy NilReportPolicy
+		report, policy = s.nilReport(ctx, RollbackAction)
+		switch policy {
+		case RetryOnNilReport:
+			if retryCtx, ok := s.retryNilReport(ctx, RollbackAction); ok {
+				return s.self.Rollback(retryCtx, prevFailure)
+			}
+			fallthrough
+		case FailOnNilReport:
+			return s.FailedRollback(ctx, prevFailure, s.nilReportError(RollbackAction), report)
+		case SkipOnNilReport:
+			return s.SkippedRollback(ctx, prevFailure, report)
+		}
+	}
+
+	emitEvent(ctx, RollbackCompleted, s.GetID(), StatusScheduled, nil)
+	if s.Prev != nil {
+		return rollbackStep(ctx, s.Prev, NewScheduledRollback(prevFailure, report))
+	}
+
+	prevFailure.workflowReport.Append(report, RollbackAction, StatusScheduled)
+
+	return prevFailure.workflowReport, nil
+}
+
 // FailedRollback is a helper method to report that current step's rollback has failed and trigger previous step's rollback
+// If the RollbackMode is StopOnRollbackError, the rollback of the previous steps is not triggered.
 // It marks the current step RollbackAction as StatusFailed
 func (s *Step) FailedRollback(ctx context.Context, prevFailure *Failure, err error, report *StepReport) (WorkflowReport, error) {
 	if report == nil {
-		report = NewStepReport(s.GetID(), RollbackAction)
+		report, _ = s.nilReport(ctx, RollbackAction)
 	}
 
 	report.FailureReason = errors.EncodeError(ctx, err)
 
+	if s.getRollbackMode(ctx) == StopOnRollbackError {
+		failure := NewFailedRollback(ctx, prevFailure, err, report)
+		return failure.workflowReport, failure.error
+	}
+
 	if s.Prev != nil {
-		return s.Prev.Rollback(ctx, NewFailedRollback(ctx, prevFailure, err, report))
+		return rollbackStep(ctx, s.Prev, NewFailedRollback(ctx, prevFailure, err, report))
 	}
 
 	prevFailure.workflowReport.Append(report, RollbackAction, StatusFailed)
@@ -164,11 +612,28 @@ func (s *Step) FailedRollback(ctx context.Context, prevFailure *Failure, err err
 // It marks the current step as StatusSuccess
 func (s *Step) RunNext(ctx context.Context, prevSuccess *Success, report *StepReport) (WorkflowReport, error) {
 	if report == nil {
-		report = NewStepReport(s.GetID(), RunAction)
+		var policy NilReportPolicy
+		report, policy = s.nilReport(ctx, RunAction)
+		switch policy {
+		case RetryOnNilReport:
+			if retryCtx, ok := s.retryNilReport(ctx, RunAction); ok {
+				return s.self.Run(retryCtx, prevSuccess)
+			}
+			fallthrough
+		case FailOnNilReport:
+			return s.Rollback(ctx, NewFailedRun(ctx, prevSuccess, s.nilReportError(RunAction), report))
+		case SkipOnNilReport:
+			return s.SkippedRun(ctx, prevSuccess, report)
+		}
 	}
 
+	if err := checkStateSize(ctx, &prevSuccess.workflowReport, report); err != nil {
+		return s.Rollback(ctx, NewFailedRun(ctx, prevSuccess, err, report))
+	}
+
+	emitEvent(ctx, StepCompleted, s.GetID(), StatusSuccess, nil)
 	if s.Next != nil {
-		return s.Next.Run(ctx, NewSuccess(prevSuccess, report))
+		return runStep(ctx, s.Next, NewSuccess(prevSuccess, report))
 	}
 
 	prevSuccess.workflowReport.Append(report, RunAction, StatusSuccess)
@@ -179,17 +644,124 @@ func (s *Step) RunNext(ctx context.Context, prevSuccess *Success, report *StepRe
 // It marks the current step as StatusFailed
 func (s *Step) RollbackPrev(ctx context.Context, prevFailure *Failure, report *StepReport) (WorkflowReport, error) {
 	if report == nil {
-		report = NewStepReport(s.GetID(), RollbackAction)
+		var policy NilReportPolicy
+		report, policy = s.nilReport(ctx, RollbackAction)
+		switch policy {
+		case RetryOnNilReport:
+			if retryCtx, ok := s.retryNilReport(ctx, RollbackAction); ok {
+				return s.self.Rollback(retryCtx, prevFailure)
+			}
+			fallthrough
+		case FailOnNilReport:
+			return s.FailedRollback(ctx, prevFailure, s.nilReportError(RollbackAction), report)
+		case SkipOnNilReport:
+			return s.SkippedRollback(ctx, prevFailure, report)
+		}
 	}
 
+	emitEvent(ctx, RollbackCompleted, s.GetID(), StatusSuccess, nil)
 	if s.Prev != nil {
-		return s.Prev.Rollback(ctx, NewFailure(prevFailure, report))
+		return rollbackStep(ctx, s.Prev, NewFailure(prevFailure, report))
 	}
 
 	prevFailure.workflowReport.Append(report, RollbackAction, StatusSuccess)
 	return prevFailure.workflowReport, nil
 }
 
+// scheduledUndo returns the SagaUndo handed over to the RollbackScheduler
+// The context of the scheduler is extended with the IDs of the run and the static values of the step, and a panic is
+// recovered since the rollback may be executed on a timer goroutine.
+func (s *Step) scheduledUndo(ctx context.Context) SagaUndo {
+	workflowID, _ := WorkflowIdFromContext(ctx)
+	runID, _ := RunIdFromContext(ctx)
+
+	return func(ctx context.Context) (bool, error) {
+		return callSaga(s.stepContext(withRun(ctx, workflowID, runID)), s.GetID(), RollbackAction, s.rollback)
+	}
+}
+
+// stepContext returns the context for SagaRun and SagaUndo containing the step ID and the static values of the step
+func (s *Step) stepContext(ctx context.Context) context.Context {
+	for _, cv := range s.contextValues {
+		ctx = context.WithValue(ctx, cv.key, cv.value)
+	}
+
+	ctx = context.WithValue(ctx, ctxKeyGoroutineBudget, s.goroutineBudget)
+
+	return withStepID(ctx, s.GetID())
+}
+
+// runStep invokes the Run method of the next step with its ID injected in the context, see StepFromContext
+// Terminal steps don't have an ID and get the context as is.
+func runStep(ctx context.Context, next Forward, prevSuccess *Success) (WorkflowReport, error) {
+	if step, ok := next.(interface{ GetID() string }); ok {
+		ctx = withStepID(ctx, step.GetID())
+	}
+
+	return next.Run(ctx, prevSuccess)
+}
+
+// rollbackStep invokes the Rollback method of the previous step with its ID injected in the context, see
+// StepFromContext
+func rollbackStep(ctx context.Context, prev Backward, prevFailure *Failure) (WorkflowReport, error) {
+	if step, ok := prev.(interface{ GetID() string }); ok {
+		ctx = withStepID(ctx, step.GetID())
+	}
+
+	return prev.Rollback(ctx, prevFailure)
+}
+
+// getExecutionMode returns the ExecutionMode of the step if set, otherwise the one of the workflow
+func (s *Step) getExecutionMode(ctx context.Context) ExecutionMode {
+	if s.executionMode != "" {
+		return s.executionMode
+	}
+
+	return executionModeFromContext(ctx)
+}
+
+// getRollbackMode returns the RollbackMode of the step if set, otherwise the one of the workflow
+func (s *Step) getRollbackMode(ctx context.Context) RollbackMode {
+	if s.rollbackMode != "" {
+		return s.rollbackMode
+	}
+
+	return rollbackModeFromContext(ctx)
+}
+
+// nilReport returns a default report to replace a nil report along with the NilReportPolicy to be applied
+// It adds a warning to the workflow report unless the policy is FailOnNilReport.
+func (s *Step) nilReport(ctx context.Context, action StepActionType) (*StepReport, NilReportPolicy) {
+	policy := nilReportPolicyFromContext(ctx)
+	if policy != FailOnNilReport {
+		AddWarning(ctx, "step %q reported a nil report", s.GetID())
+	}
+
+	return NewStepReport(s.GetID(), action), policy
+}
+
+// bind records the AtomicStep embedding the Step in the workflow
+func (s *Step) bind(step AtomicStep) {
+	s.self = step
+}
+
+// stepBinder is implemented by the AtomicStep implementations embedding Step
+type stepBinder interface {
+	bind(step AtomicStep)
+}
+
+// bindStep records the AtomicStep in the Step it embeds, if any
+func bindStep(step AtomicStep) {
+	if b, ok := step.(stepBinder); ok {
+		b.bind(step)
+	}
+}
+
+// nilReportError returns the error to be used when a nil report is treated as a failure
+func (s *Step) nilReportError(action StepActionType) error {
+	return errors.Newf("step %q reported a nil report for %s action", s.GetID(), action)
+}
+
 // failedStep defines the failed state of the Workflow that implements Backward interface only
 // This is one of the terminal states of the Workflow that works as the prev step of the first AtomicStep of the Workflow
 type failedStep struct {
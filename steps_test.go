@@ -6,6 +6,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 type mockSuccessStep struct {
@@ -292,3 +293,243 @@ func TestRunWithFailure(t *testing.T) {
 	assert.Equal(t, StatusSuccess, reports.StepReports[0].Status)
 
 }
+
+func TestStep_AddUndo(t *testing.T) {
+	ctx := context.Background()
+
+	var order []string
+	undo := func(name string, skipped bool, err error) SagaUndo {
+		return func(ctx context.Context) (bool, error) {
+			order = append(order, name)
+			return skipped, err
+		}
+	}
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(nil, undo("undo_1", false, nil)).
+		AddUndo(nil).
+		AddUndo(undo("undo_2", true, nil)).
+		AddUndo(undo("undo_3", false, nil))
+
+	skipped, err := s1.rollback(ctx)
+	assert.NoError(t, err)
+	assert.False(t, skipped)
+	assert.Equal(t, []string{"undo_3", "undo_2", "undo_1"}, order)
+
+	// all undo functions are executed even if one fails
+	order = nil
+	s2 := &Step{ID: "step_2"}
+	s2.AddUndo(undo("undo_1", true, nil)).
+		AddUndo(undo("undo_2", false, errors.New("mock error"))).
+		AddUndo(undo("undo_3", true, nil))
+
+	skipped, err = s2.rollback(ctx)
+	assert.Error(t, err)
+	assert.False(t, skipped)
+	assert.Equal(t, []string{"undo_3", "undo_2", "undo_1"}, order)
+
+	s3 := &Step{ID: "step_3"}
+	s3.AddUndo(undo("undo_1", true, nil)).AddUndo(undo("undo_2", true, nil))
+	skipped, err = s3.rollback(ctx)
+	assert.NoError(t, err)
+	assert.True(t, skipped)
+}
+
+func TestNilReportPolicy(t *testing.T) {
+	s1 := &mockSuccessStep{
+		Step:  Step{ID: "stop_containers"},
+		cache: map[string][]byte{},
+	}
+
+	// skip policy marks the action as skipped
+	ctx := withNilReportPolicy(context.Background(), SkipOnNilReport)
+	prevSuccess := &Success{workflowReport: *NewWorkflowReport("test", nil)}
+	reports, err := s1.RunNext(ctx, prevSuccess, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(reports.StepReports))
+	assert.Equal(t, StatusSkipped, reports.StepReports[0].Status)
+
+	prevFailure := &Failure{error: errors.New("Test"), workflowReport: *NewWorkflowReport("test", nil)}
+	reports, err = s1.RollbackPrev(ctx, prevFailure, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(reports.StepReports))
+	assert.Equal(t, StatusSkipped, reports.StepReports[0].Status)
+
+	// fail policy treats the nil report as a failure
+	ctx = withNilReportPolicy(context.Background(), FailOnNilReport)
+	prevSuccess = &Success{workflowReport: *NewWorkflowReport("test", nil)}
+	reports, err = s1.RunNext(ctx, prevSuccess, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(reports.StepReports))
+	assert.Equal(t, StatusFailed, reports.StepReports[0].Status)
+	assert.Equal(t, RunAction, reports.StepReports[0].Action)
+
+	prevFailure = &Failure{error: errors.New("Test"), workflowReport: *NewWorkflowReport("test", nil)}
+	reports, err = s1.SkippedRollback(ctx, prevFailure, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(reports.StepReports))
+	assert.Equal(t, StatusFailed, reports.StepReports[0].Status)
+}
+
+func TestWorkflow_WithNilReportPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &mockSuccessStep{
+		Step:  Step{ID: "stop_containers"},
+		cache: map[string][]byte{},
+	}
+	s1.RegisterSaga(s1.run, s1.rollback)
+
+	s2 := &mockNilReportStep{Step: Step{ID: "legacy_step"}}
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"step \"legacy_step\" reported a nil report"}, report.Warnings)
+
+	workflow = NewWorkflow("workflow_2", WithSteps(s1, s2), WithNilReportPolicy(FailOnNilReport))
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.NotContains(t, report.Warnings, "step \"legacy_step\" reported a nil report")
+}
+
+// mockNilReportStep is an example of a legacy step implementation that doesn't provide any report
+type mockNilReportStep struct {
+	Step
+}
+
+func (s *mockNilReportStep) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	return s.RunNext(ctx, prevSuccess, nil)
+}
+
+// mockFlakyReportStep is an example of a legacy step implementation that doesn't provide any report at first
+type mockFlakyReportStep struct {
+	Step
+	nilReports int
+	runs       int
+	rollbacks  int
+}
+
+func (s *mockFlakyReportStep) Run(ctx context.Context, prevSuccess *Success) (WorkflowReport, error) {
+	s.runs++
+	if s.runs <= s.nilReports {
+		return s.RunNext(ctx, prevSuccess, nil)
+	}
+
+	return s.RunNext(ctx, prevSuccess, NewStepReport(s.GetID(), RunAction))
+}
+
+func (s *mockFlakyReportStep) Rollback(ctx context.Context, prevFailure *Failure) (WorkflowReport, error) {
+	s.rollbacks++
+	if s.rollbacks <= s.nilReports {
+		return s.RollbackPrev(ctx, prevFailure, nil)
+	}
+
+	return s.RollbackPrev(ctx, prevFailure, NewStepReport(s.GetID(), RollbackAction))
+}
+
+func TestWorkflow_WithNilReportPolicy_Retry(t *testing.T) {
+	ctx := context.Background()
+
+	// the action is invoked again until it provides a report
+	s1 := &mockFlakyReportStep{Step: Step{ID: "legacy_step"}, nilReports: 2}
+	workflow := NewWorkflow("workflow_1", WithSteps(s1),
+		WithNilReportPolicy(RetryOnNilReport), WithRetryPolicy(3, ConstantBackoff(time.Millisecond)))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.Equal(t, 3, s1.runs)
+	assert.Equal(t, 1, len(report.StepReports))
+
+	// the nil report is a failure once the attempts are exhausted
+	s2 := &mockFlakyReportStep{Step: Step{ID: "legacy_step"}, nilReports: 3}
+	workflow = NewWorkflow("workflow_2", WithSteps(s2),
+		WithNilReportPolicy(RetryOnNilReport), WithRetryPolicy(3, ConstantBackoff(time.Millisecond)))
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, 3, s2.runs)
+
+	// rollback actions are invoked again as well
+	s3 := &mockFlakyReportStep{Step: Step{ID: "legacy_step"}, nilReports: 1}
+	s4 := &Step{ID: "fail"}
+	s4.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil)
+	workflow = NewWorkflow("workflow_3", WithSteps(s3, s4),
+		WithNilReportPolicy(RetryOnNilReport), WithRetryPolicy(2, nil))
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 2, s3.runs)
+	assert.Equal(t, 2, s3.rollbacks)
+	last := report.StepReports[len(report.StepReports)-1]
+	assert.Equal(t, RollbackAction, last.Action)
+	assert.Equal(t, StatusSuccess, last.Status)
+
+	// without a retry policy the nil report is a failure
+	s5 := &mockFlakyReportStep{Step: Step{ID: "legacy_step"}, nilReports: 1}
+	workflow = NewWorkflow("workflow_4", WithSteps(s5), WithNilReportPolicy(RetryOnNilReport))
+	_, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, s5.runs)
+}
+
+func TestStep_WithGroup(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "create_vpc"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil).WithGroup("networking")
+
+	s2 := &Step{ID: "check_disk"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "networking", report.StepReports[0].Group)
+	assert.Equal(t, "", report.StepReports[1].Group)
+	assert.Equal(t, 1, len(report.Groups))
+	assert.Equal(t, StatusSuccess, report.Groups[0].Status)
+	assert.Equal(t, StepIDs{"create_vpc"}, report.Groups[0].StepIDs)
+}
+
+func TestStep_WithContextValue(t *testing.T) {
+	ctx := context.Background()
+
+	type endpointKey struct{}
+	type dryRunKey struct{}
+
+	var runEndpoint, rollbackEndpoint interface{}
+	var dryRun interface{}
+	s1 := &Step{ID: "register_node"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		runEndpoint = ctx.Value(endpointKey{})
+		dryRun = ctx.Value(dryRunKey{})
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		rollbackEndpoint = ctx.Value(endpointKey{})
+		return false, nil
+	}).WithContextValue(endpointKey{}, "http://old").
+		WithContextValue(endpointKey{}, "http://registry").
+		WithContextValue(dryRunKey{}, true)
+
+	var otherEndpoint interface{}
+	s2 := &Step{ID: "start_node"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		otherEndpoint = ctx.Value(endpointKey{})
+		return false, errors.New("mock error")
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	_, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, "http://registry", runEndpoint)
+	assert.Equal(t, "http://registry", rollbackEndpoint)
+	assert.Equal(t, true, dryRun)
+	assert.Nil(t, otherEndpoint)
+}
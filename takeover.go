@@ -0,0 +1,97 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"time"
+)
+
+// TakeOverAction defines how a zombie run is handled once its lease is taken over
+type TakeOverAction string
+
+const (
+	// ResumeRun resumes the run from the step that was being executed when the heartbeat stopped
+	ResumeRun TakeOverAction = "resume"
+
+	// CompensateRun marks the run as failed and compensates it by rolling back from the step that was being executed
+	CompensateRun TakeOverAction = "compensate"
+)
+
+// ErrRunAbandoned is the failure reason of a run compensated after being taken over
+var ErrRunAbandoned = errors.New("run was abandoned")
+
+// TakeOver takes over a zombie run of the Workflow whose heartbeat has not been updated for longer than the ttl
+// The heartbeat store set using WithHeartbeat must implement LeaseStore so that only one process takes over the run.
+// The step recorded in the heartbeat is executed again, therefore steps must be idempotent. The returned report only
+// contains the step reports of the resumed or compensated part of the run, since the original report was lost.
+// Resuming the run is subject to the admission policies of the Workflow as AdmitResume, see WithAdmissionPolicy.
+func (wf *Workflow) TakeOver(ctx context.Context, runID string, ttl time.Duration, action TakeOverAction) (WorkflowReport, error) {
+	defer wf.dispatchCallbacks()
+	wf.mutex.Lock()
+	defer wf.mutex.Unlock()
+
+	store, ok := wf.heartbeatStore.(LeaseStore)
+	if !ok {
+		return wf.report, errors.Newf("heartbeat store of workflow %q does not support leases", wf.id)
+	}
+
+	// a resumed run executes steps again, it is admitted before the lease is taken over
+	if action == ResumeRun {
+		if err := wf.admit(ctx, AdmitResume, runID); err != nil {
+			return wf.report, err
+		}
+	}
+
+	release, err := wf.acquire(ctx)
+	if err != nil {
+		return wf.report, err
+	}
+	defer release()
+
+	hb, ok, err := store.TakeOver(ctx, runID, ttl, time.Now())
+	if err != nil {
+		return wf.report, errors.Wrapf(err, "failed to take over run %q", runID)
+	}
+
+	if !ok {
+		return wf.report, errors.Newf("run %q is not a zombie run", runID)
+	}
+
+	if hb.WorkflowID != wf.id {
+		return wf.report, errors.Newf("run %q belongs to workflow %q", runID, hb.WorkflowID)
+	}
+
+	var step AtomicStep
+	for _, s := range wf.steps {
+		if s.GetID() == hb.CurrentStep {
+			step = s
+			break
+		}
+	}
+
+	if step == nil {
+		return wf.report, errors.Newf("step %q of run %q is not found in workflow %q", hb.CurrentStep, runID, wf.id)
+	}
+
+	wf.report.StepSequence = wf.stepIDs
+	wf.report.Status = StatusUndefined
+	wf.report.StartTime = hb.StartTime
+	wf.report.StepReports = []*StepReport{}
+	wf.report.Outputs = map[string][]byte{}
+	wf.report.RunID = hb.RunID
+	wf.report.ManifestHash = wf.Manifest().Hash()
+	wf.report.ManifestDiff = nil
+
+	return wf.execute(ctx, func(ctx context.Context) (WorkflowReport, error) {
+		if action == ResumeRun && hb.CurrentAction == RunAction {
+			return runStep(wf.phaseContext(ctx, step.GetID()), step, NewStartTrigger(wf.report))
+		}
+
+		// a run abandoned during rollback is always resumed from the rollback of the current step
+		return rollbackStep(wf.phaseContext(ctx, step.GetID()), step, &Failure{workflowReport: wf.report, error: &StepError{
+			StepID: step.GetID(),
+			Action: hb.CurrentAction,
+			Err:    ErrRunAbandoned,
+		}})
+	})
+}
@@ -0,0 +1,161 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkflow_TakeOver(t *testing.T) {
+	ctx := context.Background()
+
+	var executed []string
+	newStep := func(id string) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			executed = append(executed, "run_"+id)
+			return false, nil
+		}, func(ctx context.Context) (skipped bool, err error) {
+			executed = append(executed, "rollback_"+id)
+			return false, nil
+		})
+		return s
+	}
+
+	store := NewInMemHeartbeatStore()
+	zombie := func(runID string, action StepActionType) {
+		assert.NoError(t, store.SaveHeartbeat(ctx, Heartbeat{
+			WorkflowID:    "workflow_1",
+			RunID:         runID,
+			CurrentStep:   "step_2",
+			CurrentAction: action,
+			Status:        StatusUndefined,
+			StartTime:     time.Now().Add(-2 * time.Hour),
+			Time:          time.Now().Add(-time.Hour),
+		}))
+	}
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(newStep("step_1"), newStep("step_2"), newStep("step_3")),
+		WithHeartbeat(store, time.Minute))
+
+	// resume
+	zombie("run_1", RunAction)
+	report, err := workflow.TakeOver(ctx, "run_1", time.Minute, ResumeRun)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.Equal(t, "run_1", report.RunID)
+	assert.Equal(t, []string{"run_step_2", "run_step_3"}, executed)
+	hb, _, _ := store.LoadHeartbeat(ctx, "run_1")
+	assert.Equal(t, StatusSuccess, hb.Status)
+
+	// the run is not a zombie anymore
+	_, err = workflow.TakeOver(ctx, "run_1", time.Minute, ResumeRun)
+	assert.Error(t, err)
+
+	// compensate
+	executed = nil
+	zombie("run_2", RunAction)
+	report, err = workflow.TakeOver(ctx, "run_2", time.Minute, CompensateRun)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRunAbandoned))
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, []string{"rollback_step_2", "rollback_step_1"}, executed)
+
+	// lease is not expired
+	zombie("run_3", RunAction)
+	_, err = workflow.TakeOver(ctx, "run_3", 2*time.Hour, ResumeRun)
+	assert.Error(t, err)
+
+	// the store must support leases
+	workflow = NewWorkflow("workflow_1", WithSteps(newStep("step_1")))
+	_, err = workflow.TakeOver(ctx, "run_3", time.Minute, ResumeRun)
+	assert.Error(t, err)
+}
+
+func TestWorkflow_TakeOver_AdmissionPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	executed := false
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		executed = true
+		return false, nil
+	}, nil)
+
+	store := NewInMemHeartbeatStore()
+	assert.NoError(t, store.SaveHeartbeat(ctx, Heartbeat{
+		WorkflowID:    "workflow_1",
+		RunID:         "run_1",
+		CurrentStep:   "step_1",
+		CurrentAction: RunAction,
+		Status:        StatusUndefined,
+		StartTime:     time.Now().Add(-2 * time.Hour),
+		Time:          time.Now().Add(-time.Hour),
+	}))
+
+	var requests []AdmissionRequest
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(s1),
+		WithHeartbeat(store, time.Minute),
+		WithAdmissionPolicy("no_resume", AdmissionPolicyFunc(func(ctx context.Context, req AdmissionRequest) ([]PolicyViolation, error) {
+			requests = append(requests, req)
+			return []PolicyViolation{{Reason: "resume is not allowed"}}, nil
+		})))
+	defer workflow.End(ctx)
+
+	_, err := workflow.TakeOver(ctx, "run_1", time.Minute, ResumeRun)
+	var violationErr *PolicyViolationError
+	assert.True(t, errors.As(err, &violationErr))
+	assert.False(t, executed)
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, AdmitResume, requests[0].Action)
+	assert.Equal(t, "run_1", requests[0].RunID)
+
+	// the run is still a zombie that can be taken over by another process
+	_, ok, err := store.TakeOver(ctx, "run_1", time.Minute, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestWorkflow_TakeOver_ParallelGroup(t *testing.T) {
+	ctx := context.Background()
+
+	var runs int32
+	newStep := func(id string) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			atomic.AddInt32(&runs, 1)
+			return false, nil
+		}, nil)
+		return s
+	}
+
+	store := NewInMemHeartbeatStore()
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(newStep("prepare"), NewParallelGroup("install_tools", newStep("install_helm"), newStep("install_jq"))),
+		WithHeartbeat(store, time.Minute))
+
+	// the heartbeat reports the group rather than its members
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	hb, ok, _ := store.LoadHeartbeat(ctx, report.RunID)
+	assert.True(t, ok)
+	assert.Equal(t, "install_tools", hb.CurrentStep)
+
+	// so that a run abandoned within the group can be taken over
+	hb.RunID = "run_1"
+	hb.Status = StatusUndefined
+	hb.CurrentAction = RunAction
+	hb.Time = time.Now().Add(-time.Hour)
+	assert.NoError(t, store.SaveHeartbeat(ctx, hb))
+
+	atomic.StoreInt32(&runs, 0)
+	report, err = workflow.TakeOver(ctx, "run_1", time.Minute, ResumeRun)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, report.Status)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
@@ -0,0 +1,58 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"time"
+)
+
+// WithTimeout sets the maximum duration of the run actions of a workflow run
+// The context of the run is cancelled once the timeout is exceeded, including the contexts of nested workflows and
+// parallel group members, and the run is rolled back. Rollback actions are not subject to the timeout. The report of
+// the run then has StatusTimedOut.
+func WithTimeout(timeout time.Duration) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.timeout = timeout
+	}
+}
+
+// WithTimeout sets the maximum duration of every attempt of the run action of the step
+// Once the timeout is exceeded, the context passed to SagaRun is cancelled and the run action has StatusTimedOut.
+func (s *Step) WithTimeout(timeout time.Duration) *Step {
+	s.timeout = timeout
+
+	return s
+}
+
+// runContext returns the context for an attempt of the run action with the step timeout, if any
+// The deadline of the run, see WithTimeout, is inherited from the context of the run.
+func (s *Step) runContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// failureStatus returns StatusTimedOut if the error is a timeout, or else StatusFailed
+func failureStatus(err error) Status {
+	if IsTimeout(err) {
+		return StatusTimedOut
+	}
+
+	return StatusFailed
+}
+
+// cancelledStatus returns StatusTimedOut if the deadline of the context is exceeded, or else StatusCancelled
+func cancelledStatus(ctx context.Context) Status {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return StatusTimedOut
+	}
+
+	return StatusCancelled
+}
+
+// isFailure returns true if the status is StatusFailed or StatusTimedOut
+func isFailure(status Status) bool {
+	return status == StatusFailed || status == StatusTimedOut
+}
@@ -0,0 +1,135 @@
+package automa
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStep_WithTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	hang := func(ctx context.Context) (skipped bool, err error) {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+
+	rolledBack := false
+	s1 := &Step{ID: "prepare"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		// rollback is not subject to the timeout
+		rolledBack = ctx.Err() == nil
+		return false, nil
+	})
+
+	s2 := &Step{ID: "hung_script"}
+	s2.RegisterSaga(hang, nil).WithTimeout(10 * time.Millisecond)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.True(t, IsTimeout(err))
+	assert.Equal(t, StatusTimedOut, report.Status)
+	assert.Equal(t, StatusTimedOut, report.StepReports[1].Status)
+	assert.True(t, rolledBack)
+	assert.Equal(t, StepIDs{"hung_script"}, report.HardFailures())
+
+	// tolerated timeout
+	s2.WithSeverity(SeverityWarning)
+	report, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPartial, report.Status)
+	assert.Equal(t, StatusTimedOut, report.StepReports[1].Status)
+	assert.Equal(t, StepIDs{"hung_script"}, report.ToleratedFailures())
+
+	// other failures are not timeouts
+	s3 := &Step{ID: "failing_script"}
+	s3.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, errors.New("mock error")
+	}, nil).WithTimeout(time.Second)
+	report, err = NewWorkflow("workflow_2", WithSteps(s3)).Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, StatusFailed, report.StepReports[0].Status)
+}
+
+func TestWorkflow_WithTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	var deadlines []time.Time
+	newStep := func(id string, delay time.Duration) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			deadline, _ := ctx.Deadline()
+			deadlines = append(deadlines, deadline)
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(delay):
+				return false, nil
+			}
+		}, nil)
+		return s
+	}
+
+	// the run deadline is shared by all steps
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(newStep("step_1", 20*time.Millisecond), newStep("step_2", time.Second)),
+		WithTimeout(50*time.Millisecond))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, StatusTimedOut, report.Status)
+	assert.Equal(t, StatusSuccess, report.StepReports[0].Status)
+	assert.Equal(t, StatusTimedOut, report.StepReports[1].Status)
+	assert.Equal(t, deadlines[0], deadlines[1])
+
+	// a shorter step timeout takes precedence
+	deadlines = nil
+	workflow = NewWorkflow("workflow_1",
+		WithSteps(newStep("step_1", 0).WithTimeout(time.Minute)),
+		WithTimeout(time.Hour))
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.True(t, deadlines[0].Before(time.Now().Add(2*time.Minute)))
+}
+
+func TestWorkflow_WithTimeout_RunContext(t *testing.T) {
+	ctx := context.Background()
+
+	// the run context is cancelled for nested workflows too and the rollback is not subject to the timeout
+	hungStep := &Step{ID: "hung_step"}
+	hungStep.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}, nil)
+	nested := NewWorkflow("nested", WithSteps(hungStep))
+	defer nested.End(ctx)
+
+	var rollbackErr error
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		rollbackErr = ctx.Err()
+		return false, nil
+	})
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		_, err = nested.Start(ctx)
+		return false, err
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2), WithTimeout(20*time.Millisecond))
+	defer workflow.End(ctx)
+	report, err := workflow.Start(ctx)
+	assert.True(t, IsTimeout(err))
+	assert.Equal(t, StatusTimedOut, report.Status)
+	assert.Equal(t, StatusTimedOut, report.StepReports[1].Status)
+	assert.Equal(t, StatusSuccess, report.StepReports[3].Status)
+	assert.NoError(t, rollbackErr)
+	assert.Equal(t, StatusTimedOut, nested.report.Status)
+}
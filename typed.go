@@ -0,0 +1,258 @@
+package automa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"reflect"
+	"sync"
+)
+
+// StepIO defines a typed output produced or an input required by a step, see DeclareOutput and RequireInput
+type StepIO struct {
+	Key  string `yaml:"key" json:"key"`
+	Type string `yaml:"type" json:"type"`
+}
+
+// Output is a typed output of a step stored in StepReport.Outputs
+// Strings and byte slices are stored as is, other types are stored as JSON.
+type Output[T any] struct {
+	key string
+}
+
+// Input is a typed input of a step read from the outputs of the previous steps of the run
+type Input[T any] struct {
+	key string
+}
+
+// DeclareOutput declares that the step produces the output with the given key and type
+// The declaration is added to the StepManifest of the step so that Workflow.Validate checks the inputs of the later
+// steps. The returned Output is used by SagaRun to set the value.
+func DeclareOutput[T any](s *Step, key string) Output[T] {
+	s.outputs = append(s.outputs, StepIO{Key: key, Type: typeName[T]()})
+
+	return Output[T]{key: key}
+}
+
+// RequireInput declares that the step requires the input with the given key and type
+// Workflow.Validate reports a violation if no previous step declares an output with the same key and type, unless the
+// key is imported using ImportInputs. The returned Input is used by SagaRun to get the value.
+func RequireInput[T any](s *Step, key string) Input[T] {
+	s.inputs = append(s.inputs, StepIO{Key: key, Type: typeName[T]()})
+
+	return Input[T]{key: key}
+}
+
+// Key returns the key of the output
+func (o Output[T]) Key() string {
+	return o.key
+}
+
+// Set sets the value of the output of the step being executed
+// It is meant to be called from SagaRun, the value is added to StepReport.Outputs once SagaRun succeeds.
+func (o Output[T]) Set(ctx context.Context, val T) error {
+	data, err := encodeOutput(val)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode output %q", o.key)
+	}
+
+	return PublishOutput(ctx, o.key, data)
+}
+
+// Key returns the key of the input
+func (i Input[T]) Key() string {
+	return i.key
+}
+
+// Get returns the value of the input from the outputs of the previous steps of the run
+// It falls back to the inputs imported using ImportInputs. It returns error if the input is missing or it cannot be
+// decoded as T.
+func (i Input[T]) Get(ctx context.Context) (T, error) {
+	var val T
+	data, ok := lookupInput(ctx, i.key)
+	if !ok {
+		return val, errors.Newf("input %q is not available", i.key)
+	}
+
+	if err := decodeOutput(data, &val); err != nil {
+		return val, errors.Wrapf(err, "failed to decode input %q as %s", i.key, typeName[T]())
+	}
+
+	return val, nil
+}
+
+// SetOutput sets the typed value of the output in the StepReport
+// It is meant to be used by AtomicStep implementations that build their own report.
+func SetOutput[T any](report *StepReport, key string, val T) error {
+	data, err := encodeOutput(val)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode output %q", key)
+	}
+
+	if report.Outputs == nil {
+		report.Outputs = map[string][]byte{}
+	}
+
+	report.Outputs[key] = data
+
+	return nil
+}
+
+// typeName returns the name of the type T
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
+
+// encodeOutput returns strings and byte slices as is and other values as JSON
+func encodeOutput(val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// decodeOutput decodes the data into strings and byte slices as is and into other types as JSON
+func decodeOutput(data []byte, val interface{}) error {
+	switch v := val.(type) {
+	case *[]byte:
+		*v = data
+		return nil
+	case *string:
+		*v = string(data)
+		return nil
+	default:
+		return json.Unmarshal(data, v)
+	}
+}
+
+// stepIO holds the outputs available to a step and the outputs it sets
+type stepIO struct {
+	available map[string][]byte
+
+	mutex    sync.Mutex
+	produced map[string][]byte
+}
+
+// newStepIO returns a stepIO with the outputs of the successful RunAction of the steps in the report
+// The outputs available to the parent context, e.g. the ones before a ParallelGroup, are available too.
+func newStepIO(ctx context.Context, report WorkflowReport) *stepIO {
+	sio := &stepIO{available: map[string][]byte{}, produced: map[string][]byte{}}
+	if parent, ok := ctx.Value(ctxKeyStepIO).(*stepIO); ok && parent != nil {
+		for key, val := range parent.available {
+			sio.available[key] = val
+		}
+	}
+
+	for _, stepReport := range report.StepReports {
+		if stepReport.Action != RunAction || stepReport.Status != StatusSuccess {
+			continue
+		}
+
+		for key, val := range stepReport.Outputs {
+			sio.available[key] = val
+		}
+	}
+
+	return sio
+}
+
+// set sets the value of an output of the step
+func (sio *stepIO) set(key string, data []byte) {
+	sio.mutex.Lock()
+	defer sio.mutex.Unlock()
+
+	sio.produced[key] = data
+}
+
+// addTo adds the outputs set by the step to its report
+func (sio *stepIO) addTo(report *StepReport) {
+	sio.mutex.Lock()
+	defer sio.mutex.Unlock()
+
+	for key, val := range sio.produced {
+		if report.Outputs == nil {
+			report.Outputs = map[string][]byte{}
+		}
+
+		report.Outputs[key] = val
+	}
+}
+
+// withStepIO returns a copy of the context with the given stepIO, nil to hide the outputs of a parent workflow
+func withStepIO(ctx context.Context, sio *stepIO) context.Context {
+	return context.WithValue(ctx, ctxKeyStepIO, sio)
+}
+
+// lookupInput returns the output of a previous step of the run or the imported input with the given key
+func lookupInput(ctx context.Context, key string) ([]byte, bool) {
+	if sio, ok := ctx.Value(ctxKeyStepIO).(*stepIO); ok && sio != nil {
+		if val, ok := sio.available[key]; ok {
+			return val, true
+		}
+	}
+
+	if val, ok := InputValue(ctx, key); ok {
+		return val.Data, true
+	}
+
+	return nil, false
+}
+
+// validateIO returns the violations of the inputs of the steps that are not produced by the previous steps
+// The members of a ParallelGroup only see the outputs of the steps before the group.
+func (wf *Workflow) validateIO() []Violation {
+	var violations []Violation
+	produced := map[string]StepIO{}
+	if wf.inputs != nil {
+		for key := range wf.inputs.Values {
+			produced[key] = StepIO{Key: key}
+		}
+	}
+
+	check := func(step AtomicStep) []StepIO {
+		d, ok := step.(StepDescriber)
+		if !ok {
+			return nil
+		}
+
+		m := d.Describe()
+		for _, input := range m.Inputs {
+			output, ok := produced[input.Key]
+			if !ok {
+				violations = append(violations, Violation{
+					StepID: step.GetID(),
+					Reason: fmt.Sprintf("input %q is not produced by any previous step", input.Key),
+				})
+			} else if output.Type != "" && output.Type != input.Type {
+				violations = append(violations, Violation{
+					StepID: step.GetID(),
+					Reason: fmt.Sprintf("input %q of type %s is produced as %s", input.Key, input.Type, output.Type),
+				})
+			}
+		}
+
+		return m.Outputs
+	}
+
+	for _, step := range wf.steps {
+		var outputs []StepIO
+		if g, ok := step.(*ParallelGroup); ok {
+			for _, member := range g.members {
+				outputs = append(outputs, check(member)...)
+			}
+		} else {
+			outputs = check(step)
+		}
+
+		for _, output := range outputs {
+			produced[output.Key] = output
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,111 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// clusterInfo is an example of a typed output
+type clusterInfo struct {
+	Name  string `json:"name"`
+	Nodes int    `json:"nodes"`
+}
+
+func TestTypedOutputsAndInputs(t *testing.T) {
+	ctx := context.Background()
+
+	create := &Step{ID: "create_cluster"}
+	clusterOut := DeclareOutput[clusterInfo](create, "cluster")
+	regionOut := DeclareOutput[string](create, "region")
+	create.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		if err = clusterOut.Set(ctx, clusterInfo{Name: "c-1", Nodes: 3}); err != nil {
+			return false, err
+		}
+
+		return false, regionOut.Set(ctx, "us-east-1")
+	}, nil)
+
+	var got []interface{}
+	deploy := &Step{ID: "deploy"}
+	clusterIn := RequireInput[clusterInfo](deploy, "cluster")
+	regionIn := RequireInput[string](deploy, "region")
+	deploy.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		cluster, err := clusterIn.Get(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		region, err := regionIn.Get(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		got = append(got, cluster, region)
+		return false, nil
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(create, NewParallelGroup("group", deploy)), WithStrictValidation(true))
+	defer workflow.End(ctx)
+	assert.Empty(t, workflow.Validate())
+
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{clusterInfo{Name: "c-1", Nodes: 3}, "us-east-1"}, got)
+	assert.Equal(t, []byte(`{"name":"c-1","nodes":3}`), report.Outputs["cluster"])
+	assert.Equal(t, []byte("us-east-1"), report.Outputs["region"])
+
+	m := create.Describe()
+	assert.Equal(t, []StepIO{{Key: "cluster", Type: "automa.clusterInfo"}, {Key: "region", Type: "string"}}, m.Outputs)
+}
+
+func TestWorkflow_ValidateIO(t *testing.T) {
+	produce := &Step{ID: "produce"}
+	DeclareOutput[int](produce, "count")
+
+	consume := &Step{ID: "consume"}
+	RequireInput[string](consume, "count")
+	RequireInput[string](consume, "token")
+	RequireInput[string](consume, "imported")
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(consume, produce),
+		ImportInputs(&PortableState{Values: map[string]PortableValue{"imported": {Type: PortableString}}}),
+	)
+	assert.Equal(t, []Violation{
+		{StepID: "consume", Reason: `input "count" is not produced by any previous step`},
+		{StepID: "consume", Reason: `input "token" is not produced by any previous step`},
+	}, workflow.Validate())
+
+	workflow = NewWorkflow("workflow_1", WithSteps(produce, consume))
+	assert.Equal(t, []Violation{
+		{StepID: "consume", Reason: `input "count" of type string is produced as int`},
+		{StepID: "consume", Reason: `input "token" is not produced by any previous step`},
+		{StepID: "consume", Reason: `input "imported" is not produced by any previous step`},
+	}, workflow.Validate())
+
+	_, err := NewWorkflow("workflow_1", WithSteps(produce, consume), WithStrictValidation(true)).Start(context.Background())
+	assert.IsType(t, &ValidationError{}, err)
+}
+
+func TestSetOutput(t *testing.T) {
+	report := NewStepReport("step_1", RunAction)
+	assert.NoError(t, SetOutput(report, "nodes", []int{1, 2}))
+	assert.NoError(t, SetOutput(report, "raw", []byte{0x1}))
+	assert.Equal(t, []byte("[1,2]"), report.Outputs["nodes"])
+	assert.Equal(t, []byte{0x1}, report.Outputs["raw"])
+
+	// outputs and inputs are not available outside of a step
+	ctx := context.Background()
+	assert.Error(t, DeclareOutput[int](&Step{ID: "step_1"}, "count").Set(ctx, 1))
+	_, err := RequireInput[int](&Step{ID: "step_1"}, "count").Get(ctx)
+	assert.Error(t, err)
+
+	// imported inputs are decoded as per the type of the input
+	ctx = withInputs(ctx, &PortableState{Values: map[string]PortableValue{"count": {Type: PortableJSON, Data: []byte("3")}}})
+	count, err := RequireInput[int](&Step{ID: "step_1"}, "count").Get(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	_, err = RequireInput[clusterInfo](&Step{ID: "step_1"}, "count").Get(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package automa
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RollbackDescriber is an optional interface for steps to tell whether they have a rollback action
+// Step implements it. Steps that don't implement it are assumed to have a rollback action.
+type RollbackDescriber interface {
+	HasRollback() bool
+}
+
+// ValidationError is the error returned by Start when a workflow with strict validation has violations, or by
+// StepRegistry.BuildWorkflowFromSpec when the spec does not match the registered builders
+type ValidationError struct {
+	WorkflowID string
+	Violations []Violation
+}
+
+// Error implements error interface for ValidationError
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+
+	return fmt.Sprintf("workflow %q is invalid: %s", e.WorkflowID, strings.Join(msgs, "; "))
+}
+
+// WithDestructive marks the step as making destructive changes, e.g. deleting files or dropping tables
+// Workflow.Validate reports a destructive step without rollback unless WithNoRollbackJustification is set.
+func (s *Step) WithDestructive(destructive bool) *Step {
+	s.destructive = destructive
+
+	return s
+}
+
+// WithNoRollbackJustification documents why a destructive step has no rollback, e.g. "data is backed up by step X"
+func (s *Step) WithNoRollbackJustification(justification string) *Step {
+	s.noRollbackJustification = justification
+
+	return s
+}
+
+// HasRollback implements RollbackDescriber interface
+func (s *Step) HasRollback() bool {
+	return s.rollback != nil
+}
+
+// WithStrictValidation allows Start to fail with a ValidationError if Validate reports any violation
+// By default, the violations are added to the warnings of the report of every run.
+func WithStrictValidation(strict bool) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.strictValidation = strict
+	}
+}
+
+// Validate checks the workflow definition for saga hygiene and returns the list of violations
+// A destructive step, see Step.WithDestructive, must have a rollback or a justification for not having one, and the
+// inputs of every step must be produced by the previous steps, see RequireInput. Steps are described using
+// StepDescriber and RollbackDescriber. An empty list means that the workflow is valid.
+func (wf *Workflow) Validate() []Violation {
+	violations := []Violation{}
+	for _, step := range wf.steps {
+		d, ok := step.(StepDescriber)
+		if !ok {
+			continue
+		}
+
+		m := d.Describe()
+		if !m.Destructive || m.NoRollbackJustification != "" {
+			continue
+		}
+
+		if r, ok := step.(RollbackDescriber); ok && !r.HasRollback() {
+			violations = append(violations, Violation{StepID: step.GetID(), Reason: "destructive step has no rollback"})
+		}
+	}
+
+	return append(violations, wf.validateIO()...)
+}
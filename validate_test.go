@@ -0,0 +1,48 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWorkflow_Validate(t *testing.T) {
+	ctx := context.Background()
+	noop := func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}
+
+	s1 := &Step{ID: "delete_files"}
+	s1.RegisterSaga(noop, nil).WithDestructive(true)
+
+	s2 := &Step{ID: "drop_table"}
+	s2.RegisterSaga(noop, nil).WithDestructive(true).WithNoRollbackJustification("table is backed up")
+
+	s3 := &Step{ID: "move_files"}
+	s3.RegisterSaga(noop, noop).WithDestructive(true)
+
+	s4 := &Step{ID: "read_files"}
+	s4.RegisterSaga(noop, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2, s3, s4))
+	violations := workflow.Validate()
+	assert.Equal(t, []Violation{{StepID: "delete_files", Reason: "destructive step has no rollback"}}, violations)
+	assert.True(t, workflow.Manifest().Steps[0].Destructive)
+
+	// warning by default
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`step "delete_files": destructive step has no rollback`}, report.Warnings)
+
+	// error under strict validation
+	workflow = NewWorkflow("workflow_1", WithSteps(s1, s2, s3, s4), WithStrictValidation(true))
+	_, err = workflow.Start(ctx)
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, violations, validationErr.Violations)
+	assert.Equal(t, `workflow "workflow_1" is invalid: step "delete_files": destructive step has no rollback`, err.Error())
+
+	s1.WithNoRollbackJustification("files are regenerated")
+	_, err = workflow.Start(ctx)
+	assert.NoError(t, err)
+}
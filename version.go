@@ -0,0 +1,56 @@
+package automa
+
+// version of the module, it is updated by `cz bump` as per cz.yaml
+const version = "0.2.1"
+
+// APIContract defines the compatibility guarantee of an exported interface since the given version
+// Stable interfaces only change in a major release, while the others may still change in a minor release.
+type APIContract struct {
+	Name   string `yaml:"name" json:"name"`
+	Since  string `yaml:"since" json:"since"`
+	Stable bool   `yaml:"stable" json:"stable"`
+}
+
+// VersionInfo defines the version of the module and the compatibility matrix of its exported interfaces
+type VersionInfo struct {
+	Version string        `yaml:"version" json:"version"`
+	APIs    []APIContract `yaml:"apis" json:"apis"`
+}
+
+// nextVersion is the next unreleased version of the module
+// Interfaces added or changed since the last release are listed with it in the compatibility matrix.
+const nextVersion = "0.3.0"
+
+// apiContracts is the compatibility matrix of the exported interfaces
+// The method sets of the interfaces are checked by the tests so that they cannot change without updating it.
+var apiContracts = []APIContract{
+	{Name: "Forward", Since: "0.2.1", Stable: true},
+	{Name: "Backward", Since: "0.2.1", Stable: true},
+	{Name: "Choreographer", Since: "0.2.1", Stable: true},
+	{Name: "AtomicStep", Since: "0.2.1", Stable: true},
+	{Name: "AtomicStepRegistry", Since: "0.2.1", Stable: true},
+	{Name: "AtomicWorkflow", Since: "0.2.1", Stable: true},
+	{Name: "Undoer", Since: nextVersion},
+	{Name: "StateStore", Since: nextVersion},
+	{Name: "HeartbeatStore", Since: nextVersion},
+	{Name: "LeaseStore", Since: nextVersion},
+	{Name: "RollbackScheduler", Since: nextVersion},
+	{Name: "StepDescriber", Since: nextVersion},
+	{Name: "RollbackDescriber", Since: nextVersion},
+	{Name: "Planner", Since: nextVersion},
+	{Name: "Prefetcher", Since: nextVersion},
+	{Name: "StepPresenter", Since: nextVersion},
+	{Name: "MessageCatalog", Since: nextVersion},
+	{Name: "EventEmitter", Since: nextVersion},
+	{Name: "MetricsCollector", Since: nextVersion},
+	{Name: "AdmissionPolicy", Since: nextVersion},
+	{Name: "ReportSink", Since: nextVersion},
+}
+
+// Version returns the version of the module and the compatibility matrix of its exported interfaces
+func Version() VersionInfo {
+	apis := make([]APIContract, len(apiContracts))
+	copy(apis, apiContracts)
+
+	return VersionInfo{Version: version, APIs: apis}
+}
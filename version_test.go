@@ -0,0 +1,140 @@
+package automa
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// conformance of the types of the module to the stable interfaces
+var (
+	_ AtomicStep         = (*Step)(nil)
+	_ AtomicStep         = (*ParallelGroup)(nil)
+	_ AtomicStepRegistry = (*StepRegistry)(nil)
+	_ AtomicWorkflow     = (*Workflow)(nil)
+	_ Undoer             = (*Workflow)(nil)
+	_ StepDescriber      = (*Step)(nil)
+	_ RollbackDescriber  = (*Step)(nil)
+	_ Planner            = (*Step)(nil)
+	_ Planner            = (*ParallelGroup)(nil)
+)
+
+// apiInterfaces returns the exported interfaces listed in the compatibility matrix
+func apiInterfaces() map[string]reflect.Type {
+	return map[string]reflect.Type{
+		"Forward":            reflect.TypeOf((*Forward)(nil)).Elem(),
+		"Backward":           reflect.TypeOf((*Backward)(nil)).Elem(),
+		"Choreographer":      reflect.TypeOf((*Choreographer)(nil)).Elem(),
+		"AtomicStep":         reflect.TypeOf((*AtomicStep)(nil)).Elem(),
+		"AtomicStepRegistry": reflect.TypeOf((*AtomicStepRegistry)(nil)).Elem(),
+		"AtomicWorkflow":     reflect.TypeOf((*AtomicWorkflow)(nil)).Elem(),
+		"Undoer":             reflect.TypeOf((*Undoer)(nil)).Elem(),
+		"StateStore":         reflect.TypeOf((*StateStore)(nil)).Elem(),
+		"HeartbeatStore":     reflect.TypeOf((*HeartbeatStore)(nil)).Elem(),
+		"LeaseStore":         reflect.TypeOf((*LeaseStore)(nil)).Elem(),
+		"RollbackScheduler":  reflect.TypeOf((*RollbackScheduler)(nil)).Elem(),
+		"StepDescriber":      reflect.TypeOf((*StepDescriber)(nil)).Elem(),
+		"RollbackDescriber":  reflect.TypeOf((*RollbackDescriber)(nil)).Elem(),
+		"Planner":            reflect.TypeOf((*Planner)(nil)).Elem(),
+		"Prefetcher":         reflect.TypeOf((*Prefetcher)(nil)).Elem(),
+		"StepPresenter":      reflect.TypeOf((*StepPresenter)(nil)).Elem(),
+		"MessageCatalog":     reflect.TypeOf((*MessageCatalog)(nil)).Elem(),
+		"EventEmitter":       reflect.TypeOf((*EventEmitter)(nil)).Elem(),
+		"MetricsCollector":   reflect.TypeOf((*MetricsCollector)(nil)).Elem(),
+		"AdmissionPolicy":    reflect.TypeOf((*AdmissionPolicy)(nil)).Elem(),
+		"ReportSink":         reflect.TypeOf((*ReportSink)(nil)).Elem(),
+	}
+}
+
+// methodSet returns the sorted signatures of the methods of the interface
+func methodSet(t reflect.Type) []string {
+	var methods []string
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		methods = append(methods, fmt.Sprintf("%s %s", m.Name, m.Type))
+	}
+
+	sort.Strings(methods)
+
+	return methods
+}
+
+// stableAPI contains the method sets of the stable interfaces
+// A change of these method sets breaks the embedders of the module and requires a major release.
+var stableAPI = map[string][]string{
+	"Forward": {
+		"Run func(context.Context, *automa.Success) (automa.WorkflowReport, error)",
+	},
+	"Backward": {
+		"Rollback func(context.Context, *automa.Failure) (automa.WorkflowReport, error)",
+	},
+	"Choreographer": {
+		"GetNext func() automa.Forward",
+		"GetPrev func() automa.Backward",
+		"SetNext func(automa.Forward)",
+		"SetPrev func(automa.Backward)",
+	},
+	"AtomicStep": {
+		"GetID func() string",
+		"GetNext func() automa.Forward",
+		"GetPrev func() automa.Backward",
+		"Rollback func(context.Context, *automa.Failure) (automa.WorkflowReport, error)",
+		"Run func(context.Context, *automa.Success) (automa.WorkflowReport, error)",
+		"SetNext func(automa.Forward)",
+		"SetPrev func(automa.Backward)",
+	},
+	"AtomicStepRegistry": {
+		"BuildWorkflow func(string, automa.StepIDs) (automa.AtomicWorkflow, error)",
+		"GetStep func(string) automa.AtomicStep",
+		"RegisterSteps func(map[string]automa.AtomicStep) automa.AtomicStepRegistry",
+	},
+	"AtomicWorkflow": {
+		"End func(context.Context)",
+		"GetID func() string",
+		"Start func(context.Context) (automa.WorkflowReport, error)",
+	},
+}
+
+func TestVersion(t *testing.T) {
+	info := Version()
+	assert.Equal(t, version, info.Version)
+	assert.Equal(t, len(apiContracts), len(info.APIs))
+
+	// the returned matrix is a copy
+	info.APIs[0].Stable = false
+	assert.True(t, Version().APIs[0].Stable)
+}
+
+func TestVersion_StableAPI(t *testing.T) {
+	interfaces := apiInterfaces()
+	for _, contract := range Version().APIs {
+		typ, ok := interfaces[contract.Name]
+		if !assert.True(t, ok, "interface %s of the compatibility matrix is not checked", contract.Name) {
+			continue
+		}
+
+		if contract.Stable {
+			assert.Equal(t, stableAPI[contract.Name], methodSet(typ),
+				"method set of stable interface %s has changed", contract.Name)
+		}
+	}
+
+	assert.Equal(t, len(interfaces), len(Version().APIs))
+}
+
+func TestVersion_Since(t *testing.T) {
+	current, err := parseSemver(version)
+	assert.NoError(t, err)
+	next, err := parseSemver(nextVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, next.compare(current), "next version must be greater than the released version")
+
+	// an interface cannot be listed with a version later than the next release
+	for _, contract := range Version().APIs {
+		since, err := parseSemver(contract.Since)
+		assert.NoError(t, err)
+		assert.True(t, since.compare(next) <= 0, "interface %s is listed since %s", contract.Name, contract.Since)
+	}
+}
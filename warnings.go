@@ -0,0 +1,57 @@
+package automa
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// warnings collects unique warning messages during a workflow run
+type warnings struct {
+	mutex    sync.Mutex
+	seen     map[string]bool
+	messages []string
+}
+
+// newWarnings returns an empty warnings collector
+func newWarnings() *warnings {
+	return &warnings{seen: map[string]bool{}}
+}
+
+// add adds the message if it has not been added already
+func (w *warnings) add(msg string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.seen[msg] {
+		return
+	}
+
+	w.seen[msg] = true
+	w.messages = append(w.messages, msg)
+}
+
+// list returns the collected warning messages in the order they were added
+func (w *warnings) list() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	msgs := make([]string, len(w.messages))
+	copy(msgs, w.messages)
+
+	return msgs
+}
+
+// withWarnings returns a copy of the context with the given warnings collector
+func withWarnings(ctx context.Context, w *warnings) context.Context {
+	return context.WithValue(ctx, ctxKeyWarnings, w)
+}
+
+// AddWarning adds a warning to the report of the current workflow run
+// A warning message is reported only once per run, even if it is added multiple times.
+// It is a NOOP if the context doesn't belong to a workflow run.
+func AddWarning(ctx context.Context, format string, args ...interface{}) {
+	if w, ok := ctx.Value(ctxKeyWarnings).(*warnings); ok {
+		w.add(fmt.Sprintf(format, args...))
+	}
+}
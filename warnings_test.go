@@ -0,0 +1,49 @@
+package automa
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAddWarning(t *testing.T) {
+	// NOOP outside a workflow run
+	AddWarning(context.Background(), "ignored")
+
+	w := newWarnings()
+	ctx := withWarnings(context.Background(), w)
+	AddWarning(ctx, "warning %d", 1)
+	AddWarning(ctx, "warning %d", 2)
+	AddWarning(ctx, "warning %d", 1)
+	assert.Equal(t, []string{"warning 1", "warning 2"}, w.list())
+}
+
+func TestWorkflow_Warnings(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		AddWarning(ctx, "deprecated parameter")
+		AddWarning(ctx, "deprecated parameter")
+		return false, nil
+	}, nil)
+
+	s2 := &mockRestartContainersStep{
+		Step:  Step{ID: "restart_containers"},
+		cache: map[string][]byte{},
+	}
+	s2.RegisterSaga(s2.run, s2.rollback)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"deprecated parameter", "step \"step_1\" has no rollback"}, report.Warnings)
+
+	// warnings are collected per run
+	workflow = NewWorkflow("workflow_2", WithSteps(s1))
+	for i := 0; i < 2; i++ {
+		report, err = workflow.Start(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"deprecated parameter"}, report.Warnings)
+	}
+}
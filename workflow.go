@@ -2,8 +2,10 @@ package automa
 
 import (
 	"context"
+	"github.com/cockroachdb/errors"
 	"go.uber.org/zap"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,10 +31,132 @@ type Workflow struct {
 
 	logger  *zap.Logger
 	stepIDs StepIDs
+	steps   []AtomicStep
+
+	// undoWindow is the duration after a successful run during which Undo is allowed
+	// undoDeadline is set at the end of a successful run and cleared once the run is undone or the workflow is ended
+	undoWindow   time.Duration
+	undoDeadline time.Time
+
+	// callbacks to be invoked at the end of a run
+	// if asyncCallbacks is set, callbacks are executed by a bounded dispatcher that is drained when the workflow ends
+	onCompletion   WorkflowCallback
+	onFailure      WorkflowCallback
+	asyncCallbacks *asyncCallbackConfig
+
+	// callbackMutex guards the dispatcher and the async callbacks of the runs waiting to be dispatched, it is never
+	// held while dispatching so that callbacks blocked by a full queue don't block the Workflow, see dispatchCallbacks
+	callbackMutex    sync.Mutex
+	dispatcher       *callbackDispatcher
+	pendingCallbacks []func()
+	droppedCallbacks uint64
+
+	// failures of async callbacks since they cannot be added to the report that has already been returned
+	asyncFailureMutex sync.Mutex
+	asyncFailures     []*CallbackFailure
+
+	// executionMode is injected in the context of the steps, see ExecutionMode
+	executionMode ExecutionMode
+
+	// rollbackMode is injected in the context of the steps, see RollbackMode
+	rollbackMode RollbackMode
+
+	// cancelBehavior is injected in the context of the steps, see CancelBehavior
+	cancelBehavior CancelBehavior
+
+	// nilReportPolicy is injected in the context of the steps, see NilReportPolicy
+	nilReportPolicy NilReportPolicy
+
+	// retryPolicy is injected in the context of the steps, see RetryPolicy
+	retryPolicy *RetryPolicy
+
+	// phase of the steps added using WithPhase
+	stepPhases map[string]*Phase
+
+	// if set, Start fails if Validate reports any violation
+	strictValidation bool
+
+	// policies checking the runs before any step is executed, see WithAdmissionPolicy
+	policies []namedPolicy
+
+	// listeners of the lifecycle events of the runs, if any
+	events *eventBus
+
+	// settings of the goroutine dumps of failing or slow steps, see WithGoroutineDump
+	goroutineDump *GoroutineDumpOptions
+
+	// features toggled for the runs, see WithFeatures
+	features map[Feature]bool
+
+	// tolerance of the skew of the clocks reporting the timestamps of the steps, see WithClockSkewTolerance
+	clockSkewTolerance *time.Duration
+
+	// thresholds of the size of the state of a run, see WithStateSizeLimits
+	stateSizeLimits *StateSizeLimits
+
+	// maximum duration of the run actions of a run, see WithTimeout
+	timeout time.Duration
+
+	// progress reported by the steps of the current run, see Progress
+	progress *progressTracker
+
+	// pause request of the current run, see Pause
+	pause *pauseSignal
+
+	// store to persist the state of the runs, if any
+	stateStore StateStore
+
+	// outputs of the nested workflows merged into the reports of the steps running them, see WithStateMergePolicy
+	mergePolicy *StateMergePolicy
+
+	// sink receiving the reports of the runs through a bounded buffer created on the first run, see WithReportSink
+	reportSink        ReportSink
+	reportSinkOptions ReportSinkOptions
+	reportSinkStats   *ReportSinkStats
+	reportSinkBuffer  *reportSinkBuffer
+
+	// masks sensitive values of the reports handed to callbacks and the report sink, see WithReportRedactor
+	redactor ReportRedactor
+
+	// quarantine of known-flaky steps, if any
+	quarantine *quarantine
+
+	// manifest of the previous run, used to report changes of the workflow definition between runs
+	prevManifest *WorkflowManifest
+
+	// catalog to localize the user facing texts of the steps, if any
+	catalog MessageCatalog
+
+	// store to persist heartbeats of in-flight runs, if any
+	heartbeatStore    HeartbeatStore
+	heartbeatInterval time.Duration
+
+	// locker serializing the runs of workflows with the same key, if any
+	runLocker      *RunLocker
+	runLockKey     string
+	runLockTimeout time.Duration
+
+	// outputs of a previous run to warm-start the runs, if any
+	seed *seedState
+
+	// inputs handed off by another workflow, if any
+	inputs *PortableState
+
+	// quota limiting the executions of the workflow, if any
+	quotaManager *QuotaManager
+	quotaKey     string
+}
+
+// asyncCallbackConfig holds the settings of the async callback dispatcher
+type asyncCallbackConfig struct {
+	workers   int
+	queueSize int
+	policy    BackpressurePolicy
 }
 
 // addStep add an AtomicStep in the internal double linked list of steps
 func (wf *Workflow) addStep(s AtomicStep) {
+	bindStep(s)
 	if wf.firstStep == nil {
 		wf.firstStep = s
 		wf.firstStep.SetPrev(wf.failedStep)
@@ -53,6 +177,7 @@ func WithSteps(steps ...AtomicStep) WorkflowOption {
 	return func(wf *Workflow) {
 		for _, step := range steps {
 			wf.addStep(step)
+			wf.steps = append(wf.steps, step)
 			wf.stepIDs = append(wf.stepIDs, step.GetID())
 		}
 	}
@@ -66,6 +191,128 @@ func WithLogger(logger *zap.Logger) WorkflowOption {
 	}
 }
 
+// WithUndoWindow allows a successful run of the Workflow to be reversed using Undo within the given window
+// Steps are expected to keep the data required by their rollback logic at least for the duration of the window.
+// By default, Undo is disabled for a Workflow.
+func WithUndoWindow(window time.Duration) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.undoWindow = window
+	}
+}
+
+// WithOnCompletion allows Workflow to be initialized with a callback to be invoked when a run succeeds
+func WithOnCompletion(cb WorkflowCallback) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.onCompletion = cb
+	}
+}
+
+// WithOnFailure allows Workflow to be initialized with a callback to be invoked when a run fails
+func WithOnFailure(cb WorkflowCallback) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.onFailure = cb
+	}
+}
+
+// WithAsyncCallbacks allows callbacks to be executed asynchronously by a bounded dispatcher
+// At most the given number of workers execute callbacks concurrently and at most queueSize callbacks can be pending.
+// The policy determines whether the workflow blocks or drops callbacks when the queue is full.
+// Pending callbacks are drained when the Workflow ends. By default, callbacks are executed synchronously.
+func WithAsyncCallbacks(workers int, queueSize int, policy BackpressurePolicy) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.asyncCallbacks = &asyncCallbackConfig{
+			workers:   workers,
+			queueSize: queueSize,
+			policy:    policy,
+		}
+	}
+}
+
+// WithExecutionMode allows Workflow to be initialized with an ExecutionMode
+// The mode is passed to the steps through the context and is honoured by the default Run controller logic of Step.
+// By default a Workflow is initialized with StopOnError.
+func WithExecutionMode(mode ExecutionMode) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.executionMode = mode
+	}
+}
+
+// WithRollbackMode allows Workflow to be initialized with a RollbackMode
+// The mode is passed to the steps through the context and is honoured by the default Rollback controller logic of Step.
+// By default a Workflow is initialized with ContinueOnRollbackError.
+func WithRollbackMode(mode RollbackMode) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.rollbackMode = mode
+	}
+}
+
+// WithCancelBehavior allows Workflow to be initialized with a CancelBehavior
+// The behavior is passed to the steps through the context and is honoured by the default Run controller logic of Step.
+// By default a Workflow is initialized with RollbackOnCancel.
+func WithCancelBehavior(behavior CancelBehavior) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.cancelBehavior = behavior
+	}
+}
+
+// WithNilReportPolicy allows Workflow to be initialized with a NilReportPolicy
+// The policy is passed to the steps through the context and is honoured by the helper methods of Step.
+// By default a Workflow is initialized with WarnOnNilReport.
+func WithNilReportPolicy(policy NilReportPolicy) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.nilReportPolicy = policy
+	}
+}
+
+// WithQuarantine allows Workflow to be initialized with a list of known-flaky steps
+// Failures of quarantined steps are tolerated as SeverityWarning failures and their run action is retried once in a
+// background queue. The results of the retries are available using QuarantineRetries once the Workflow ends.
+func WithQuarantine(ids ...string) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.quarantine = newQuarantine(ids, zap.NewNop())
+	}
+}
+
+// WithPreviousManifest allows Workflow to be initialized with the manifest of a previous run, e.g. loaded from disk
+// If the workflow definition has changed, the ManifestDiff is embedded in the report of the next run.
+// The manifest of every run is retained in memory to report changes between subsequent runs.
+func WithPreviousManifest(m WorkflowManifest) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.prevManifest = &m
+	}
+}
+
+// WithHeartbeat allows the runs of the Workflow to persist a Heartbeat in the store at every interval
+// The heartbeat contains the step being executed so that external monitors can detect dead runs. A final heartbeat
+// with the status of the run is saved when the run finishes.
+func WithHeartbeat(store HeartbeatStore, interval time.Duration) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.heartbeatStore = store
+		wf.heartbeatInterval = interval
+	}
+}
+
+// WithQuota allows the executions of the Workflow to be limited by the QuotaManager for the given key
+// The key may be the workflow ID or a tenant ID shared by multiple workflows. If key is empty, the workflow ID is used.
+// Start returns a QuotaExceeded error without executing any step if the quota is exceeded.
+func WithQuota(manager *QuotaManager, key string) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.quotaManager = manager
+		wf.quotaKey = key
+	}
+}
+
+// WithRunLock allows the runs of workflows sharing the same key to be serialized using the RunLocker
+// If key is empty, the workflow ID is used. Start blocks until the other run releases the lock and returns a
+// RunLockTimeout error without executing any step if the lock is not acquired within the timeout.
+func WithRunLock(locker *RunLocker, key string, timeout time.Duration) WorkflowOption {
+	return func(wf *Workflow) {
+		wf.runLocker = locker
+		wf.runLockKey = key
+		wf.runLockTimeout = timeout
+	}
+}
+
 // NewWorkflow returns an instance of WorkFlow that implements AtomicWorkflow interface
 func NewWorkflow(id string, opts ...WorkflowOption) *Workflow {
 	fs := &failedStep{}
@@ -78,12 +325,23 @@ func NewWorkflow(id string, opts ...WorkflowOption) *Workflow {
 		successStep: ss,
 		report:      *report,
 		logger:      zap.NewNop(),
+
+		executionMode:   StopOnError,
+		rollbackMode:    ContinueOnRollbackError,
+		cancelBehavior:  RollbackOnCancel,
+		nilReportPolicy: WarnOnNilReport,
+		pause:           &pauseSignal{},
+		progress:        &progressTracker{},
 	}
 
 	for _, opt := range opts {
 		opt(wf)
 	}
 
+	if wf.quarantine != nil {
+		wf.quarantine.logger = wf.logger
+	}
+
 	return wf
 }
 
@@ -93,32 +351,521 @@ func (wf *Workflow) GetID() string {
 }
 
 // Start starts the workflow and returns the WorkflowReport
+// On failure, the returned error is a WorkflowError aggregating the errors of the failed step actions.
 func (wf *Workflow) Start(ctx context.Context) (WorkflowReport, error) {
+	defer wf.dispatchCallbacks()
 	wf.mutex.Lock()
 	defer wf.mutex.Unlock()
 
-	var err error
+	if wf.seed != nil && wf.seed.status != StatusSuccess {
+		return wf.report, errors.Newf("workflow %q cannot be seeded with run %q since its status is %s",
+			wf.id, wf.seed.runID, wf.seed.status)
+	}
+
+	if violations := wf.Validate(); wf.strictValidation && len(violations) > 0 {
+		return wf.report, &ValidationError{WorkflowID: wf.id, Violations: violations}
+	}
+
+	if err := wf.admit(ctx, AdmitStart, ""); err != nil {
+		return wf.report, err
+	}
+
+	release, err := wf.acquire(ctx)
+	if err != nil {
+		return wf.report, err
+	}
+	defer release()
 
 	if wf.firstStep != nil {
 		wf.report.StepSequence = wf.stepIDs
 		wf.report.Status = StatusUndefined
+		wf.report.StartTime = time.Now()
+		wf.report.StepReports = []*StepReport{}
+		wf.report.Outputs = map[string][]byte{}
+		wf.report.RunID = newRunID()
+		manifest := wf.Manifest()
+		wf.report.ManifestHash = manifest.Hash()
+		wf.report.ManifestDiff = nil
+		if wf.prevManifest != nil && wf.prevManifest.Hash() != wf.report.ManifestHash {
+			diff := DiffManifests(*wf.prevManifest, manifest)
+			wf.report.ManifestDiff = &diff
+		}
+		wf.prevManifest = &manifest
 
-		wf.report, err = wf.firstStep.Run(ctx, NewStartTrigger(wf.report))
+		return wf.execute(ctx, func(ctx context.Context) (WorkflowReport, error) {
+			return runStep(ctx, wf.firstStep, NewStartTrigger(wf.report))
+		})
+	}
+
+	return wf.report, nil
+}
+
+// acquire acquires the run lock and the quota of the workflow, if any
+// It returns a release func to be invoked when the run finishes.
+func (wf *Workflow) acquire(ctx context.Context) (func(), error) {
+	releaseLock := func() {}
+	if wf.runLocker != nil {
+		key := wf.runLockKey
+		if key == "" {
+			key = wf.id
+		}
+
+		var err error
+		releaseLock, err = wf.runLocker.Acquire(ctx, key, wf.runLockTimeout)
 		if err != nil {
-			wf.report.Status = StatusFailed
-		} else {
-			wf.report.Status = StatusSuccess
+			return nil, err
+		}
+	}
+
+	releaseQuota := func() {}
+	if wf.quotaManager != nil {
+		key := wf.quotaKey
+		if key == "" {
+			key = wf.id
+		}
+
+		var err error
+		releaseQuota, err = wf.quotaManager.Acquire(key)
+		if err != nil {
+			releaseLock()
+			return nil, err
+		}
+	}
+
+	return func() {
+		releaseQuota()
+		releaseLock()
+	}, nil
+}
+
+// execute executes the run prepared in the report using the trigger and finalizes the report
+// It injects the run scoped settings in the context and invokes the callbacks at the end. The mutex must be held.
+func (wf *Workflow) execute(ctx context.Context, trigger func(ctx context.Context) (WorkflowReport, error)) (WorkflowReport, error) {
+	var err error
+
+	wf.progress.reset()
+	ctx, scope := wf.withRunScope(ctx)
+	if runMemoFromContext(ctx) == nil {
+		ctx = withRunMemo(ctx, newRunMemo())
+	}
+	for _, violation := range wf.Validate() {
+		AddWarning(ctx, "%s", violation)
+	}
+	if wf.features != nil {
+		warnUnknownFeatures(ctx, wf.features)
+	}
+
+	atomic.StoreInt32(&wf.pause.requested, 0)
+	if e := engineFromContext(ctx); e != nil && e.shuttingDown() {
+		// the engine may have requested the pause before it was reset
+		wf.Pause()
+	}
+	ctx = withPauseSignal(ctx, wf.pause)
+
+	var recorder *stateRecorder
+	if wf.stateStore != nil {
+		recorder = &stateRecorder{store: wf.stateStore, stepIDs: wf.stepIDs}
+		ctx = withStateRecorder(ctx, recorder)
+	}
+
+	emitEvent(ctx, WorkflowStarted, "", "", nil)
+
+	var hb *heartbeater
+	if wf.heartbeatStore != nil && wf.heartbeatInterval > 0 {
+		hb = startHeartbeat(ctx, wf.heartbeatStore, wf.heartbeatInterval, wf.logger, scope.tracker, Heartbeat{
+			WorkflowID: wf.id,
+			RunID:      wf.report.RunID,
+			StartTime:  wf.report.StartTime,
+		})
+	}
+
+	if p := startPrefetch(ctx, wf.steps); p != nil {
+		ctx = withPrefetcher(ctx, p)
+		defer p.stop()
+	}
+
+	// only the run actions are bound by the timeout, the rest of the run, e.g. callbacks, uses the context of the caller
+	runCtx := ctx
+	if wf.timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, wf.timeout)
+		defer cancel()
+	}
+
+	wf.report, err = trigger(runCtx)
+	wf.report.ResourceCleanups = scope.resources.cleanup(detachedContext{ctx})
+	for _, cleanup := range wf.report.ResourceCleanups {
+		if cleanup.Status == StatusFailed {
+			AddWarning(ctx, "cleanup of %s %q tracked by step %q failed", cleanup.Kind, cleanup.Name, cleanup.StepID)
+		}
+	}
+	if wf.clockSkewTolerance != nil {
+		wf.report.normalizeClocks(ctx, wf.report.StartTime, time.Now(), *wf.clockSkewTolerance)
+	}
+	if !errors.Is(err, ErrWorkflowPaused) {
+		err = joinErrors(wf.id, err)
+	}
+	wf.report.Warnings = scope.warnings.list()
+	wf.report.Panics = scope.panics.list()
+	for _, stepReport := range wf.report.StepReports {
+		stepReport.DisplayName, _ = wf.StepText(stepReport.StepID)
+		if p, ok := wf.stepPhases[stepReport.StepID]; ok {
+			stepReport.Phase = p.name
+		}
+	}
+	wf.report.summarizeGroups()
+	wf.report.summarizePhases()
+	wf.report.Costs = totalCosts(wf.report.StepReports)
+	if scope.parentCosts != nil {
+		scope.parentCosts.add(wf.report.Costs)
+	}
+	if errors.Is(err, ErrWorkflowPaused) {
+		wf.report.Status = StatusPaused
+	} else if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		wf.report.Status = StatusCancelled
+	} else if err != nil {
+		wf.report.Status = failureStatus(err)
+	} else if wf.report.hasFailedRun() {
+		wf.report.Status = StatusPartial
+		wf.mergeOutputs(scope)
+	} else {
+		wf.report.Status = StatusSuccess
+		wf.mergeOutputs(scope)
+	}
+
+	wf.report.EndTime = time.Now()
+	emitEvent(ctx, WorkflowFinished, "", wf.report.Status, err)
+
+	if hb != nil {
+		hb.stop(ctx, wf.report.Status)
+	}
+
+	if recorder != nil {
+		recorder.save(ctx, wf.report)
+	}
+
+	wf.undoDeadline = time.Time{}
+	if err == nil && wf.undoWindow > 0 {
+		wf.undoDeadline = wf.report.EndTime.Add(wf.undoWindow)
+	}
+
+	wf.report.CallbackFailures = nil
+	wf.report.Diagnostics.SinkErrors = scope.sinkErrors.list()
+	wf.report.Diagnostics.LeakedGoroutines = scope.goroutines.leaks()
+	// callbacks of a paused run are invoked once the resumed run finishes
+	if err != nil && wf.report.Status != StatusPaused {
+		wf.invokeCallback(ctx, "onFailure", wf.onFailure)
+	} else if err == nil {
+		wf.invokeCallback(ctx, "onCompletion", wf.onCompletion)
+	}
+
+	if wf.reportSink != nil {
+		if wf.reportSinkBuffer == nil {
+			wf.reportSinkBuffer = newReportSinkBuffer(wf.reportSink, wf.reportSinkOptions, wf.reportSinkStats)
 		}
 
-		wf.report.EndTime = time.Now()
+		if sinkErr := wf.reportSinkBuffer.enqueue(ctx, wf.report.Redacted(wf.redactor)); sinkErr != nil {
+			ReportSinkError(ctx, "report_sink", sinkErr)
+		}
+	}
+
+	// sync callbacks may have reported sink errors too
+	wf.report.Diagnostics.SinkErrors = scope.sinkErrors.list()
+
+	return wf.report, err
+}
+
+// mergeOutputs collects the outputs of the run after merging the outputs of its nested workflows
+// The outputs are then merged into the run of the parent workflow if the run is nested in one of its steps.
+func (wf *Workflow) mergeOutputs(scope *runScope) {
+	if scope.runMerge != nil {
+		scope.runMerge.apply(&wf.report)
+	}
+
+	wf.report.collectOutputs()
+
+	if scope.parentMerge != nil && scope.nested {
+		scope.parentMerge.merge(scope.parentStepID, wf.report.Outputs)
+	}
+}
+
+// runScope holds the collectors of a run, or of an undo, injected in the context by withRunScope
+type runScope struct {
+	warnings   *warnings
+	sinkErrors *sinkErrors
+	panics     *panics
+	resources  *resources
+	goroutines *goroutines
+	tracker    *runTracker
+
+	// state of the parent workflow if the run is nested in one of its steps
+	parentMerge  *stateMerge
+	runMerge     *stateMerge
+	parentStepID string
+	nested       bool
+	parentCosts  *costMeter
+}
+
+// withRunScope returns a copy of the context with the run scoped settings and collectors of the Workflow
+// It is shared by the runs and Undo so that the steps get the same features whether they run or roll back.
+func (wf *Workflow) withRunScope(ctx context.Context) (context.Context, *runScope) {
+	scope := &runScope{
+		warnings:   newWarnings(),
+		sinkErrors: &sinkErrors{},
+		panics:     &panics{},
+		resources:  &resources{},
+		goroutines: newGoroutines(),
+		tracker:    &runTracker{},
+	}
+
+	ctx = withRun(ctx, wf.id, wf.report.RunID)
+	ctx = withExecutionMode(ctx, wf.executionMode)
+	ctx = withRollbackMode(ctx, wf.rollbackMode)
+	ctx = withCancelBehavior(ctx, wf.cancelBehavior)
+	ctx = withNilReportPolicy(ctx, wf.nilReportPolicy)
+	if wf.retryPolicy != nil {
+		ctx = withRetryPolicy(ctx, wf.retryPolicy)
+	}
+	if wf.goroutineDump != nil {
+		ctx = withGoroutineDump(ctx, wf.goroutineDump)
+	}
+	if wf.stateSizeLimits != nil {
+		ctx = withStateSizeLimits(ctx, wf.stateSizeLimits)
+	}
+	if wf.seed != nil {
+		ctx = withSeedState(ctx, wf.seed)
+	}
+	if wf.inputs != nil {
+		ctx = withInputs(ctx, wf.inputs)
+	}
+
+	scope.parentMerge = stateMergeFromContext(ctx)
+	scope.parentStepID, scope.nested = StepFromContext(ctx)
+	if scope.nested {
+		// the steps of a nested workflow must not see the step of the parent workflow
+		ctx = context.WithValue(ctx, ctxKeyStepID, nil)
+	}
+	if wf.mergePolicy != nil {
+		scope.runMerge = newStateMerge(*wf.mergePolicy)
+	}
+	if scope.runMerge != nil || scope.parentMerge != nil {
+		ctx = withStateMerge(ctx, scope.runMerge)
+	}
+	scope.parentCosts = costMeterFromContext(ctx)
+	if scope.parentCosts != nil {
+		ctx = withCostMeter(ctx, nil)
+	}
+	if ctx.Value(ctxKeyStepIO) != nil {
+		ctx = withStepIO(ctx, nil)
+	}
+
+	ctx = withWarnings(ctx, scope.warnings)
+	if wf.features != nil {
+		ctx = withFeatures(ctx, wf.features)
+	}
+	ctx = withSinkErrors(ctx, scope.sinkErrors)
+	ctx = withPanics(ctx, scope.panics)
+	if wf.quarantine != nil {
+		ctx = withQuarantine(ctx, wf.quarantine)
+	}
+	ctx = withRunTracker(ctx, scope.tracker)
+	ctx = withProgressTracker(ctx, wf.progress)
+	ctx = withResources(ctx, scope.resources)
+	ctx = withGoroutines(ctx, scope.goroutines)
+	if wf.events != nil {
+		ctx = withEventEmitter(ctx, wf.events)
+	}
+
+	return ctx, scope
+}
+
+// Undo reverses a successful run of the Workflow by executing the rollback of every step in reverse order
+// It is only allowed within the undo window configured using WithUndoWindow and only once for a given run.
+// The rollback reports are appended to the report of the run and the status is set as StatusUndone on success.
+// Like a run, it holds the run lock and the quota of the Workflow, if any. Admission policies are not evaluated since
+// an undo only compensates a run that was already admitted, like TakeOver with CompensateRun.
+func (wf *Workflow) Undo(ctx context.Context) (WorkflowReport, error) {
+	wf.mutex.Lock()
+	defer wf.mutex.Unlock()
+
+	if wf.lastStep == nil {
+		return wf.report, nil
+	}
+
+	if wf.report.Status != StatusSuccess {
+		return wf.report, errors.Newf("workflow %q cannot be undone since its status is %s", wf.id, wf.report.Status)
+	}
+
+	if wf.undoDeadline.IsZero() || time.Now().After(wf.undoDeadline) {
+		return wf.report, errors.Newf("undo window of workflow %q is not available", wf.id)
+	}
 
+	release, err := wf.acquire(ctx)
+	if err != nil {
 		return wf.report, err
 	}
+	defer release()
 
-	return wf.report, nil
+	wf.undoDeadline = time.Time{}
+
+	ctx, scope := wf.withRunScope(ctx)
+	for _, msg := range wf.report.Warnings {
+		scope.warnings.add(msg)
+	}
+
+	// the Failure event has no error so that only rollback failures are returned at the end of the chain
+	wf.report, err = rollbackStep(ctx, wf.lastStep, &Failure{workflowReport: wf.report})
+	wf.report.ResourceCleanups = append(wf.report.ResourceCleanups, scope.resources.cleanup(detachedContext{ctx})...)
+	wf.report.Warnings = scope.warnings.list()
+	wf.report.Panics = append(wf.report.Panics, scope.panics.list()...)
+	wf.report.Diagnostics.SinkErrors = append(wf.report.Diagnostics.SinkErrors, scope.sinkErrors.list()...)
+	wf.report.Diagnostics.LeakedGoroutines = append(wf.report.Diagnostics.LeakedGoroutines, scope.goroutines.leaks()...)
+	wf.report.summarizeGroups()
+	wf.report.summarizePhases()
+	wf.report.Costs = totalCosts(wf.report.StepReports)
+	if err == nil {
+		wf.report.Status = StatusUndone
+		wf.report.Outputs = map[string][]byte{}
+	} else {
+		wf.report.Status = StatusFailed
+		err = joinErrors(wf.id, err)
+	}
+
+	wf.report.EndTime = time.Now()
+
+	return wf.report, err
+}
+
+// invokeCallback invokes the callback with a redacted clone of the current report
+// If async callbacks are enabled, the callback is queued to be handed over to the dispatcher by dispatchCallbacks once
+// the mutex is released instead. Since the callback receives a clone, it never observes later updates of the report,
+// e.g. by Undo. Likewise, its own changes don't leak into the report.
+// A panic in the callback is recovered and recorded as a CallbackFailure so that it never crashes the run.
+func (wf *Workflow) invokeCallback(ctx context.Context, name string, cb WorkflowCallback) {
+	if cb == nil {
+		return
+	}
+
+	report := wf.report.Redacted(wf.redactor)
+	if wf.asyncCallbacks == nil {
+		if failure := safeInvoke(ctx, name, cb, report); failure != nil {
+			wf.logger.Error("callback panicked", zap.String("workflow_id", wf.id), zap.String("callback", name))
+			wf.report.CallbackFailures = append(wf.report.CallbackFailures, failure)
+		}
+		return
+	}
+
+	// the context tells WaitCallbacks that it is invoked by a callback of the workflow
+	callbackCtx := context.WithValue(ctx, ctxKeyAsyncCallback, wf)
+	task := func() {
+		if failure := safeInvoke(callbackCtx, name, cb, report); failure != nil {
+			wf.logger.Error("async callback panicked", zap.String("workflow_id", wf.id), zap.String("callback", name))
+			wf.asyncFailureMutex.Lock()
+			wf.asyncFailures = append(wf.asyncFailures, failure)
+			wf.asyncFailureMutex.Unlock()
+		}
+	}
+
+	wf.callbackMutex.Lock()
+	wf.pendingCallbacks = append(wf.pendingCallbacks, task)
+	wf.callbackMutex.Unlock()
+}
+
+// dispatchCallbacks hands over the async callbacks queued by invokeCallback to the dispatcher
+// It must be deferred by the methods running the workflow before the mutex is locked, so that it is invoked after the
+// mutex is released. Therefore, a full queue with BlockOnFull doesn't block the Workflow and callbacks may use it, e.g.
+// WaitCallbacks or Start.
+func (wf *Workflow) dispatchCallbacks() {
+	wf.callbackMutex.Lock()
+	tasks := wf.pendingCallbacks
+	wf.pendingCallbacks = nil
+	if len(tasks) > 0 && wf.dispatcher == nil {
+		wf.dispatcher = newCallbackDispatcher(wf.asyncCallbacks.workers, wf.asyncCallbacks.queueSize, wf.asyncCallbacks.policy)
+	}
+	dispatcher := wf.dispatcher
+	wf.callbackMutex.Unlock()
+
+	for _, task := range tasks {
+		if !dispatcher.dispatch(task) {
+			atomic.AddUint64(&wf.droppedCallbacks, 1)
+			wf.logger.Warn("async callback dropped since the queue is full or the workflow has ended",
+				zap.String("workflow_id", wf.id))
+		}
+	}
+}
+
+// DroppedCallbacks returns the number of async callbacks dropped so far because the queue was full, see DropOnFull
+func (wf *Workflow) DroppedCallbacks() uint64 {
+	return atomic.LoadUint64(&wf.droppedCallbacks)
+}
+
+// WaitCallbacks blocks until all the async callbacks dispatched so far have been executed
+// It returns the context error if the context is done before that. It returns immediately if async callbacks are
+// not enabled, since callbacks are then executed before Start returns.
+// If it is invoked by an async callback of the Workflow with its context, or a derived one, the callback doesn't wait
+// for itself nor for the other callbacks waiting at the same time.
+func (wf *Workflow) WaitCallbacks(ctx context.Context) error {
+	wf.callbackMutex.Lock()
+	dispatcher := wf.dispatcher
+	wf.callbackMutex.Unlock()
+
+	if dispatcher == nil {
+		return nil
+	}
+
+	if ctx.Value(ctxKeyAsyncCallback) == wf {
+		return dispatcher.waitFromCallback(ctx)
+	}
+
+	return dispatcher.wait(ctx)
+}
+
+// QuarantineRetries returns the results of the background retries of quarantined steps completed so far
+func (wf *Workflow) QuarantineRetries() []*QuarantineRetry {
+	if wf.quarantine == nil {
+		return nil
+	}
+
+	return wf.quarantine.results()
+}
+
+// CallbackFailures returns the failures of the callbacks executed asynchronously so far
+func (wf *Workflow) CallbackFailures() []*CallbackFailure {
+	wf.asyncFailureMutex.Lock()
+	defer wf.asyncFailureMutex.Unlock()
+
+	failures := make([]*CallbackFailure, len(wf.asyncFailures))
+	copy(failures, wf.asyncFailures)
+
+	return failures
 }
 
 // End performs any cleanup after the Workflow execution
-// This is a NOOP currently, but left as  placeholder for any future cleanup steps if required
+// It closes the undo window of the last run, if any, and drains pending async callbacks and quarantine retries.
+// Pending reports are delivered to the ReportSink until ctx is done, the remaining ones being counted as dropped.
+// The mutex is released before draining so that the pending callbacks may still use the Workflow, e.g. Start.
 func (wf *Workflow) End(ctx context.Context) {
+	wf.mutex.Lock()
+	wf.undoDeadline = time.Time{}
+
+	wf.callbackMutex.Lock()
+	dispatcher := wf.dispatcher
+	wf.dispatcher = nil
+	wf.callbackMutex.Unlock()
+
+	reportSinkBuffer := wf.reportSinkBuffer
+	wf.reportSinkBuffer = nil
+	wf.mutex.Unlock()
+
+	if dispatcher != nil {
+		dispatcher.drain()
+	}
+
+	if reportSinkBuffer != nil {
+		reportSinkBuffer.close(ctx)
+	}
+
+	if wf.quarantine != nil {
+		wf.quarantine.drain()
+	}
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"testing"
+	"time"
 )
 
 type mockStopContainersStep struct {
@@ -190,3 +191,325 @@ func TestWorkflowEngine_Start(t *testing.T) {
 	assert.Equal(t, 0, len(report4.StepReports))
 	assert.Nil(t, err)
 }
+
+func TestWorkflow_Undo(t *testing.T) {
+	ctx := context.Background()
+
+	stop := &mockStopContainersStep{
+		Step:  Step{ID: "stop_containers"},
+		cache: map[string][]byte{},
+	}
+	stop.RegisterSaga(stop.run, stop.rollback)
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	notify := &mockNotifyStep{
+		Step:  Step{ID: "notify_on_slack"},
+		cache: map[string][]byte{},
+	}
+	notify.RegisterSaga(notify.run, notify.rollback)
+
+	// undo is not allowed without an undo window
+	workflow1 := NewWorkflow("workflow_1", WithSteps(fetch, notify))
+	_, err := workflow1.Start(ctx)
+	assert.NoError(t, err)
+	_, err = workflow1.Undo(ctx)
+	assert.Error(t, err)
+
+	// undo within the window
+	workflow2 := NewWorkflow("workflow_2", WithSteps(fetch, notify), WithUndoWindow(time.Minute))
+	report, err := workflow2.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(report.StepReports))
+
+	report, err = workflow2.Undo(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusUndone, report.Status)
+	assert.Equal(t, 4, len(report.StepReports))
+	assert.Equal(t, RollbackAction, report.StepReports[2].Action)
+	assert.Equal(t, notify.GetID(), report.StepReports[2].StepID)
+	assert.Equal(t, fetch.GetID(), report.StepReports[3].StepID)
+
+	// a run can be undone only once
+	_, err = workflow2.Undo(ctx)
+	assert.Error(t, err)
+
+	// End closes the undo window
+	_, err = workflow2.Start(ctx)
+	assert.NoError(t, err)
+	workflow2.End(ctx)
+	_, err = workflow2.Undo(ctx)
+	assert.Error(t, err)
+
+	// failure of a rollback during undo
+	workflow3 := NewWorkflow("workflow_3", WithSteps(stop, fetch), WithUndoWindow(time.Minute))
+	_, err = workflow3.Start(ctx)
+	assert.NoError(t, err)
+	report, err = workflow3.Undo(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+
+	// expired undo window
+	workflow4 := NewWorkflow("workflow_4", WithSteps(fetch), WithUndoWindow(time.Nanosecond))
+	_, err = workflow4.Start(ctx)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = workflow4.Undo(ctx)
+	assert.Error(t, err)
+}
+
+func TestWorkflow_CompensateAndContinue(t *testing.T) {
+	ctx := context.Background()
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	restart := &mockRestartContainersStep{
+		Step:  Step{ID: "restart_containers"},
+		cache: map[string][]byte{},
+	}
+	restart.RegisterSaga(restart.run, restart.rollback)
+
+	notify := &mockNotifyStep{
+		Step:  Step{ID: "notify_on_slack"},
+		cache: map[string][]byte{},
+	}
+	notify.RegisterSaga(notify.run, notify.rollback)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(fetch, restart, notify), WithExecutionMode(CompensateAndContinue))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPartial, report.Status)
+	assert.Equal(t, 4, len(report.StepReports))
+	assert.Equal(t, StatusSuccess, report.StepReports[0].Status)
+	assert.Equal(t, restart.GetID(), report.StepReports[1].StepID)
+	assert.Equal(t, RunAction, report.StepReports[1].Action)
+	assert.Equal(t, StatusFailed, report.StepReports[1].Status)
+	assert.Equal(t, RollbackAction, report.StepReports[2].Action)
+	assert.Equal(t, StatusSuccess, report.StepReports[2].Status)
+	assert.Equal(t, notify.GetID(), report.StepReports[3].StepID)
+
+	// the report of a run should not include the step reports of the previous run
+	workflow = NewWorkflow("workflow_2", WithSteps(fetch, notify), WithExecutionMode(CompensateAndContinue))
+	for i := 0; i < 2; i++ {
+		report, err = workflow.Start(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSuccess, report.Status)
+		assert.Equal(t, 2, len(report.StepReports))
+	}
+}
+
+func TestWorkflow_Severity(t *testing.T) {
+	ctx := context.Background()
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	restart := &mockRestartContainersStep{
+		Step:  Step{ID: "restart_containers"},
+		cache: map[string][]byte{},
+	}
+	restart.RegisterSaga(restart.run, restart.rollback).WithSeverity(SeverityWarning)
+	assert.Equal(t, SeverityWarning, restart.GetSeverity())
+	assert.Equal(t, SeverityCritical, fetch.GetSeverity())
+
+	notify := &mockNotifyStep{
+		Step:  Step{ID: "notify_on_slack"},
+		cache: map[string][]byte{},
+	}
+	notify.RegisterSaga(notify.run, notify.rollback)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(fetch, restart, notify))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPartial, report.Status)
+	assert.Equal(t, 3, len(report.StepReports))
+	assert.Equal(t, StatusFailed, report.StepReports[1].Status)
+	assert.Equal(t, SeverityWarning, report.StepReports[1].Severity)
+	assert.Equal(t, StepIDs{restart.GetID()}, report.ToleratedFailures())
+	assert.Empty(t, report.HardFailures())
+
+	restart.WithSeverity(SeverityCritical)
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, StepIDs{restart.GetID()}, report.HardFailures())
+	assert.Empty(t, report.ToleratedFailures())
+}
+
+func TestWorkflow_WorkflowError(t *testing.T) {
+	ctx := context.Background()
+
+	runErr := errors.New("mock run error")
+	rollbackErr := errors.New("mock rollback error")
+
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(nil, func(ctx context.Context) (skipped bool, err error) {
+		return false, rollbackErr
+	})
+
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, runErr
+	}, nil)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(s1, s2))
+	_, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, runErr))
+	assert.True(t, errors.Is(err, rollbackErr))
+
+	var wfErr *WorkflowError
+	assert.True(t, errors.As(err, &wfErr))
+	assert.Equal(t, 2, len(wfErr.Errors))
+
+	var stepErr *StepError
+	assert.True(t, errors.As(err, &stepErr))
+	assert.Equal(t, "step_2", stepErr.StepID)
+	assert.Equal(t, RunAction, stepErr.Action)
+	assert.Contains(t, err.Error(), "step \"step_1\" rollback failed: mock rollback error")
+}
+
+func TestWorkflow_ModeOverrides(t *testing.T) {
+	ctx := context.Background()
+
+	stop := &mockStopContainersStep{
+		Step:  Step{ID: "stop_containers"},
+		cache: map[string][]byte{},
+	}
+	stop.RegisterSaga(stop.run, stop.rollback)
+
+	fetch := &mockFetchLatestStep{
+		Step:  Step{ID: "fetch_latest_images"},
+		cache: map[string][]byte{},
+	}
+	fetch.RegisterSaga(fetch.run, fetch.rollback)
+
+	restart := &mockRestartContainersStep{
+		Step:  Step{ID: "restart_containers"},
+		cache: map[string][]byte{},
+	}
+	restart.RegisterSaga(restart.run, restart.rollback)
+
+	// step level execution mode overrides the workflow execution mode
+	restart.WithExecutionMode(CompensateAndContinue)
+	workflow := NewWorkflow("workflow_1", WithSteps(fetch, restart))
+	report, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPartial, report.Status)
+
+	restart.WithExecutionMode(StopOnError)
+	workflow = NewWorkflow("workflow_2", WithSteps(fetch, restart), WithExecutionMode(CompensateAndContinue))
+	_, err = workflow.Start(ctx)
+	assert.Error(t, err)
+
+	// rollback stops at the first rollback failure
+	workflow = NewWorkflow("workflow_3", WithSteps(fetch, stop, restart), WithRollbackMode(StopOnRollbackError))
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.True(t, IsRollbackFailure(err))
+	assert.Equal(t, 5, len(report.StepReports))
+	assert.Equal(t, stop.GetID(), report.StepReports[4].StepID)
+	assert.Equal(t, StatusFailed, report.StepReports[4].Status)
+
+	stop.WithRollbackMode(ContinueOnRollbackError)
+	report, err = workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 6, len(report.StepReports))
+}
+
+func TestWorkflow_CompensateAndContinue_NoDoubleRollback(t *testing.T) {
+	ctx := context.Background()
+
+	rollbacks := map[string]int{}
+	newStep := func(id string, fail bool) *Step {
+		s := &Step{ID: id}
+		s.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+			if fail {
+				return false, errors.Newf("%s failed", id)
+			}
+
+			return false, nil
+		}, func(ctx context.Context) (skipped bool, err error) {
+			rollbacks[id]++
+			return false, nil
+		})
+
+		return s
+	}
+
+	a := newStep("a", false)
+	b := newStep("b", true)
+	c := newStep("c", true).WithExecutionMode(StopOnError)
+
+	workflow := NewWorkflow("workflow_1", WithSteps(a, b, c), WithExecutionMode(CompensateAndContinue))
+	report, err := workflow.Start(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, rollbacks)
+
+	successfulRollbacks := map[string]int{}
+	for _, stepReport := range report.StepReports {
+		if stepReport.Action == RollbackAction && stepReport.Status == StatusSuccess {
+			successfulRollbacks[stepReport.StepID]++
+		}
+	}
+	assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, successfulRollbacks)
+}
+
+func TestWorkflow_Undo_RunScope(t *testing.T) {
+	ctx := context.Background()
+
+	var events []string
+	var goErr error
+	s1 := &Step{ID: "step_1"}
+	s1.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		goErr = Go(ctx, func(ctx context.Context) {})
+		return false, nil
+	})
+
+	s2 := &Step{ID: "step_2"}
+	s2.RegisterSaga(func(ctx context.Context) (skipped bool, err error) {
+		return false, nil
+	}, func(ctx context.Context) (skipped bool, err error) {
+		panic("mock panic")
+	})
+
+	workflow := NewWorkflow("workflow_1",
+		WithSteps(s1, s2),
+		WithUndoWindow(time.Minute),
+		WithEventListener(func(ctx context.Context, event Event) {
+			if event.Type == RollbackStarted || event.Type == RollbackCompleted || event.Type == RollbackFailed {
+				events = append(events, fmt.Sprintf("%s %s %s", event.Type, event.StepID, event.Status))
+			}
+		}))
+	defer workflow.End(ctx)
+
+	_, err := workflow.Start(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	report, err := workflow.Undo(ctx)
+	assert.Error(t, err)
+	assert.NoError(t, goErr)
+	assert.Equal(t, []string{
+		"rollback_started step_2 ",
+		"rollback_failed step_2 FAILED",
+		"rollback_started step_1 ",
+		"rollback_completed step_1 SUCCESS",
+	}, events)
+	assert.Equal(t, 1, len(report.Panics))
+	assert.Equal(t, "step_2", report.Panics[0].StepID)
+	assert.Equal(t, RollbackAction, report.Panics[0].Action)
+}